@@ -1,7 +1,9 @@
 package main
 
 import (
+	"bytes"
 	"context"
+	"io/ioutil"
 	"log"
 	"os"
 	"os/signal"
@@ -16,12 +18,16 @@ import (
 
 	"github.com/2gis/loggo/common"
 	"github.com/2gis/loggo/components/containers"
+	"github.com/2gis/loggo/components/cri"
+	"github.com/2gis/loggo/components/files"
 	"github.com/2gis/loggo/dispatcher"
 	"github.com/2gis/loggo/parsers"
+	"github.com/2gis/loggo/processors"
 	"github.com/2gis/loggo/stages"
 
 	"github.com/2gis/loggo/components/k8s"
 	"github.com/2gis/loggo/components/rates"
+	"github.com/2gis/loggo/components/watcher"
 	"github.com/2gis/loggo/configuration"
 	"github.com/2gis/loggo/dispatcher/workers"
 	"github.com/2gis/loggo/logging"
@@ -30,6 +36,7 @@ import (
 	"github.com/2gis/loggo/transport"
 	"github.com/2gis/loggo/transport/amqpclient"
 	"github.com/2gis/loggo/transport/firehoseclient"
+	"github.com/2gis/loggo/transport/otlpclient"
 	"github.com/2gis/loggo/transport/redisclient"
 )
 
@@ -56,6 +63,12 @@ func main() {
 		if err != nil {
 			logger.Fatalf("Unable to init firehose client, %s", err)
 		}
+	case transport.TypeOTLP:
+		transportClient, err = otlpclient.NewOTLPClient(config)
+
+		if err != nil {
+			logger.Fatalf("Unable to init otlp client, %s", err)
+		}
 	default:
 		logger.Fatalf(
 			"Unsupported transport type, supported types: [%s].",
@@ -68,28 +81,27 @@ func main() {
 		logger.Fatalln(err)
 	}
 
-	providerContainers, err := containers.NewProviderContainers(config.LogsPath, logger)
+	ignoreStorage, err := storage.NewStorage(config.ContainersIgnoreFilePath, 1)
+	if err != nil {
+		logger.Fatalln(err)
+	}
+
+	ignoreList, err := dispatcher.NewIgnoreList(ignoreStorage, logger)
 	if err != nil {
 		logger.Fatalln(err)
 	}
 
+	selectors := newSelectors(config, logger)
+	providerFiles := newProviderFiles(config, logger)
+
+	providerContainers := newContainersProvider(config, logger)
+
 	var providerK8SServices k8s.ServicesProvider = k8s.NewProviderStub()
 
 	if config.SLIExporterConfig.Enabled {
 		switch config.SLIExporterConfig.ServiceSourcePath {
 		case "":
-			k8sConfig, err := configuration.K8sConfig(config.SLIExporterConfig.K8SConfigPath)
-			if err != nil {
-				logger.Fatal(err)
-			}
-
-			logger.Printf("Info: Init Kubernetes client with APIPath %s", k8sConfig.APIPath)
-
-			client, err := kubernetes.NewForConfig(k8sConfig)
-			if err != nil {
-				logger.Fatal(err)
-			}
-
+			client := newK8SClient(config.SLIExporterConfig.K8SConfigPath, logger)
 			providerK8SServices = k8s.NewProviderK8SServices(client, config.SLIExporterConfig, logger)
 		default:
 			providerFile, err := k8s.NewProviderFile(config.SLIExporterConfig)
@@ -102,11 +114,20 @@ func main() {
 		}
 	}
 
-	metricsCollector, err := metrics.NewCollector(config.SLIExporterConfig.Buckets)
+	metricsCollector, err := metrics.NewCollector(
+		config.SLIExporterConfig.Buckets,
+		config.SLIExporterConfig.NativeHistograms,
+		time.Duration(config.MetricsSeriesTTLSec)*time.Second,
+	)
 	if err != nil {
 		logger.Fatalln(err)
 	}
 
+	logMetrics := metrics.NewLogMetrics(
+		config.LogMetricsSeriesLimit,
+		time.Duration(config.MetricsSeriesTTLSec)*time.Second,
+	)
+
 	var recordsProvider rates.RateRecordsProvider = rates.NewRuleRecordsProviderStub()
 
 	if len(config.ReadRateRulesPath) != 0 {
@@ -119,16 +140,48 @@ func main() {
 	}
 
 	var parserSLI stages.ParserSLI = parsers.NewParserSliStub()
+	sliCardinalityGuard := parsers.NewSLICardinalityGuard(
+		config.SLIExporterConfig.SeriesLimitPerService,
+		config.SLIExporterConfig.SeriesLimit,
+		time.Duration(config.MetricsSeriesTTLSec)*time.Second,
+		logger,
+	)
 
 	if config.SLIExporterConfig.Enabled {
-		parserSLI = parsers.NewParserSLI(providerK8SServices, metricsCollector)
+		mapping, err := parsers.NewSLIFieldMapping(
+			config.SLIExporterConfig.Preset, config.SLIExporterConfig.FieldsMapping)
+		if err != nil {
+			logger.Fatalln(err)
+		}
+
+		parserSLI = parsers.NewParserSLI(providerK8SServices, metricsCollector, mapping, sliCardinalityGuard)
+	}
+
+	ctx, stop := context.WithCancel(context.Background())
+
+	var providerK8SPods dispatcher.PodsProvider = k8s.NewProviderStub()
+
+	if config.PodsConfig.Enabled {
+		client := newK8SClient(config.SLIExporterConfig.K8SConfigPath, logger)
+		providerPods := k8s.NewProviderK8SPods(client, config.PodsConfig, config.K8SExtends.NodeHostname, logger)
+
+		if err := providerPods.Start(ctx); err != nil {
+			logger.Fatal(err)
+		}
+
+		providerK8SPods = providerPods
+	}
+
+	if config.PodsConfig.EnrichmentEnabled && !config.PodsConfig.Enabled {
+		logger.Warn("Pods enrichment requires pods watch to be enabled, entries won't be enriched")
 	}
 
 	go metrics.ServeHTTPRequests(":8080", "/metrics")
 
 	transportInputs := make([]<-chan string, 0, 2)
+	// entries produced by Loggo itself are processed along with parsed container entries
+	processingInputs := make([]<-chan common.EntryMap, 0, 2)
 	wg := &sync.WaitGroup{}
-	ctx, stop := context.WithCancel(context.Background())
 
 	go components.RetrievePeriodic(
 		ctx,
@@ -139,7 +192,29 @@ func main() {
 	go components.RetrievePeriodic(
 		ctx,
 		metricsCollector,
-		time.Duration(config.MetricsResetIntervalSec)*time.Second,
+		time.Duration(config.MetricsSeriesSweepIntervalSec)*time.Second,
+		logger,
+	)
+
+	if config.MetricsResetIntervalSec > 0 {
+		go components.RetrievePeriodic(
+			ctx,
+			components.RetrieverFunc(metricsCollector.Reset),
+			time.Duration(config.MetricsResetIntervalSec)*time.Second,
+			logger,
+		)
+	}
+
+	go components.RetrievePeriodic(
+		ctx,
+		sliCardinalityGuard,
+		time.Duration(config.MetricsSeriesSweepIntervalSec)*time.Second,
+		logger,
+	)
+	go components.RetrievePeriodic(
+		ctx,
+		logMetrics,
+		time.Duration(config.MetricsSeriesSweepIntervalSec)*time.Second,
 		logger,
 	)
 	go components.RetrievePeriodic(
@@ -149,21 +224,67 @@ func main() {
 		logger,
 	)
 
+	if config.EventsConfig.Enabled {
+		identity, err := os.Hostname()
+		if err != nil {
+			logger.Fatal(err)
+		}
+
+		eventsCollector, err := k8s.NewEventsCollector(
+			newK8SClient(config.SLIExporterConfig.K8SConfigPath, logger),
+			config.EventsConfig,
+			config.ParserConfig,
+			config.K8SExtends.EntryMap(),
+			identity,
+			logger,
+		)
+		if err != nil {
+			logger.Fatalf("Unable to init K8S events collector, %s", err)
+		}
+
+		processingInputs = append(processingInputs, eventsCollector.Out())
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			eventsCollector.Start(ctx)
+		}()
+	}
+
+	// interfaces stay nil if notifications are unavailable, files are polled then
+	var fileWatcher workers.FileWatcher
+	var treeWatcher dispatcher.TreeWatcher
+
+	if fsWatcher := newWatcher(config, logger); fsWatcher != nil {
+		fileWatcher, treeWatcher = fsWatcher, fsWatcher
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			fsWatcher.Start(ctx)
+		}()
+	}
+
 	followerFabric := workers.NewFollowersFabric(
 		config,
 		metricsCollector,
 		cursorStorage,
 		rater,
+		fileWatcher,
 		logger,
 	)
 	workersDispatcher := dispatcher.NewDispatcher(
 		config,
 		followerFabric,
 		providerContainers,
+		providerK8SPods,
+		providerFiles,
+		treeWatcher,
 		cursorStorage,
+		ignoreList,
+		selectors,
 		logger,
 	)
-	transportInputs = append(transportInputs, workersDispatcher.OutJournald())
 
 	wg.Add(1)
 	go func() {
@@ -175,19 +296,36 @@ func main() {
 		workersDispatcher.Out(),
 		parsers.CreateParserDockerFormat(config.ParserConfig),
 		parsers.CreateParserContainerDFormat(config.ParserConfig),
+		parsers.CreateParserCRIOFormat(config.ParserConfig),
+		parsers.CreateParserFileFormat(config.ParserConfig),
 		parsers.CreateParserPlain(config.ParserConfig),
 		config.ParserConfig.ExtendsFieldsKey,
 		logger,
 	)
 
+	processingInputs = append(processingInputs, stageParsing.Out())
+
+	redactionProcessors := newRedactionProcessors(config, metricsCollector, logger)
+	// entries are redacted before metrics are derived from them, so redacted values don't get to labels and exemplars
+	stageProcessing := stages.NewStageProcessing(
+		common.MergeChannelsEntryMap(processingInputs...),
+		newProcessors(config, metricsCollector, logMetrics, redactionProcessors, logger),
+		logger,
+	)
 	stageParsingSLI := stages.NewStageParsingSLI(
-		stageParsing.Out(),
+		stageProcessing.Out(),
 		config.ParserConfig.UserLogFieldsKey,
 		parserSLI,
 		logger,
 	)
-	stageFiltering := stages.NewStageFiltering(
+	// entries are selected after SLI parsing, so SLI is measured on all of them
+	stageSelection := stages.NewStageProcessing(
 		stageParsingSLI.Out(),
+		newSelectionProcessors(config, rater, metricsCollector, logger),
+		logger,
+	)
+	stageFiltering := stages.NewStageFiltering(
+		stageSelection.Out(),
 		config.ParserConfig.UserLogFieldsKey,
 		logger,
 	)
@@ -197,6 +335,17 @@ func main() {
 	)
 	transportInputs = append(transportInputs, stageMarshalling.Out())
 
+	stageProcessingJournald := stages.NewStageProcessing(
+		workersDispatcher.OutJournald(),
+		newJournaldProcessors(config, redactionProcessors, logger),
+		logger,
+	)
+	stageMarshallingJournald := stages.NewStageJSONMarshalling(
+		stageProcessingJournald.Out(),
+		logger,
+	)
+	transportInputs = append(transportInputs, stageMarshallingJournald.Out())
+
 	stageTransport := stages.NewStageTransport(
 		common.MergeChannelsString(transportInputs...),
 		transportClient,
@@ -206,7 +355,8 @@ func main() {
 	)
 
 	for _, stage := range []stages.Stage{
-		stageParsing, stageParsingSLI, stageFiltering, stageMarshalling, stageTransport} {
+		stageParsing, stageProcessing, stageParsingSLI, stageSelection, stageFiltering, stageMarshalling,
+		stageProcessingJournald, stageMarshallingJournald, stageTransport} {
 		wg.Add(1)
 
 		go func(stage stages.Stage) {
@@ -230,5 +380,263 @@ func main() {
 		logger.Error(err)
 	}
 
+	if err = ignoreStorage.Close(); err != nil {
+		logger.Error(err)
+	}
+
 	logger.Println("Loggo has been stopped.")
 }
+
+// newProcessors returns processors normalizing entries, redaction goes after normalization and before log metrics
+func newProcessors(config configuration.Config, collector *metrics.Collector, logMetrics *metrics.LogMetrics,
+	redaction []stages.Processor, logger logging.Logger) []stages.Processor {
+	result := make([]stages.Processor, 0)
+
+	if config.TimestampConfig.Enabled {
+		normalizer, err := processors.NewTimestampNormalizer(config.TimestampConfig, config.ParserConfig)
+		if err != nil {
+			logger.Fatal(err)
+		}
+
+		result = append(result, normalizer)
+	}
+
+	if config.SeverityConfig.Enabled {
+		normalizer, err := processors.NewSeverityNormalizer(config.SeverityConfig, config.ParserConfig)
+		if err != nil {
+			logger.Fatal(err)
+		}
+
+		result = append(result, normalizer)
+	}
+
+	if config.TraceContextConfig.Enabled {
+		result = append(result, processors.NewTraceContextNormalizer(collector, config.ParserConfig))
+	}
+
+	result = append(result, redaction...)
+
+	if config.LogMetricsPath != "" {
+		records, err := processors.LoadLogMetricRecords(config.LogMetricsPath)
+		if err != nil {
+			logger.Fatalf("Unable to load log metrics, %s", err)
+		}
+
+		deriver, err := processors.NewLogMetricsDeriver(
+			records,
+			func(name, metricType, help string, labels []string, buckets []float64) (processors.LogMetric, error) {
+				return logMetrics.NewLogMetric(name, metricType, help, labels, buckets)
+			},
+		)
+		if err != nil {
+			logger.Fatalf("Unable to init log metrics, %s", err)
+		}
+
+		result = append(result, deriver)
+	}
+
+	return result
+}
+
+// newSelectionProcessors returns processors dropping entries
+func newSelectionProcessors(config configuration.Config, rater *rates.Rater, collector *metrics.Collector,
+	logger logging.Logger) []stages.Processor {
+	result := make([]stages.Processor, 0)
+
+	if config.FilterExpression != "" {
+		filter, err := processors.NewFilter(config.FilterExpression)
+		if err != nil {
+			logger.Fatalf("Unable to compile filter expression, %s", err)
+		}
+
+		result = append(result, filter)
+	}
+
+	if config.SeverityConfig.SamplingEnabled {
+		if !config.SeverityConfig.Enabled {
+			logger.Fatal("Severity sampling requires severity normalization to be enabled")
+		}
+
+		result = append(result, processors.NewSampler(
+			rater, collector, config.SeverityConfig.OutputField, config.ParserConfig))
+	}
+
+	return result
+}
+
+// newJournaldProcessors returns processors applied to journald entries, redaction goes after normalization as for
+// container entries
+func newJournaldProcessors(
+	config configuration.Config, redaction []stages.Processor, logger logging.Logger) []stages.Processor {
+	result := make([]stages.Processor, 0)
+
+	if config.TimestampConfig.Enabled {
+		// journald realtime timestamp is put to the top level of the entry
+		parserConfig := config.ParserConfig
+		parserConfig.CRIFieldsKey = ""
+
+		normalizer, err := processors.NewTimestampNormalizer(config.TimestampConfig, parserConfig)
+		if err != nil {
+			logger.Fatal(err)
+		}
+
+		result = append(result, normalizer)
+	}
+
+	return append(result, redaction...)
+}
+
+func newSelectors(config configuration.Config, logger logging.Logger) *dispatcher.Selectors {
+	records := dispatcher.SelectorsRecords{}
+
+	if config.ContainersSelectorsPath != "" {
+		var err error
+
+		records, err = dispatcher.LoadSelectorsRecords(config.ContainersSelectorsPath)
+		if err != nil {
+			logger.Fatalf("Unable to load containers selectors, %s", err)
+		}
+	}
+
+	selectors, err := dispatcher.NewSelectors(records)
+	if err != nil {
+		logger.Fatalf("Unable to init containers selectors, %s", err)
+	}
+
+	if selectors.UsesPods() && !config.PodsConfig.Enabled {
+		logger.Warn("Containers selectors by pod labels or annotations require pods watch to be enabled, " +
+			"they won't match")
+	}
+
+	return selectors
+}
+
+func newWatcher(config configuration.Config, logger logging.Logger) *watcher.Watcher {
+	if !config.FollowerConfig.FSNotify {
+		return nil
+	}
+
+	fsWatcher, err := watcher.NewWatcher(logger)
+	if err != nil {
+		logger.Warnf("Filesystem notifications are unavailable, falling back to polling: %s", err)
+		return nil
+	}
+
+	return fsWatcher
+}
+
+func newContainersProvider(config configuration.Config, logger logging.Logger) dispatcher.ContainersProvider {
+	runtimeClient := newRuntimeClient(config, logger)
+
+	switch config.ContainersProvider {
+	case containers.ProviderTypeLogs:
+		var runtime containers.RuntimeService
+
+		if runtimeClient != nil {
+			runtime = runtimeClient
+		}
+
+		providerContainers, err := containers.NewProviderContainers(
+			config.LogsPath,
+			config.CRIOContainersPath,
+			config.CRIORunrootPath,
+			runtime,
+			logger,
+		)
+		if err != nil {
+			logger.Fatalln(err)
+		}
+
+		return providerContainers
+	case containers.ProviderTypeCRI:
+		if runtimeClient == nil {
+			logger.Fatalf("CRI runtime endpoint must be set for '%s' containers provider", containers.ProviderTypeCRI)
+		}
+
+		return containers.NewProviderCRI(runtimeClient, logger)
+	default:
+		logger.Fatalf(
+			"Unsupported containers provider, supported providers: [%s].",
+			strings.Join(containers.ProviderTypesSupported, ", "),
+		)
+	}
+
+	return nil
+}
+
+func newRuntimeClient(config configuration.Config, logger logging.Logger) *cri.Client {
+	if config.CRIRuntimeEndpoint == "" {
+		return nil
+	}
+
+	client, err := cri.NewClient(config.CRIRuntimeEndpoint, time.Duration(config.CRIRuntimeTimeoutSec)*time.Second)
+	if err != nil {
+		logger.Fatalf("Unable to init CRI runtime client, %s", err)
+	}
+
+	return client
+}
+
+func newProviderFiles(config configuration.Config, logger logging.Logger) *files.ProviderFiles {
+	records := make([]files.InputRecord, 0)
+
+	if config.FileInputsPath != "" {
+		var err error
+
+		records, err = files.LoadInputRecords(config.FileInputsPath)
+		if err != nil {
+			logger.Fatalf("Unable to load file inputs, %s", err)
+		}
+	}
+
+	providerFiles, err := files.NewProviderFiles(records)
+	if err != nil {
+		logger.Fatalf("Unable to init file inputs, %s", err)
+	}
+
+	return providerFiles
+}
+
+func newRedactionProcessors(
+	config configuration.Config, collector *metrics.Collector, logger logging.Logger) []stages.Processor {
+	if config.RedactionRulesPath == "" {
+		return []stages.Processor{}
+	}
+
+	records, err := processors.LoadRedactionRecords(config.RedactionRulesPath)
+	if err != nil {
+		logger.Fatalf("Unable to load redaction rules, %s", err)
+	}
+
+	var hmacKey []byte
+
+	if config.RedactionHMACKeyPath != "" {
+		hmacKey, err = ioutil.ReadFile(config.RedactionHMACKeyPath)
+		if err != nil {
+			logger.Fatalf("Unable to read redaction hmac key, %s", err)
+		}
+	}
+
+	redactor, err := processors.NewRedactor(records, bytes.TrimSpace(hmacKey), collector, config.ParserConfig)
+	if err != nil {
+		logger.Fatalf("Unable to init redaction rules, %s", err)
+	}
+
+	return []stages.Processor{redactor}
+}
+
+func newK8SClient(configPath string, logger logging.Logger) *kubernetes.Clientset {
+	k8sConfig, err := configuration.K8sConfig(configPath)
+	if err != nil {
+		logger.Fatal(err)
+	}
+
+	logger.Printf("Info: Init Kubernetes client with APIPath %s", k8sConfig.APIPath)
+
+	client, err := kubernetes.NewForConfig(k8sConfig)
+	if err != nil {
+		logger.Fatal(err)
+	}
+
+	return client
+}
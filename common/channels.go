@@ -23,3 +23,25 @@ func MergeChannelsString(cs ...<-chan string) <-chan string {
 	}()
 	return out
 }
+
+// MergeChannelsEntryMap multiplexes entry map channels into returned channel
+func MergeChannelsEntryMap(cs ...<-chan EntryMap) <-chan EntryMap {
+	out := make(chan EntryMap)
+	wg := sync.WaitGroup{}
+	wg.Add(len(cs))
+
+	for _, c := range cs {
+		go func(c <-chan EntryMap) {
+			defer wg.Done()
+			for n := range c {
+				out <- n
+			}
+		}(c)
+	}
+
+	go func() {
+		wg.Wait()
+		close(out)
+	}()
+	return out
+}
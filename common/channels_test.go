@@ -23,3 +23,21 @@ func TestMergeChannelsString(t *testing.T) {
 	_, ok := <-output
 	assert.False(t, ok)
 }
+
+func TestMergeChannelsEntryMap(t *testing.T) {
+	inputA := make(chan EntryMap, 1)
+	inputB := make(chan EntryMap, 1)
+	output := MergeChannelsEntryMap(inputA, inputB)
+
+	inputA <- EntryMap{"input": "a"}
+	assert.Equal(t, EntryMap{"input": "a"}, <-output)
+
+	inputB <- EntryMap{"input": "b"}
+	assert.Equal(t, EntryMap{"input": "b"}, <-output)
+
+	close(inputA)
+	close(inputB)
+
+	_, ok := <-output
+	assert.False(t, ok)
+}
@@ -8,6 +8,7 @@ const (
 	LabelLogstashPrefix    = "logstash_prefix"
 	LabelLogType           = "type"
 	LabelTime              = "time"
+	LabelFilePath          = "log.file.path"
 )
 
 /* Filename is arbitrary cursorStorage key picked as the standard journal file ending */
@@ -29,6 +30,24 @@ const (
 
 	// KubernetesNodeHostname name of field
 	KubernetesNodeHostname = "kubernetes.node_hostname"
+
+	// KubernetesPodIP name of field
+	KubernetesPodIP = "kubernetes.pod_ip"
+
+	// KubernetesOwnerKind name of field, contains kind of workload owning the pod
+	KubernetesOwnerKind = "kubernetes.owner_kind"
+
+	// KubernetesOwnerName name of field, contains name of workload owning the pod
+	KubernetesOwnerName = "kubernetes.owner_name"
+
+	// KubernetesLabelsPrefix is a prefix of pod labels fields names
+	KubernetesLabelsPrefix = "kubernetes.labels."
+
+	// KubernetesAnnotationsPrefix is a prefix of pod annotations fields names
+	KubernetesAnnotationsPrefix = "kubernetes.annotations."
+
+	// KubernetesNodeLabelsPrefix is a prefix of node labels fields names
+	KubernetesNodeLabelsPrefix = "kubernetes.node_labels."
 )
 
 // Containers Provider related
@@ -46,4 +65,28 @@ const (
 const (
 	CRITypeContainerD = "containerd"
 	CRITypeDocker     = "docker"
+	CRITypeCRIO       = "crio"
+
+	// FormatFile is the format of host log files lines, which are user log lines as is
+	FormatFile = "file"
 )
+
+// User log parsers
+const (
+	UserLogParserJSON   = "json"
+	UserLogParserLogfmt = "logfmt"
+	UserLogParserRegexp = "regex"
+)
+
+/* Canonical severities produced by severity normalization */
+const (
+	SeverityTrace = "trace"
+	SeverityDebug = "debug"
+	SeverityInfo  = "info"
+	SeverityWarn  = "warn"
+	SeverityError = "error"
+	SeverityFatal = "fatal"
+)
+
+// Severities are canonical severities ordered from the least severe one
+var Severities = []string{SeverityTrace, SeverityDebug, SeverityInfo, SeverityWarn, SeverityError, SeverityFatal}
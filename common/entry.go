@@ -1,7 +1,13 @@
 package common
 
+// UserLogParser turns user log content into map of fields
+type UserLogParser func(content string) (map[string]interface{}, error)
+
 type Entry struct {
 	Origin  []byte
 	Format  string
 	Extends EntryMap
+
+	// UserLogParser is used to parse user log content; default parser is used if not set
+	UserLogParser UserLogParser
 }
@@ -1,5 +1,7 @@
 package common
 
+import "strings"
+
 // EntryMap is a shorthand for easier representation of parsed data
 type EntryMap map[string]interface{}
 
@@ -22,6 +24,46 @@ func (entryMap EntryMap) Filter(keys ...string) EntryMap {
 	return entrymap
 }
 
+// Lookup returns value by the path of keys joined with dots; both flat keys containing dots
+// ("kubernetes.pod_name") and nested maps ("request.status") are resolved, flat keys are preferred
+func (entryMap EntryMap) Lookup(path string) (interface{}, bool) {
+	return lookup(entryMap, path)
+}
+
+func lookup(value map[string]interface{}, path string) (interface{}, bool) {
+	if result, ok := value[path]; ok {
+		return result, true
+	}
+
+	for i := strings.IndexByte(path, '.'); i >= 0; i = nextDot(path, i) {
+		var nested map[string]interface{}
+
+		switch typed := value[path[:i]].(type) {
+		case EntryMap:
+			nested = typed
+		case map[string]interface{}:
+			nested = typed
+		default:
+			continue
+		}
+
+		if result, ok := lookup(nested, path[i+1:]); ok {
+			return result, true
+		}
+	}
+
+	return nil, false
+}
+
+func nextDot(path string, position int) int {
+	next := strings.IndexByte(path[position+1:], '.')
+
+	if next < 0 {
+		return -1
+	}
+
+	return position + 1 + next
+}
 
 // NamespaceName attempts to return field from EntryMap
 func (entryMap EntryMap) NamespaceName() string {
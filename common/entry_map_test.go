@@ -50,3 +50,34 @@ func TestEntryMap_Filter(t *testing.T) {
 	entryMap = entryMap.Filter("key2")
 	assert.Equal(t, EntryMap{"key2": "value2"}, entryMap)
 }
+
+func TestEntryMap_Lookup(t *testing.T) {
+	entryMap := EntryMap{
+		KubernetesNamespaceName: "namespace",
+		"request": map[string]interface{}{
+			"status": float64(200),
+			"headers": EntryMap{
+				"x.request.id": "id",
+			},
+		},
+		"request.method": "GET",
+	}
+
+	testCases := map[string]interface{}{
+		KubernetesNamespaceName:        "namespace",
+		"request.status":               float64(200),
+		"request.method":               "GET",
+		"request.headers.x.request.id": "id",
+	}
+
+	for path, expected := range testCases {
+		value, ok := entryMap.Lookup(path)
+		assert.True(t, ok, path)
+		assert.Equal(t, expected, value, path)
+	}
+
+	for _, path := range []string{"request.missing", "kubernetes", "request.status.value", ""} {
+		_, ok := entryMap.Lookup(path)
+		assert.False(t, ok, path)
+	}
+}
@@ -2,12 +2,15 @@ package containers
 
 import (
 	"encoding/json"
+	"fmt"
 	"io/ioutil"
 	"os"
 	"path/filepath"
 	"strings"
 	"sync"
 
+	runtimeapi "k8s.io/cri-api/pkg/apis/runtime/v1"
+
 	"github.com/2gis/loggo/common"
 	"github.com/2gis/loggo/logging"
 )
@@ -24,11 +27,29 @@ import (
 //    * kubernetes.container_name   Config.Labels."io.kubernetes.container.name"
 
 const (
-	configFileName     = "config.v2.json"
-	loggoContainerName = "loggo"
-	logFilesSuffix     = ".log"
+	configFileName = "config.v2.json"
+	logFilesSuffix = ".log"
+
+	// CRI-O keeps OCI config of the container in its storage, <id>/userdata/config.json, and its state in the run
+	// directory of the storage, <id>/userdata/state.json
+	crioUserdataDir   = "userdata"
+	crioConfigName    = "config.json"
+	crioStateName     = "state.json"
+	crioStateStopped  = "stopped"
+	crioTypeSandbox   = "sandbox"
+	annotationID      = "io.kubernetes.cri-o.ContainerID"
+	annotationType    = "io.kubernetes.cri-o.ContainerType"
+	annotationLabels  = "io.kubernetes.cri-o.Labels"
+	annotationLogPath = "io.kubernetes.cri-o.LogPath"
+
+	labelPodUID = "io.kubernetes.pod.uid"
 )
 
+// RuntimeService lists containers of CRI runtime
+type RuntimeService interface {
+	ListContainers() ([]*runtimeapi.Container, error)
+}
+
 // Container represents container configuration
 type Container struct {
 	Type string `json:"-"`
@@ -66,8 +87,22 @@ type StateSection struct {
 // ProviderContainers seeks for logs in requested logPath and resolves links
 type ProviderContainers struct {
 	sync.Mutex
-	logsPath string
-	logger   logging.Logger
+	logsPath           string
+	crioContainersPath string
+	crioRunrootPath    string
+	runtime            RuntimeService
+	logger             logging.Logger
+
+	// the last CRI-O containers read, they're used while the storage can't be read
+	crioLast map[string]*Container
+}
+
+type crioConfig struct {
+	Annotations map[string]string `json:"annotations"`
+}
+
+type crioState struct {
+	Status string `json:"status"`
 }
 
 // GetPodName returns container pod name or empty string
@@ -92,8 +127,11 @@ func (c *Container) getLabelValue(label string) string {
 	return ""
 }
 
-// NewProviderContainers is ProviderContainers constructor
-func NewProviderContainers(path string, logger logging.Logger) (*ProviderContainers, error) {
+// NewProviderContainers is ProviderContainers constructor; crioContainersPath is the CRI-O containers storage, node is
+// assumed to run containerd if it doesn't exist, crioRunrootPath is the run directory of the storage keeping containers
+// states. Containerd containers IDs and states are taken from runtime, they're considered running if runtime is nil
+func NewProviderContainers(path, crioContainersPath, crioRunrootPath string, runtime RuntimeService,
+	logger logging.Logger) (*ProviderContainers, error) {
 	absPath, err := filepath.Abs(path)
 
 	if err != nil {
@@ -103,8 +141,11 @@ func NewProviderContainers(path string, logger logging.Logger) (*ProviderContain
 	logger.Infof("Absolute path to search logs in: '%s'", absPath)
 
 	return &ProviderContainers{
-		logsPath: absPath,
-		logger:   logger,
+		logsPath:           absPath,
+		crioContainersPath: crioContainersPath,
+		crioRunrootPath:    crioRunrootPath,
+		runtime:            runtime,
+		logger:             logger,
 	}, nil
 }
 
@@ -117,6 +158,13 @@ func (provider *ProviderContainers) Containers() (Containers, error) {
 		return containers, err
 	}
 
+	crio := provider.crioContainers()
+	var runtime map[string]*runtimeapi.Container
+
+	if crio == nil {
+		runtime = provider.runtimeContainers()
+	}
+
 	for _, dir := range directories {
 		links, files, err := SymlinksAndFiles(dir)
 
@@ -146,21 +194,22 @@ func (provider *ProviderContainers) Containers() (Containers, error) {
 				continue
 			}
 
-			if strings.Contains(container.GetName(), loggoContainerName) {
-				continue
-			}
-
 			containers[container.LogPath] = container
 		}
 
 		for _, path := range files {
-			container := deserializeContainerConfigContainerD(path)
-			if !strings.HasSuffix(container.LogPath, logFilesSuffix) {
+			if !strings.HasSuffix(path, logFilesSuffix) {
 				continue
 			}
 
-			if strings.Contains(container.GetName(), loggoContainerName) {
-				continue
+			container := deserializeContainerConfigContainerD(path)
+
+			if crio != nil {
+				container = crioContainer(crio, path)
+			}
+
+			if runtime != nil {
+				setRuntimeState(container, runtime)
 			}
 
 			containers[container.LogPath] = container
@@ -306,7 +355,169 @@ func deserializeContainerConfigContainerD(path string) *Container {
 				common.LabelKubernetesContainerName: filepath.Base(containerDir),
 			},
 		},
-		// todo: hotfix, until we'll be able to evaluate it
+		// container is considered running unless runtime state is available
 		State: StateSection{Running: true},
 	}
 }
+
+// crioContainers reads CRI-O containers metadata by kubelet log path key; nil is returned if CRI-O storage is absent,
+// the last containers read are returned if it can't be read
+func (provider *ProviderContainers) crioContainers() map[string]*Container {
+	if provider.crioContainersPath == "" {
+		return nil
+	}
+
+	directories, err := ioutil.ReadDir(provider.crioContainersPath)
+
+	if os.IsNotExist(err) {
+		return nil
+	}
+
+	if err != nil {
+		provider.logger.Warnf("containers provider is unable to read CRI-O storage: %s", err)
+		return provider.crioLast
+	}
+
+	containers := make(map[string]*Container)
+
+	for _, dir := range directories {
+		container, err := deserializeContainerConfigCRIO(
+			filepath.Join(provider.crioContainersPath, dir.Name(), crioUserdataDir),
+			filepath.Join(provider.crioRunrootPath, dir.Name(), crioUserdataDir),
+		)
+
+		if err != nil {
+			provider.logger.Warnf("containers provider is unable to read CRI-O container %s, %s", dir.Name(), err)
+			continue
+		}
+
+		// sandboxes have no log files of their own
+		if container != nil {
+			containers[kubeletLogKey(container.LogPath)] = container
+		}
+	}
+
+	provider.crioLast = containers
+	return containers
+}
+
+// crioContainer returns CRI-O container of the log file; CRI-O removes metadata of the container, but kubelet keeps
+// its log file until the pod is removed, such containers are exited
+func crioContainer(crio map[string]*Container, path string) *Container {
+	if container, ok := crio[kubeletLogKey(path)]; ok {
+		container.LogPath = path
+		return container
+	}
+
+	container := deserializeContainerConfigContainerD(path)
+	container.Type = common.CRITypeCRIO
+	container.ID = ""
+	container.State.Running = false
+	return container
+}
+
+// runtimeContainers returns CRI runtime containers by kubelet log path key; nil is returned if runtime is unavailable
+func (provider *ProviderContainers) runtimeContainers() map[string]*runtimeapi.Container {
+	if provider.runtime == nil {
+		return nil
+	}
+
+	list, err := provider.runtime.ListContainers()
+
+	if err != nil {
+		provider.logger.Warnf("containers provider is unable to list runtime containers, %s", err)
+		return nil
+	}
+
+	containers := make(map[string]*runtimeapi.Container, len(list))
+
+	for _, container := range list {
+		containers[runtimeLogKey(container)] = container
+	}
+
+	return containers
+}
+
+// setRuntimeState sets ID and state of the containerd container; runtime removes exited containers eventually, but
+// kubelet keeps their log files until the pod is removed
+func setRuntimeState(container *Container, runtime map[string]*runtimeapi.Container) {
+	runtimeContainer, ok := runtime[kubeletLogKey(container.LogPath)]
+
+	if !ok {
+		container.ID = ""
+		container.State.Running = false
+		return
+	}
+
+	container.ID = runtimeContainer.Id
+	container.State.Running = runtimeContainer.State != runtimeapi.ContainerState_CONTAINER_EXITED
+}
+
+// runtimeLogKey returns kubelet log path key of the runtime container, its log file is named by restart count
+func runtimeLogKey(container *runtimeapi.Container) string {
+	labels := container.Labels
+
+	return filepath.Join(
+		fmt.Sprintf(
+			"%s_%s_%s",
+			labels[common.LabelKubernetesPodNamespace],
+			labels[common.LabelKubernetesPodName],
+			labels[labelPodUID],
+		),
+		container.GetMetadata().GetName(),
+		fmt.Sprintf("%d%s", container.GetMetadata().GetAttempt(), logFilesSuffix),
+	)
+}
+
+// kubeletLogKey returns the part of kubelet log path which doesn't depend on logs mount point:
+// namespace_pod_uid/container/N.log
+func kubeletLogKey(path string) string {
+	container := filepath.Dir(path)
+	pod := filepath.Dir(container)
+	return filepath.Join(filepath.Base(pod), filepath.Base(container), filepath.Base(path))
+}
+
+// deserializeContainerConfigCRIO reads container from CRI-O userdata directories of the storage and its run directory;
+// nil container is returned for sandboxes
+func deserializeContainerConfigCRIO(userdataPath, runUserdataPath string) (*Container, error) {
+	data, err := ioutil.ReadFile(filepath.Join(userdataPath, crioConfigName))
+
+	if err != nil {
+		return nil, err
+	}
+
+	config := crioConfig{}
+
+	if err = json.Unmarshal(data, &config); err != nil {
+		return nil, err
+	}
+
+	if config.Annotations[annotationType] == crioTypeSandbox || config.Annotations[annotationLogPath] == "" {
+		return nil, nil
+	}
+
+	labels := make(map[string]string)
+
+	if err = json.Unmarshal([]byte(config.Annotations[annotationLabels]), &labels); err != nil {
+		return nil, fmt.Errorf("labels annotation unmarshalling, %w", err)
+	}
+
+	// state is written by CRI-O after the config, container may be just created
+	state := crioState{}
+
+	if data, err = ioutil.ReadFile(filepath.Join(runUserdataPath, crioStateName)); err == nil {
+		err = json.Unmarshal(data, &state)
+	}
+
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	return &Container{
+		Type:    common.CRITypeCRIO,
+		ID:      config.Annotations[annotationID],
+		LogPath: config.Annotations[annotationLogPath],
+		State:   StateSection{Running: state.Status != crioStateStopped},
+		Config:  ConfigSection{Labels: labels},
+	}, nil
+}
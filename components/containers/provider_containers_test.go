@@ -1,15 +1,21 @@
 package containers
 
 import (
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	runtimeapi "k8s.io/cri-api/pkg/apis/runtime/v1"
 
+	"github.com/2gis/loggo/common"
+	"github.com/2gis/loggo/components/cri"
 	"github.com/2gis/loggo/logging"
+	"github.com/2gis/loggo/tests/mocks"
 )
 
 type testEnvironment struct {
@@ -129,7 +135,7 @@ func TestContainersProvider(t *testing.T) {
 	te := setupEnvironment(t)
 	defer tearDown(te.tempDir)
 
-	providerContainers, err := NewProviderContainers(te.linksDir, logging.NewLoggerDefault())
+	providerContainers, err := NewProviderContainers(te.linksDir, "", "", nil, logging.NewLoggerDefault())
 	assert.NoError(t, err)
 
 	containers, err := providerContainers.Containers()
@@ -141,3 +147,182 @@ func TestContainersProvider(t *testing.T) {
 	assert.Equal(t, "123abc", container.GetPodName())
 	assert.Equal(t, "yabloko", container.GetPodNamespace())
 }
+
+func TestContainersProviderCRIO(t *testing.T) {
+	logsPath := filepath.Join("..", "..", "tests", "fixtures", "pods_crio")
+	crioPath := filepath.Join("..", "..", "tests", "fixtures", "crio", "lib")
+	runrootPath := filepath.Join("..", "..", "tests", "fixtures", "crio", "run")
+
+	providerContainers, err := NewProviderContainers(
+		logsPath, crioPath, runrootPath, nil, logging.NewLoggerDefault(),
+	)
+	assert.NoError(t, err)
+
+	containers, err := providerContainers.Containers()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(containers))
+
+	path, err := filepath.Abs(filepath.Join(logsPath, "namespace_pod_uid", "container", "0.log"))
+	assert.NoError(t, err)
+	container := containers[path]
+	assert.Equal(t, common.CRITypeCRIO, container.Type)
+	assert.Equal(t, "4f2b7c1e9a3d", container.ID)
+	assert.True(t, container.Running())
+	assert.Equal(t, "container", container.GetName())
+	assert.Equal(t, "pod", container.GetPodName())
+	assert.Equal(t, "namespace", container.GetPodNamespace())
+
+	// containerd is assumed without CRI-O storage
+	providerContainers, err = NewProviderContainers(
+		logsPath, filepath.Join(crioPath, "absent"), runrootPath, nil, logging.NewLoggerDefault(),
+	)
+	assert.NoError(t, err)
+
+	containers, err = providerContainers.Containers()
+	assert.NoError(t, err)
+	assert.Equal(t, common.CRITypeContainerD, containers[path].Type)
+}
+
+func TestContainersProviderCRIOState(t *testing.T) {
+	logsPath := t.TempDir()
+	crioPath := t.TempDir()
+	runrootPath := t.TempDir()
+
+	writeCRIOContainer := func(id, container, logFile, status string) {
+		userdata := filepath.Join(crioPath, id, crioUserdataDir)
+		assert.NoError(t, os.MkdirAll(userdata, 0755))
+
+		labels := fmt.Sprintf(
+			`{"io.kubernetes.container.name":"%s","io.kubernetes.pod.name":"pod","io.kubernetes.pod.namespace":"ns"}`,
+			container,
+		)
+		config, err := json.Marshal(crioConfig{Annotations: map[string]string{
+			annotationID:      id,
+			annotationLabels:  labels,
+			annotationLogPath: filepath.Join("/var/log/pods/ns_pod_uid", container, logFile),
+		}})
+		assert.NoError(t, err)
+		assert.NoError(t, ioutil.WriteFile(filepath.Join(userdata, crioConfigName), config, 0644))
+
+		if status != "" {
+			runUserdata := filepath.Join(runrootPath, id, crioUserdataDir)
+			assert.NoError(t, os.MkdirAll(runUserdata, 0755))
+			state := fmt.Sprintf(`{"id":"%s","status":"%s"}`, id, status)
+			assert.NoError(t, ioutil.WriteFile(filepath.Join(runUserdata, crioStateName), []byte(state), 0644))
+		}
+	}
+
+	writeCRIOContainer("exited", "app", "0.log", "stopped")
+	writeCRIOContainer("restarted", "app", "1.log", "running")
+	writeCRIOContainer("created", "init", "0.log", "")
+
+	for _, path := range []string{"app/0.log", "app/1.log", "init/0.log", "removed/0.log", "app/0.log.20210101-100000"} {
+		path = filepath.Join(logsPath, "ns_pod_uid", path)
+		assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		assert.NoError(t, ioutil.WriteFile(path, []byte{}, 0644))
+	}
+
+	providerContainers, err := NewProviderContainers(logsPath, crioPath, runrootPath, nil, logging.NewLoggerDefault())
+	assert.NoError(t, err)
+
+	containers, err := providerContainers.Containers()
+	assert.NoError(t, err)
+	assert.Equal(t, 4, len(containers))
+
+	for path, expected := range map[string]struct {
+		id      string
+		running bool
+	}{
+		"app/0.log":     {"exited", false},
+		"app/1.log":     {"restarted", true},
+		"init/0.log":    {"created", true},
+		"removed/0.log": {"", false},
+	} {
+		container := containers[filepath.Join(logsPath, "ns_pod_uid", path)]
+		assert.Equal(t, common.CRITypeCRIO, container.Type, path)
+		assert.Equal(t, expected.id, container.ID, path)
+		assert.Equal(t, expected.running, container.Running(), path)
+		assert.Equal(t, "ns", container.GetPodNamespace(), path)
+	}
+
+	// the last containers read are used while the storage can't be read
+	assert.NoError(t, os.RemoveAll(crioPath))
+	assert.NoError(t, ioutil.WriteFile(crioPath, []byte{}, 0644))
+
+	containers, err = providerContainers.Containers()
+	assert.NoError(t, err)
+	container := containers[filepath.Join(logsPath, "ns_pod_uid", "app", "1.log")]
+	assert.Equal(t, common.CRITypeCRIO, container.Type)
+	assert.Equal(t, "restarted", container.ID)
+}
+
+func TestContainersProviderRuntimeState(t *testing.T) {
+	logsPath := t.TempDir()
+
+	for _, path := range []string{"app/0.log", "app/1.log", "init/0.log"} {
+		path = filepath.Join(logsPath, "ns_pod_uid", path)
+		assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		assert.NoError(t, ioutil.WriteFile(path, []byte{}, 0644))
+	}
+
+	runtimeContainer := func(id, name string, attempt uint32, state runtimeapi.ContainerState) *runtimeapi.Container {
+		return &runtimeapi.Container{
+			Id:       id,
+			Metadata: &runtimeapi.ContainerMetadata{Name: name, Attempt: attempt},
+			State:    state,
+			Labels: map[string]string{
+				common.LabelKubernetesPodNamespace:  "ns",
+				common.LabelKubernetesPodName:       "pod",
+				common.LabelKubernetesContainerName: name,
+				labelPodUID:                         "uid",
+			},
+		}
+	}
+
+	otherPod := runtimeContainer("other", "app", 0, runtimeapi.ContainerState_CONTAINER_RUNNING)
+	otherPod.Labels[labelPodUID] = "other"
+
+	// the container of init/0.log has been removed by runtime
+	runtime := mocks.NewRuntimeServiceStub(
+		runtimeContainer("exited", "app", 0, runtimeapi.ContainerState_CONTAINER_EXITED),
+		runtimeContainer("restarted", "app", 1, runtimeapi.ContainerState_CONTAINER_RUNNING),
+		otherPod,
+	)
+	endpoint, err := runtime.Serve(filepath.Join(t.TempDir(), "cri.sock"))
+	assert.NoError(t, err)
+	defer runtime.Stop()
+
+	client, err := cri.NewClient(endpoint, time.Second)
+	assert.NoError(t, err)
+	defer client.Close()
+
+	providerContainers, err := NewProviderContainers(logsPath, "", "", client, logging.NewLoggerDefault())
+	assert.NoError(t, err)
+
+	containers, err := providerContainers.Containers()
+	assert.NoError(t, err)
+	assert.Equal(t, 3, len(containers))
+
+	for path, expected := range map[string]struct {
+		id      string
+		running bool
+	}{
+		"app/0.log":  {"exited", false},
+		"app/1.log":  {"restarted", true},
+		"init/0.log": {"", false},
+	} {
+		container := containers[filepath.Join(logsPath, "ns_pod_uid", path)]
+		assert.Equal(t, common.CRITypeContainerD, container.Type, path)
+		assert.Equal(t, expected.id, container.ID, path)
+		assert.Equal(t, expected.running, container.Running(), path)
+	}
+
+	// containers are considered running while runtime is unavailable
+	runtime.Stop()
+	containers, err = providerContainers.Containers()
+	assert.NoError(t, err)
+
+	for _, container := range containers {
+		assert.True(t, container.Running())
+	}
+}
@@ -0,0 +1,134 @@
+package containers
+
+import (
+	"os"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	runtimeapi "k8s.io/cri-api/pkg/apis/runtime/v1"
+
+	"github.com/2gis/loggo/common"
+	"github.com/2gis/loggo/logging"
+)
+
+/* containers provider types */
+const (
+	ProviderTypeLogs = "logs"
+	ProviderTypeCRI  = "cri"
+)
+
+// ProviderTypesSupported lists containers provider types accepted by containers-provider option
+var ProviderTypesSupported = []string{ProviderTypeLogs, ProviderTypeCRI}
+
+// runtime names reported by CRI runtimes, the rest are assumed to write logs in containerd format
+const (
+	runtimeNameCRIO   = "cri-o"
+	runtimeNameDocker = "docker"
+)
+
+// RuntimeStatusService lists containers of CRI runtime along with their statuses
+type RuntimeStatusService interface {
+	RuntimeService
+	ContainerStatus(id string) (*runtimeapi.ContainerStatus, error)
+	RuntimeName() (string, error)
+}
+
+// ProviderCRI provides containers listed by CRI runtime instead of walking logs path; log path and labels don't
+// change during container lifetime, so status of the container is requested once
+type ProviderCRI struct {
+	runtime       RuntimeStatusService
+	containerType string
+	statuses      map[string]*runtimeapi.ContainerStatus
+	logger        logging.Logger
+}
+
+// NewProviderCRI is ProviderCRI constructor
+func NewProviderCRI(runtime RuntimeStatusService, logger logging.Logger) *ProviderCRI {
+	return &ProviderCRI{
+		runtime:  runtime,
+		statuses: make(map[string]*runtimeapi.ContainerStatus),
+		logger:   logger,
+	}
+}
+
+// Containers returns runtime containers having log files; error means containers are unknown, containers whose
+// statuses are requested are removed by runtime meanwhile otherwise
+func (provider *ProviderCRI) Containers() (Containers, error) {
+	containerType, err := provider.runtimeContainerType()
+
+	if err != nil {
+		return nil, err
+	}
+
+	list, err := provider.runtime.ListContainers()
+
+	if err != nil {
+		return nil, err
+	}
+
+	containers := make(Containers, len(list))
+	statuses := make(map[string]*runtimeapi.ContainerStatus, len(list))
+
+	for _, runtimeContainer := range list {
+		containerStatus, ok := provider.statuses[runtimeContainer.Id]
+
+		if !ok {
+			containerStatus, err = provider.runtime.ContainerStatus(runtimeContainer.Id)
+
+			if status.Code(err) == codes.NotFound {
+				continue
+			}
+
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		statuses[runtimeContainer.Id] = containerStatus
+
+		// log file is removed along with the pod, runtime may keep exited container a bit longer
+		if containerStatus.LogPath == "" {
+			continue
+		}
+
+		if _, err := os.Stat(containerStatus.LogPath); err != nil {
+			continue
+		}
+
+		containers[containerStatus.LogPath] = &Container{
+			Type:    containerType,
+			ID:      runtimeContainer.Id,
+			LogPath: containerStatus.LogPath,
+			State:   StateSection{Running: runtimeContainer.State != runtimeapi.ContainerState_CONTAINER_EXITED},
+			Config:  ConfigSection{Labels: runtimeContainer.Labels},
+		}
+	}
+
+	provider.statuses = statuses
+	return containers, nil
+}
+
+// runtimeContainerType returns type of the containers, which is the format of their log lines
+func (provider *ProviderCRI) runtimeContainerType() (string, error) {
+	if provider.containerType != "" {
+		return provider.containerType, nil
+	}
+
+	name, err := provider.runtime.RuntimeName()
+
+	if err != nil {
+		return "", err
+	}
+
+	switch name {
+	case runtimeNameCRIO:
+		provider.containerType = common.CRITypeCRIO
+	case runtimeNameDocker:
+		provider.containerType = common.CRITypeDocker
+	default:
+		provider.containerType = common.CRITypeContainerD
+	}
+
+	provider.logger.Infof("containers provider: runtime '%s', containers type '%s'", name, provider.containerType)
+	return provider.containerType, nil
+}
@@ -0,0 +1,110 @@
+package containers
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	runtimeapi "k8s.io/cri-api/pkg/apis/runtime/v1"
+
+	"github.com/2gis/loggo/common"
+	"github.com/2gis/loggo/components/cri"
+	"github.com/2gis/loggo/logging"
+	"github.com/2gis/loggo/tests/mocks"
+)
+
+func TestProviderCRI(t *testing.T) {
+	logsPath := t.TempDir()
+	logPath := func(name string) string {
+		return filepath.Join(logsPath, "ns_pod_uid", name, "0.log")
+	}
+
+	for _, name := range []string{"app", "init"} {
+		assert.NoError(t, os.MkdirAll(filepath.Dir(logPath(name)), 0755))
+		assert.NoError(t, ioutil.WriteFile(logPath(name), []byte{}, 0644))
+	}
+
+	labels := map[string]string{
+		common.LabelKubernetesPodNamespace:  "ns",
+		common.LabelKubernetesPodName:       "pod",
+		common.LabelKubernetesContainerName: "app",
+	}
+	app := &runtimeapi.Container{Id: "app", State: runtimeapi.ContainerState_CONTAINER_RUNNING, Labels: labels}
+	initContainer := &runtimeapi.Container{Id: "init", State: runtimeapi.ContainerState_CONTAINER_EXITED}
+	// log file of the container has been removed along with its pod
+	removed := &runtimeapi.Container{Id: "removed", State: runtimeapi.ContainerState_CONTAINER_EXITED}
+	// container has been removed between listing and status request
+	gone := &runtimeapi.Container{Id: "gone", State: runtimeapi.ContainerState_CONTAINER_EXITED}
+
+	runtime := mocks.NewRuntimeServiceStub(app, initContainer, removed, gone)
+	runtime.SetLogPath("app", logPath("app"))
+	runtime.SetLogPath("init", logPath("init"))
+	runtime.SetLogPath("removed", filepath.Join(logsPath, "ns_removed_uid", "app", "0.log"))
+
+	endpoint, err := runtime.Serve(filepath.Join(t.TempDir(), "cri.sock"))
+	assert.NoError(t, err)
+	defer runtime.Stop()
+
+	client, err := cri.NewClient(endpoint, time.Second)
+	assert.NoError(t, err)
+	defer client.Close()
+
+	provider := NewProviderCRI(client, logging.NewLoggerDefault())
+
+	containers, err := provider.Containers()
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(containers))
+
+	container := containers[logPath("app")]
+	assert.Equal(t, common.CRITypeContainerD, container.Type)
+	assert.Equal(t, "app", container.ID)
+	assert.True(t, container.Running())
+	assert.Equal(t, "app", container.GetName())
+	assert.Equal(t, "pod", container.GetPodName())
+	assert.Equal(t, "ns", container.GetPodNamespace())
+
+	assert.False(t, containers[logPath("init")].Running())
+	assert.Equal(t, 4, runtime.StatusRequests())
+
+	// statuses are requested for new containers only
+	app.State = runtimeapi.ContainerState_CONTAINER_EXITED
+	runtime.SetContainers(app, initContainer, removed)
+
+	containers, err = provider.Containers()
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(containers))
+	assert.False(t, containers[logPath("app")].Running())
+	assert.Equal(t, 4, runtime.StatusRequests())
+
+	// containers are unknown while runtime is unavailable
+	runtime.Stop()
+	_, err = provider.Containers()
+	assert.Error(t, err)
+}
+
+func TestProviderCRIContainerType(t *testing.T) {
+	for name, expected := range map[string]string{
+		"containerd": common.CRITypeContainerD,
+		"cri-o":      common.CRITypeCRIO,
+		"docker":     common.CRITypeDocker,
+	} {
+		runtime := mocks.NewRuntimeServiceStub()
+		runtime.SetRuntimeName(name)
+
+		endpoint, err := runtime.Serve(filepath.Join(t.TempDir(), "cri.sock"))
+		assert.NoError(t, err)
+
+		client, err := cri.NewClient(endpoint, time.Second)
+		assert.NoError(t, err)
+
+		containerType, err := NewProviderCRI(client, logging.NewLoggerDefault()).runtimeContainerType()
+		assert.NoError(t, err)
+		assert.Equal(t, expected, containerType)
+
+		assert.NoError(t, client.Close())
+		runtime.Stop()
+	}
+}
@@ -0,0 +1,80 @@
+package cri
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	runtimeapi "k8s.io/cri-api/pkg/apis/runtime/v1"
+)
+
+// Client is a CRI runtime service client
+type Client struct {
+	connection *grpc.ClientConn
+	runtime    runtimeapi.RuntimeServiceClient
+	timeout    time.Duration
+}
+
+// NewClient is a Client constructor; endpoint is the runtime socket, unix:///run/containerd/containerd.sock for
+// instance. Connection is established lazily, on the first request
+func NewClient(endpoint string, timeout time.Duration) (*Client, error) {
+	connection, err := grpc.Dial(endpoint, grpc.WithTransportCredentials(insecure.NewCredentials()))
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &Client{
+		connection: connection,
+		runtime:    runtimeapi.NewRuntimeServiceClient(connection),
+		timeout:    timeout,
+	}, nil
+}
+
+// ListContainers returns all containers known to the runtime, exited ones included
+func (c *Client) ListContainers() ([]*runtimeapi.Container, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+
+	response, err := c.runtime.ListContainers(ctx, &runtimeapi.ListContainersRequest{})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return response.Containers, nil
+}
+
+// ContainerStatus returns status of the container, log path included
+func (c *Client) ContainerStatus(id string) (*runtimeapi.ContainerStatus, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+
+	response, err := c.runtime.ContainerStatus(ctx, &runtimeapi.ContainerStatusRequest{ContainerId: id})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return response.Status, nil
+}
+
+// RuntimeName returns name of the runtime, containerd or cri-o for instance
+func (c *Client) RuntimeName() (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+
+	response, err := c.runtime.Version(ctx, &runtimeapi.VersionRequest{})
+
+	if err != nil {
+		return "", err
+	}
+
+	return response.RuntimeName, nil
+}
+
+// Close closes runtime connection
+func (c *Client) Close() error {
+	return c.connection.Close()
+}
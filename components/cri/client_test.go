@@ -0,0 +1,48 @@
+package cri
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	runtimeapi "k8s.io/cri-api/pkg/apis/runtime/v1"
+
+	"github.com/2gis/loggo/tests/mocks"
+)
+
+func TestClientListContainers(t *testing.T) {
+	runtime := mocks.NewRuntimeServiceStub(
+		&runtimeapi.Container{Id: "abc", State: runtimeapi.ContainerState_CONTAINER_RUNNING},
+		&runtimeapi.Container{Id: "def", State: runtimeapi.ContainerState_CONTAINER_EXITED},
+	)
+	endpoint, err := runtime.Serve(filepath.Join(t.TempDir(), "cri.sock"))
+	assert.NoError(t, err)
+	defer runtime.Stop()
+
+	client, err := NewClient(endpoint, time.Second)
+	assert.NoError(t, err)
+	defer client.Close()
+
+	containers, err := client.ListContainers()
+	assert.NoError(t, err)
+	assert.Len(t, containers, 2)
+	assert.Equal(t, "abc", containers[0].Id)
+	assert.Equal(t, runtimeapi.ContainerState_CONTAINER_EXITED, containers[1].State)
+
+	runtime.SetLogPath("abc", "/var/log/pods/ns_pod_uid/app/0.log")
+	status, err := client.ContainerStatus("abc")
+	assert.NoError(t, err)
+	assert.Equal(t, "/var/log/pods/ns_pod_uid/app/0.log", status.LogPath)
+
+	_, err = client.ContainerStatus("def")
+	assert.Error(t, err)
+
+	name, err := client.RuntimeName()
+	assert.NoError(t, err)
+	assert.Equal(t, "containerd", name)
+
+	runtime.Stop()
+	_, err = client.ListContainers()
+	assert.Error(t, err)
+}
@@ -0,0 +1,164 @@
+package files
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/2gis/loggo/common"
+	"github.com/2gis/loggo/components/k8s"
+	"github.com/2gis/loggo/parsers"
+)
+
+// InputRecord is the struct to unmarshal yaml list item of file inputs file to
+type InputRecord struct {
+	Paths            []string
+	Extends          map[string]string
+	Parser           string
+	Regexp           string `yaml:"regex"`
+	MultilinePattern string `yaml:"multiline_pattern"`
+}
+
+// File is a host log file matched by an input
+type File struct {
+	Path     string
+	Extends  common.EntryMap
+	Settings *k8s.ParsingSettings
+}
+
+// Files is the map of files by path
+type Files map[string]*File
+
+// Present checks whether the path present in files map
+func (files Files) Present(path string) bool {
+	_, ok := files[path]
+	return ok
+}
+
+type input struct {
+	patterns []string
+	extends  map[string]string
+	settings *k8s.ParsingSettings
+}
+
+// ProviderFiles provides host log files matching glob patterns of inputs
+type ProviderFiles struct {
+	inputs []*input
+}
+
+// LoadInputRecords reads file inputs from yaml file
+func LoadInputRecords(filePath string) ([]InputRecord, error) {
+	data, err := ioutil.ReadFile(filePath)
+
+	if err != nil {
+		return nil, err
+	}
+
+	records := make([]InputRecord, 0)
+	err = yaml.UnmarshalStrict(data, &records)
+	return records, err
+}
+
+// NewProviderFiles is a ProviderFiles constructor
+func NewProviderFiles(records []InputRecord) (*ProviderFiles, error) {
+	inputs := make([]*input, 0, len(records))
+
+	for i, record := range records {
+		input, err := newInput(record)
+
+		if err != nil {
+			return nil, fmt.Errorf("invalid file input %d, %w", i, err)
+		}
+
+		inputs = append(inputs, input)
+	}
+
+	return &ProviderFiles{inputs: inputs}, nil
+}
+
+func newInput(record InputRecord) (*input, error) {
+	if len(record.Paths) == 0 {
+		return nil, fmt.Errorf("paths must not be empty")
+	}
+
+	for _, pattern := range record.Paths {
+		if !filepath.IsAbs(pattern) {
+			return nil, fmt.Errorf("path '%s' must be absolute", pattern)
+		}
+
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("%w: '%s'", err, pattern)
+		}
+	}
+
+	settings := &k8s.ParsingSettings{Parser: record.Parser}
+	var err error
+
+	if record.Regexp != "" {
+		if settings.Regexp, err = regexp.Compile(record.Regexp); err != nil {
+			return nil, err
+		}
+	}
+
+	if record.MultilinePattern != "" {
+		if settings.MultilinePattern, err = regexp.Compile(record.MultilinePattern); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err = parsers.NewUserLogParser(settings.Parser, settings.Regexp); err != nil {
+		return nil, err
+	}
+
+	return &input{
+		patterns: record.Paths,
+		extends:  record.Extends,
+		settings: settings,
+	}, nil
+}
+
+// Files returns regular files matching inputs patterns; file matched by several inputs belongs to the first one
+func (p *ProviderFiles) Files() Files {
+	files := make(Files)
+
+	for _, input := range p.inputs {
+		for _, pattern := range input.patterns {
+			// patterns are validated, so the only error is ErrBadPattern
+			paths, _ := filepath.Glob(pattern)
+
+			for _, path := range paths {
+				if files.Present(path) {
+					continue
+				}
+
+				if info, err := os.Stat(path); err != nil || !info.Mode().IsRegular() {
+					continue
+				}
+
+				files[path] = &File{
+					Path:     path,
+					Extends:  input.fileExtends(path),
+					Settings: input.settings,
+				}
+			}
+		}
+	}
+
+	return files
+}
+
+// fileExtends returns static extends of the input along with file path
+func (input *input) fileExtends(path string) common.EntryMap {
+	extends := make(common.EntryMap, len(input.extends)+1)
+
+	for key, value := range input.extends {
+		extends[key] = value
+	}
+
+	extends[common.LabelFilePath] = path
+	return extends
+}
@@ -0,0 +1,92 @@
+package files
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/2gis/loggo/common"
+)
+
+func TestProviderFiles(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, os.Mkdir(filepath.Join(dir, "audit"), 0755))
+	assert.NoError(t, os.Mkdir(filepath.Join(dir, "app.log.d"), 0755))
+
+	for _, name := range []string{"audit/audit.log", "syslog", "app.log"} {
+		assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, name), []byte("line\n"), 0644))
+	}
+
+	provider, err := NewProviderFiles([]InputRecord{
+		{Paths: []string{filepath.Join(dir, "audit", "*.log")}, Extends: map[string]string{"type": "audit"}},
+		{Paths: []string{filepath.Join(dir, "*.log*"), filepath.Join(dir, "syslog")}, Parser: "logfmt"},
+	})
+	assert.NoError(t, err)
+
+	files := provider.Files()
+	assert.Len(t, files, 3)
+	assert.False(t, files.Present(filepath.Join(dir, "app.log.d")))
+
+	audit := files[filepath.Join(dir, "audit", "audit.log")]
+	assert.Equal(t, common.EntryMap{
+		"type":               "audit",
+		common.LabelFilePath: filepath.Join(dir, "audit", "audit.log"),
+	}, audit.Extends)
+	assert.Equal(t, "", audit.Settings.Parser)
+
+	syslog := files[filepath.Join(dir, "syslog")]
+	assert.Equal(t, common.EntryMap{common.LabelFilePath: filepath.Join(dir, "syslog")}, syslog.Extends)
+	assert.Equal(t, "logfmt", syslog.Settings.Parser)
+	assert.True(t, files.Present(filepath.Join(dir, "app.log")))
+
+	// files appearing later are found
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "audit", "audit-2.log"), []byte("line\n"), 0644))
+	assert.True(t, provider.Files().Present(filepath.Join(dir, "audit", "audit-2.log")))
+}
+
+func TestNewProviderFilesInvalid(t *testing.T) {
+	for _, record := range []InputRecord{
+		{},
+		{Paths: []string{"var/log/*.log"}},
+		{Paths: []string{"/var/log/[.log"}},
+		{Paths: []string{"/var/log/*.log"}, Parser: "xml"},
+		{Paths: []string{"/var/log/*.log"}, Parser: "regex"},
+		{Paths: []string{"/var/log/*.log"}, Parser: "regex", Regexp: "("},
+		{Paths: []string{"/var/log/*.log"}, MultilinePattern: "("},
+	} {
+		_, err := NewProviderFiles([]InputRecord{record})
+		assert.Error(t, err, record)
+	}
+}
+
+func TestLoadInputRecords(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "inputs.yaml")
+	assert.NoError(t, ioutil.WriteFile(path, []byte(`
+- paths: ["/var/log/audit/audit.log"]
+  extends:
+    type: audit
+  parser: regex
+  regex: '^type=(?P<audit_type>\S+) (.*)$'
+- paths: ["/var/log/app/*.log"]
+  multiline_pattern: '^\S'
+`), 0644))
+
+	records, err := LoadInputRecords(path)
+	assert.NoError(t, err)
+	assert.Equal(t, []InputRecord{
+		{
+			Paths:   []string{"/var/log/audit/audit.log"},
+			Extends: map[string]string{"type": "audit"},
+			Parser:  "regex",
+			Regexp:  `^type=(?P<audit_type>\S+) (.*)$`,
+		},
+		{Paths: []string{"/var/log/app/*.log"}, MultilinePattern: `^\S`},
+	}, records)
+
+	assert.NoError(t, ioutil.WriteFile(path, []byte("- path: /var/log/syslog\n"), 0644))
+	_, err = LoadInputRecords(path)
+	assert.Error(t, err)
+}
@@ -4,3 +4,11 @@ package components
 type Retriever interface {
 	Retrieve() error
 }
+
+// RetrieverFunc lets ordinary function be used as Retriever
+type RetrieverFunc func() error
+
+// Retrieve calls the function
+func (f RetrieverFunc) Retrieve() error {
+	return f()
+}
@@ -0,0 +1,325 @@
+package k8s
+
+import (
+	"context"
+	"regexp"
+	"sync"
+	"time"
+
+	core "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+
+	"github.com/2gis/loggo/common"
+	"github.com/2gis/loggo/configuration"
+	"github.com/2gis/loggo/logging"
+)
+
+/* Event entry user log fields */
+const (
+	EventKeyType                = "type"
+	EventKeyReason              = "reason"
+	EventKeyMessage             = "message"
+	EventKeyCount               = "count"
+	EventKeyAction              = "action"
+	EventKeySourceComponent     = "source_component"
+	EventKeyReportingController = "reporting_controller"
+	EventKeyFirstTimestamp      = "first_timestamp"
+	EventKeyLastTimestamp       = "last_timestamp"
+	EventKeyInvolvedObject      = "involved_object"
+)
+
+/* Involved object kinds extends are set for */
+const (
+	kindPod  = "Pod"
+	kindNode = "Node"
+)
+
+// patternContainerFieldPath extracts container name from involved object field path, e.g. spec.containers{app}
+var patternContainerFieldPath = regexp.MustCompile(`^spec\.(?:initContainers|containers|ephemeralContainers)\{(.+)\}$`)
+
+// EventsCollector watches K8S events and sends them to the output as entries; only the instance holding
+// the lease collects events. Events are sent once per count increase, updates not changing count (including
+// informer resyncs) are skipped, as well as events that happened before the instance became the leader minus
+// the lease duration
+type EventsCollector struct {
+	mu      sync.Mutex
+	counts  map[string]int32
+	started time.Time
+
+	client   kubernetes.Interface
+	election leaderelection.LeaderElectionConfig
+	resync   time.Duration
+	lease    time.Duration
+
+	parserConfig configuration.ParserConfig
+	extends      common.EntryMap
+
+	// informers of client-go can't be waited for, so handlers still running check output is open under the lock
+	outputMu     sync.RWMutex
+	outputClosed bool
+	output       chan common.EntryMap
+
+	logger logging.Logger
+}
+
+// NewEventsCollector is an EventsCollector constructor; identity must be unique among loggo instances, extends are
+// common fields of entries, see configuration.K8SExtends
+func NewEventsCollector(client kubernetes.Interface, config configuration.EventsConfig,
+	parserConfig configuration.ParserConfig, extends common.EntryMap, identity string,
+	logger logging.Logger) (*EventsCollector, error) {
+	collector := &EventsCollector{
+		counts: make(map[string]int32),
+
+		client: client,
+		resync: time.Duration(config.ResyncIntervalSec) * time.Second,
+		lease:  config.LeaseDuration,
+
+		parserConfig: parserConfig,
+		extends:      extends,
+
+		output: make(chan common.EntryMap),
+		logger: logger,
+	}
+
+	collector.election = leaderelection.LeaderElectionConfig{
+		Lock: &resourcelock.LeaseLock{
+			LeaseMeta:  metav1.ObjectMeta{Namespace: config.LeaseNamespace, Name: config.LeaseName},
+			Client:     client.CoordinationV1(),
+			LockConfig: resourcelock.ResourceLockConfig{Identity: identity},
+		},
+		LeaseDuration:   config.LeaseDuration,
+		RenewDeadline:   config.LeaseDuration * 2 / 3,
+		RetryPeriod:     config.LeaseDuration / 6,
+		ReleaseOnCancel: true,
+		Name:            config.LeaseName,
+		Callbacks: leaderelection.LeaderCallbacks{
+			OnStartedLeading: collector.collect,
+			OnStoppedLeading: func() {
+				logger.Infof("Stopped collecting K8S events as '%s'", identity)
+			},
+			OnNewLeader: func(leader string) {
+				logger.Infof("K8S events are collected by '%s'", leader)
+			},
+		},
+	}
+
+	// config is validated once, electors are created per campaign
+	if _, err := leaderelection.NewLeaderElector(collector.election); err != nil {
+		return nil, err
+	}
+
+	return collector, nil
+}
+
+// Out is an output channel accessor
+func (c *EventsCollector) Out() <-chan common.EntryMap {
+	return c.output
+}
+
+// Start competes for the lease and collects events while holding it; output is closed when context is done
+func (c *EventsCollector) Start(ctx context.Context) {
+	defer c.closeOutput()
+
+	for ctx.Err() == nil {
+		// elector keeps the state of the lost leadership, so every campaign starts with the new one
+		elector, err := leaderelection.NewLeaderElector(c.election)
+
+		if err != nil {
+			c.logger.Errorf("Unable to init K8S events leader elector, %s", err)
+			return
+		}
+
+		elector.Run(ctx)
+	}
+}
+
+// collect watches events until leadership is lost
+func (c *EventsCollector) collect(ctx context.Context) {
+	c.mu.Lock()
+	c.counts = make(map[string]int32)
+	c.started = time.Now().Add(-c.lease)
+	c.mu.Unlock()
+
+	factory := informers.NewSharedInformerFactory(c.client, c.resync)
+	informer := factory.Core().V1().Events().Informer()
+	informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			c.handle(ctx, obj)
+		},
+		UpdateFunc: func(_, obj interface{}) {
+			c.handle(ctx, obj)
+		},
+		DeleteFunc: c.forget,
+	})
+
+	factory.Start(ctx.Done())
+	c.logger.Info("Started collecting K8S events")
+	<-ctx.Done()
+}
+
+func (c *EventsCollector) handle(ctx context.Context, obj interface{}) {
+	event, ok := obj.(*core.Event)
+
+	if !ok || !c.changed(event) {
+		return
+	}
+
+	c.outputMu.RLock()
+	defer c.outputMu.RUnlock()
+
+	if c.outputClosed {
+		return
+	}
+
+	select {
+	case c.output <- c.entryMap(event):
+	case <-ctx.Done():
+	}
+}
+
+// closeOutput closes output once handlers sending to it have returned; handlers block on sending no longer than
+// campaign context, which is done before Start returns
+func (c *EventsCollector) closeOutput() {
+	c.outputMu.Lock()
+	defer c.outputMu.Unlock()
+
+	c.outputClosed = true
+	close(c.output)
+}
+
+// changed checks if event is new or its count increased, then remembers event count
+func (c *EventsCollector) changed(event *core.Event) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	count := eventCount(event)
+	previous, known := c.counts[string(event.UID)]
+	c.counts[string(event.UID)] = count
+
+	if known {
+		return count > previous
+	}
+
+	return !eventTime(event).Before(c.started)
+}
+
+func (c *EventsCollector) forget(obj interface{}) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+
+	event, ok := obj.(*core.Event)
+
+	if !ok {
+		return
+	}
+
+	c.mu.Lock()
+	delete(c.counts, string(event.UID))
+	c.mu.Unlock()
+}
+
+// entryMap converts event to entry; extends describe involved object the same way container entries do
+func (c *EventsCollector) entryMap(event *core.Event) common.EntryMap {
+	object := event.InvolvedObject
+	userLog := common.EntryMap{
+		EventKeyType:                event.Type,
+		EventKeyReason:              event.Reason,
+		EventKeyMessage:             event.Message,
+		EventKeyCount:               eventCount(event),
+		EventKeyAction:              event.Action,
+		EventKeySourceComponent:     event.Source.Component,
+		EventKeyReportingController: event.ReportingController,
+		EventKeyInvolvedObject: common.EntryMap{
+			"kind":       object.Kind,
+			"namespace":  object.Namespace,
+			"name":       object.Name,
+			"uid":        string(object.UID),
+			"field_path": object.FieldPath,
+		},
+	}
+
+	if !event.FirstTimestamp.IsZero() {
+		userLog[EventKeyFirstTimestamp] = event.FirstTimestamp.UTC().Format(time.RFC3339)
+	}
+
+	if !event.LastTimestamp.IsZero() {
+		userLog[EventKeyLastTimestamp] = event.LastTimestamp.UTC().Format(time.RFC3339)
+	}
+
+	extends := make(common.EntryMap, len(c.extends)+3)
+	extends.Extend(c.extends)
+	delete(extends, common.KubernetesNodeHostname)
+
+	extends[common.KubernetesNamespaceName] = event.Namespace
+
+	if object.Namespace != "" {
+		extends[common.KubernetesNamespaceName] = object.Namespace
+	}
+
+	switch object.Kind {
+	case kindPod:
+		extends[common.KubernetesPodName] = object.Name
+
+		if output := patternContainerFieldPath.FindStringSubmatch(object.FieldPath); output != nil {
+			extends[common.KubernetesContainerName] = output[1]
+		}
+	case kindNode:
+		extends[common.KubernetesNodeHostname] = object.Name
+	}
+
+	if event.Source.Host != "" {
+		extends[common.KubernetesNodeHostname] = event.Source.Host
+	}
+
+	result := common.EntryMap{}
+
+	if c.parserConfig.UserLogFieldsKey != "" {
+		result[c.parserConfig.UserLogFieldsKey] = userLog
+	} else {
+		result = userLog
+	}
+
+	if c.parserConfig.ExtendsFieldsKey != "" {
+		result[c.parserConfig.ExtendsFieldsKey] = extends
+	} else {
+		result.Extend(extends)
+	}
+
+	result[common.LabelTime] = eventTime(event).UTC().Format(time.RFC3339)
+	return result
+}
+
+// eventCount returns occurrences count of event, either deprecated core or series one
+func eventCount(event *core.Event) int32 {
+	if event.Series != nil && event.Series.Count > event.Count {
+		return event.Series.Count
+	}
+
+	if event.Count == 0 {
+		return 1
+	}
+
+	return event.Count
+}
+
+// eventTime returns time of the last event occurrence
+func eventTime(event *core.Event) time.Time {
+	switch {
+	case event.Series != nil && !event.Series.LastObservedTime.IsZero():
+		return event.Series.LastObservedTime.Time
+	case !event.LastTimestamp.IsZero():
+		return event.LastTimestamp.Time
+	case !event.EventTime.IsZero():
+		return event.EventTime.Time
+	case !event.FirstTimestamp.IsZero():
+		return event.FirstTimestamp.Time
+	}
+
+	return event.CreationTimestamp.Time
+}
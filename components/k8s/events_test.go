@@ -0,0 +1,169 @@
+package k8s
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	core "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/2gis/loggo/common"
+	"github.com/2gis/loggo/configuration"
+	"github.com/2gis/loggo/logging"
+)
+
+func newTestEvent(name string, count int32, last time.Time) *core.Event {
+	return &core.Event{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "prod", Name: name, UID: types.UID(name)},
+		InvolvedObject: core.ObjectReference{
+			Kind:      "Pod",
+			Namespace: "prod",
+			Name:      "app-5d8f",
+			FieldPath: "spec.containers{app}",
+		},
+		Type:           core.EventTypeWarning,
+		Reason:         "BackOff",
+		Message:        "Back-off restarting failed container",
+		Count:          count,
+		Source:         core.EventSource{Component: "kubelet", Host: "node-1"},
+		FirstTimestamp: metav1.NewTime(last.Add(-time.Minute)),
+		LastTimestamp:  metav1.NewTime(last),
+	}
+}
+
+func newTestEventsCollector(t *testing.T, client *fake.Clientset) *EventsCollector {
+	collector, err := NewEventsCollector(
+		client,
+		configuration.EventsConfig{LeaseNamespace: "loggo", LeaseName: "loggo-events", LeaseDuration: 3 * time.Second},
+		configuration.ParserConfig{UserLogFieldsKey: "log", ExtendsFieldsKey: "extends"},
+		common.EntryMap{common.LabelDataCenter: "dc1", common.KubernetesNodeHostname: "loggo-node"},
+		"loggo-1",
+		logging.NewLoggerDefault(),
+	)
+	assert.NoError(t, err)
+	return collector
+}
+
+func TestEventsCollectorEntryMap(t *testing.T) {
+	collector := newTestEventsCollector(t, fake.NewSimpleClientset())
+	last := time.Date(2021, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	assert.Equal(t, common.EntryMap{
+		"log": common.EntryMap{
+			EventKeyType:                "Warning",
+			EventKeyReason:              "BackOff",
+			EventKeyMessage:             "Back-off restarting failed container",
+			EventKeyCount:               int32(3),
+			EventKeyAction:              "",
+			EventKeySourceComponent:     "kubelet",
+			EventKeyReportingController: "",
+			EventKeyFirstTimestamp:      "2021-01-01T09:59:00Z",
+			EventKeyLastTimestamp:       "2021-01-01T10:00:00Z",
+			EventKeyInvolvedObject: common.EntryMap{
+				"kind":       "Pod",
+				"namespace":  "prod",
+				"name":       "app-5d8f",
+				"uid":        "",
+				"field_path": "spec.containers{app}",
+			},
+		},
+		"extends": common.EntryMap{
+			common.LabelDataCenter:         "dc1",
+			common.KubernetesNamespaceName: "prod",
+			common.KubernetesPodName:       "app-5d8f",
+			common.KubernetesContainerName: "app",
+			common.KubernetesNodeHostname:  "node-1",
+		},
+		common.LabelTime: "2021-01-01T10:00:00Z",
+	}, collector.entryMap(newTestEvent("backoff", 3, last)))
+
+	event := &core.Event{
+		ObjectMeta:     metav1.ObjectMeta{Namespace: "default", Name: "node-2.oom"},
+		InvolvedObject: core.ObjectReference{Kind: "Node", Name: "node-2"},
+		Reason:         "SystemOOM",
+		EventTime:      metav1.NewMicroTime(last),
+	}
+	entryMap := collector.entryMap(event)
+
+	assert.Equal(t, common.EntryMap{
+		common.LabelDataCenter:         "dc1",
+		common.KubernetesNamespaceName: "default",
+		common.KubernetesNodeHostname:  "node-2",
+	}, entryMap["extends"])
+	assert.Equal(t, int32(1), entryMap["log"].(common.EntryMap)[EventKeyCount])
+	assert.Equal(t, "2021-01-01T10:00:00Z", entryMap[common.LabelTime])
+}
+
+func TestEventsCollectorChanged(t *testing.T) {
+	collector := newTestEventsCollector(t, fake.NewSimpleClientset())
+	collector.started = time.Now().Add(-time.Minute)
+
+	assert.False(t, collector.changed(newTestEvent("old", 1, time.Now().Add(-time.Hour))))
+	assert.True(t, collector.changed(newTestEvent("old", 2, time.Now())))
+
+	assert.True(t, collector.changed(newTestEvent("new", 1, time.Now())))
+	assert.False(t, collector.changed(newTestEvent("new", 1, time.Now())))
+	assert.True(t, collector.changed(newTestEvent("new", 5, time.Now())))
+	assert.False(t, collector.changed(newTestEvent("new", 4, time.Now())))
+
+	collector.forget(newTestEvent("new", 5, time.Now()))
+	assert.True(t, collector.changed(newTestEvent("new", 5, time.Now())))
+}
+
+func TestEventsCollectorStart(t *testing.T) {
+	client := fake.NewSimpleClientset(newTestEvent("old", 1, time.Now().Add(-time.Hour)))
+	collector := newTestEventsCollector(t, client)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+		collector.Start(ctx)
+	}()
+
+	receive := func() common.EntryMap {
+		select {
+		case entryMap := <-collector.Out():
+			return entryMap["log"].(common.EntryMap)
+		case <-time.After(5 * time.Second):
+			assert.Fail(t, "event hasn't been collected")
+			return nil
+		}
+	}
+
+	events := client.CoreV1().Events("prod")
+
+	for {
+		_, err := client.CoordinationV1().Leases("loggo").Get(ctx, "loggo-events", metav1.GetOptions{})
+
+		if err == nil {
+			break
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// the informer may start watching after the event is created, it's listed then
+	_, err := events.Create(ctx, newTestEvent("backoff", 1, time.Now()), metav1.CreateOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, int32(1), receive()[EventKeyCount])
+
+	_, err = events.Update(ctx, newTestEvent("backoff", 2, time.Now()), metav1.UpdateOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, int32(2), receive()[EventKeyCount])
+
+	cancel()
+	<-done
+
+	_, ok := <-collector.Out()
+	assert.False(t, ok)
+
+	// informer handlers may still be running after Start returns
+	assert.NotPanics(t, func() {
+		collector.handle(ctx, newTestEvent("late", 1, time.Now()))
+	})
+}
@@ -1,7 +1,20 @@
 package k8s
 
+import (
+	"context"
+
+	"github.com/2gis/loggo/common"
+)
+
 // ServicesProvider is an interface that allows to get services for SLA metering
 type ServicesProvider interface {
 	Retrieve() error
 	GetServiceByHost(host string) *Service
 }
+
+// PodsProvider is an interface that allows to get pods of the current node
+type PodsProvider interface {
+	Start(ctx context.Context) error
+	GetPod(namespace, name string) *Pod
+	Extends(namespace, name string) common.EntryMap
+}
@@ -0,0 +1,193 @@
+package k8s
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	core "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/2gis/loggo/common"
+	"github.com/2gis/loggo/configuration"
+)
+
+const (
+	ownerKindReplicaSet  = "ReplicaSet"
+	ownerKindDeployment  = "Deployment"
+	labelPodTemplateHash = "pod-template-hash"
+)
+
+// Pod is a structured "pod" representation, containing pod metadata needed for its containers processing
+type Pod struct {
+	Name        string
+	Namespace   string
+	IP          string
+	OwnerKind   string
+	OwnerName   string
+	Labels      map[string]string
+	Annotations map[string]string
+}
+
+// ParsingSettings is a per-container parsing configuration set by pod annotations
+type ParsingSettings struct {
+	Parser           string
+	Regexp           *regexp.Regexp
+	MultilinePattern *regexp.Regexp
+	Exclude          bool
+}
+
+// CreatePod constructs Pod object from K8S pod object
+func CreatePod(pod *core.Pod) *Pod {
+	ownerKind, ownerName := podOwner(pod)
+
+	return &Pod{
+		Name:        pod.GetName(),
+		Namespace:   pod.GetNamespace(),
+		IP:          pod.Status.PodIP,
+		OwnerKind:   ownerKind,
+		OwnerName:   ownerName,
+		Labels:      pod.GetLabels(),
+		Annotations: pod.GetAnnotations(),
+	}
+}
+
+// podOwner returns kind and name of the workload controlling the pod; pods of ReplicaSet created by Deployment
+// are considered to be owned by Deployment, which name is derived from ReplicaSet name to avoid API calls
+func podOwner(pod *core.Pod) (string, string) {
+	owner := metav1.GetControllerOf(pod)
+
+	if owner == nil {
+		return "", ""
+	}
+
+	if owner.Kind == ownerKindReplicaSet {
+		hash, ok := pod.GetLabels()[labelPodTemplateHash]
+
+		if ok && strings.HasSuffix(owner.Name, "-"+hash) {
+			return ownerKindDeployment, strings.TrimSuffix(owner.Name, "-"+hash)
+		}
+	}
+
+	return owner.Kind, owner.Name
+}
+
+// Extends returns pod metadata to extend entries with; only allowed labels and annotations are included
+func (p *Pod) Extends(labels, annotations []string) common.EntryMap {
+	extends := make(common.EntryMap)
+
+	if p.IP != "" {
+		extends[common.KubernetesPodIP] = p.IP
+	}
+
+	if p.OwnerName != "" {
+		extends[common.KubernetesOwnerKind] = p.OwnerKind
+		extends[common.KubernetesOwnerName] = p.OwnerName
+	}
+
+	setAllowed(extends, common.KubernetesLabelsPrefix, p.Labels, labels)
+	setAllowed(extends, common.KubernetesAnnotationsPrefix, p.Annotations, annotations)
+	return extends
+}
+
+func setAllowed(extends common.EntryMap, prefix string, values map[string]string, allowed []string) {
+	for _, key := range allowed {
+		if value, ok := values[key]; ok {
+			extends[prefix+key] = value
+		}
+	}
+}
+
+// ParsingSettings returns parsing settings for pod container, if pod has any related annotations
+func (p *Pod) ParsingSettings(config configuration.PodsConfig, container string) (*ParsingSettings, error) {
+	return CreateParsingSettings(config, p.Annotations, container)
+}
+
+// CreateParsingSettings tries to construct ParsingSettings object from annotations dictionary;
+// container specific annotation "<annotation>.<container>" takes precedence over pod wide "<annotation>"
+func CreateParsingSettings(
+	config configuration.PodsConfig, annotations map[string]string, container string) (*ParsingSettings, error) {
+	parser, parserOk := annotationValue(annotations, config.AnnotationParser, container)
+	pattern, patternOk := annotationValue(annotations, config.AnnotationParserRegexp, container)
+	multiline, multilineOk := annotationValue(annotations, config.AnnotationMultilinePattern, container)
+	exclude, excludeOk := annotationValue(annotations, config.AnnotationExclude, container)
+
+	if !(parserOk || patternOk || multilineOk || excludeOk) {
+		return nil, nil
+	}
+
+	settings := &ParsingSettings{
+		Parser:  strings.TrimSpace(parser),
+		Exclude: strings.TrimSpace(exclude) == configuration.AnnotationExcludeTrue,
+	}
+
+	switch settings.Parser {
+	case "", common.UserLogParserJSON, common.UserLogParserLogfmt:
+	case common.UserLogParserRegexp:
+		if pattern == "" {
+			return nil, fmt.Errorf(
+				"there must be annotation '%s' with regular expression for parser '%s'",
+				config.AnnotationParserRegexp,
+				common.UserLogParserRegexp,
+			)
+		}
+
+		regex, err := regexp.Compile(pattern)
+
+		if err != nil {
+			return nil, fmt.Errorf("unable to compile regexp '%s' in '%s'", pattern, config.AnnotationParserRegexp)
+		}
+
+		if !hasNamedGroups(regex) {
+			return nil, fmt.Errorf(
+				"regexp '%s' in '%s' must contain at least one named group", pattern, config.AnnotationParserRegexp)
+		}
+
+		settings.Regexp = regex
+	default:
+		return nil, fmt.Errorf(
+			"unsupported parser '%s' in '%s', supported parsers: [%s]",
+			settings.Parser,
+			config.AnnotationParser,
+			strings.Join([]string{
+				common.UserLogParserJSON, common.UserLogParserLogfmt, common.UserLogParserRegexp,
+			}, ", "),
+		)
+	}
+
+	if multiline != "" {
+		regex, err := regexp.Compile(multiline)
+
+		if err != nil {
+			return nil, fmt.Errorf(
+				"unable to compile regexp '%s' in '%s'", multiline, config.AnnotationMultilinePattern)
+		}
+
+		settings.MultilinePattern = regex
+	}
+
+	return settings, nil
+}
+
+func annotationValue(annotations map[string]string, annotation, container string) (string, bool) {
+	if annotation == "" {
+		return "", false
+	}
+
+	if value, ok := annotations[fmt.Sprintf("%s.%s", annotation, container)]; ok {
+		return value, true
+	}
+
+	value, ok := annotations[annotation]
+	return value, ok
+}
+
+func hasNamedGroups(regex *regexp.Regexp) bool {
+	for _, name := range regex.SubexpNames() {
+		if name != "" {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,139 @@
+package k8s
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	core "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/2gis/loggo/common"
+	"github.com/2gis/loggo/configuration"
+)
+
+func TestCreateParsingSettings(t *testing.T) {
+	config := defaultPodsConfig()
+
+	settings, err := CreateParsingSettings(config, map[string]string{"unrelated": "value"}, "app")
+	assert.NoError(t, err)
+	assert.Nil(t, settings)
+
+	settings, err = CreateParsingSettings(config, map[string]string{
+		configuration.AnnotationParserDefault:                    common.UserLogParserLogfmt,
+		configuration.AnnotationMultilinePatternDefault + ".app": `^\d{4}-`,
+		configuration.AnnotationExcludeDefault + ".sidecar":      configuration.AnnotationExcludeTrue,
+	}, "app")
+	assert.NoError(t, err)
+	assert.Equal(t, common.UserLogParserLogfmt, settings.Parser)
+	assert.True(t, settings.MultilinePattern.MatchString("2020-09-10 started"))
+	assert.False(t, settings.Exclude)
+
+	settings, err = CreateParsingSettings(config, map[string]string{
+		configuration.AnnotationParserDefault:               common.UserLogParserLogfmt,
+		configuration.AnnotationParserDefault + ".sidecar":  common.UserLogParserJSON,
+		configuration.AnnotationExcludeDefault + ".sidecar": configuration.AnnotationExcludeTrue,
+	}, "sidecar")
+	assert.NoError(t, err)
+	assert.Equal(t, common.UserLogParserJSON, settings.Parser)
+	assert.True(t, settings.Exclude)
+
+	settings, err = CreateParsingSettings(config, map[string]string{
+		configuration.AnnotationParserDefault:       common.UserLogParserRegexp,
+		configuration.AnnotationParserRegexpDefault: `^(?P<level>\w+) (?P<message>.*)$`,
+	}, "app")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"", "level", "message"}, settings.Regexp.SubexpNames())
+}
+
+func TestCreateParsingSettingsInvalid(t *testing.T) {
+	config := defaultPodsConfig()
+
+	for _, annotations := range []map[string]string{
+		{configuration.AnnotationParserDefault: "yaml"},
+		{configuration.AnnotationParserDefault: common.UserLogParserRegexp},
+		{
+			configuration.AnnotationParserDefault:       common.UserLogParserRegexp,
+			configuration.AnnotationParserRegexpDefault: `^(\w+)`,
+		},
+		{
+			configuration.AnnotationParserDefault:       common.UserLogParserRegexp,
+			configuration.AnnotationParserRegexpDefault: `^(?P<level>\w+`,
+		},
+		{configuration.AnnotationMultilinePatternDefault: `[`},
+	} {
+		settings, err := CreateParsingSettings(config, annotations, "app")
+		assert.Error(t, err, annotations)
+		assert.Nil(t, settings)
+	}
+}
+
+func defaultPodsConfig() configuration.PodsConfig {
+	return configuration.PodsConfig{
+		AnnotationParser:           configuration.AnnotationParserDefault,
+		AnnotationParserRegexp:     configuration.AnnotationParserRegexpDefault,
+		AnnotationMultilinePattern: configuration.AnnotationMultilinePatternDefault,
+		AnnotationExclude:          configuration.AnnotationExcludeDefault,
+	}
+}
+
+func TestCreatePodOwner(t *testing.T) {
+	controller := true
+	testCases := []struct {
+		name      string
+		owners    []v1.OwnerReference
+		labels    map[string]string
+		kind      string
+		ownerName string
+	}{
+		{
+			name:      "Deployment",
+			owners:    []v1.OwnerReference{{Kind: "ReplicaSet", Name: "app-5d8f7c", Controller: &controller}},
+			labels:    map[string]string{"pod-template-hash": "5d8f7c"},
+			kind:      "Deployment",
+			ownerName: "app",
+		},
+		{
+			name:      "Bare ReplicaSet",
+			owners:    []v1.OwnerReference{{Kind: "ReplicaSet", Name: "app", Controller: &controller}},
+			kind:      "ReplicaSet",
+			ownerName: "app",
+		},
+		{
+			name:      "StatefulSet",
+			owners:    []v1.OwnerReference{{Kind: "StatefulSet", Name: "db", Controller: &controller}},
+			kind:      "StatefulSet",
+			ownerName: "db",
+		},
+		{
+			name:   "Not a controller",
+			owners: []v1.OwnerReference{{Kind: "DaemonSet", Name: "agent"}},
+		},
+	}
+
+	for _, testCase := range testCases {
+		pod := CreatePod(&core.Pod{
+			ObjectMeta: v1.ObjectMeta{Name: "pod", Labels: testCase.labels, OwnerReferences: testCase.owners},
+		})
+
+		assert.Equal(t, testCase.kind, pod.OwnerKind, testCase.name)
+		assert.Equal(t, testCase.ownerName, pod.OwnerName, testCase.name)
+	}
+}
+
+func TestPodExtends(t *testing.T) {
+	pod := &Pod{
+		IP:          "10.0.0.1",
+		OwnerKind:   "DaemonSet",
+		OwnerName:   "agent",
+		Labels:      map[string]string{"app": "agent", "version": "1"},
+		Annotations: map[string]string{"team": "infra"},
+	}
+
+	assert.Equal(t, common.EntryMap{
+		common.KubernetesPodIP:                      "10.0.0.1",
+		common.KubernetesOwnerKind:                  "DaemonSet",
+		common.KubernetesOwnerName:                  "agent",
+		common.KubernetesLabelsPrefix + "app":       "agent",
+		common.KubernetesAnnotationsPrefix + "team": "infra",
+	}, pod.Extends([]string{"app", "missing"}, []string{"team"}))
+}
@@ -0,0 +1,147 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	listers "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/2gis/loggo/common"
+	"github.com/2gis/loggo/configuration"
+	"github.com/2gis/loggo/logging"
+)
+
+// ProviderK8SPods provides data about pods of the current node, leveraging K8S Api server watch through informer
+type ProviderK8SPods struct {
+	factories []informers.SharedInformerFactory
+	synced    []cache.InformerSynced
+
+	lister     listers.PodLister
+	nodeLister listers.NodeLister
+	nodeName   string
+
+	enrichment            bool
+	enrichmentLabels      []string
+	enrichmentAnnotations []string
+	enrichmentNodeLabels  []string
+
+	logger logging.Logger
+}
+
+// NewProviderK8SPods is a constructor for ProviderK8SPods; only pods scheduled to the nodeName are watched,
+// the node itself is watched only if node labels enrichment is requested
+func NewProviderK8SPods(
+	client kubernetes.Interface, config configuration.PodsConfig, nodeName string, logger logging.Logger) *ProviderK8SPods {
+	resync := time.Duration(config.ResyncIntervalSec) * time.Second
+	factory := informers.NewSharedInformerFactoryWithOptions(
+		client,
+		resync,
+		informers.WithTweakListOptions(func(options *metav1.ListOptions) {
+			options.FieldSelector = fields.OneTermEqualSelector("spec.nodeName", nodeName).String()
+		}),
+	)
+	informer := factory.Core().V1().Pods()
+
+	provider := &ProviderK8SPods{
+		factories: []informers.SharedInformerFactory{factory},
+		synced:    []cache.InformerSynced{informer.Informer().HasSynced},
+		lister:    informer.Lister(),
+		nodeName:  nodeName,
+
+		enrichment:            config.EnrichmentEnabled,
+		enrichmentLabels:      splitList(config.EnrichmentLabels),
+		enrichmentAnnotations: splitList(config.EnrichmentAnnotations),
+		enrichmentNodeLabels:  splitList(config.EnrichmentNodeLabels),
+
+		logger: logger,
+	}
+
+	if !provider.enrichment || len(provider.enrichmentNodeLabels) == 0 {
+		return provider
+	}
+
+	factoryNodes := informers.NewSharedInformerFactoryWithOptions(
+		client,
+		resync,
+		informers.WithTweakListOptions(func(options *metav1.ListOptions) {
+			options.FieldSelector = fields.OneTermEqualSelector("metadata.name", nodeName).String()
+		}),
+	)
+	informerNodes := factoryNodes.Core().V1().Nodes()
+
+	provider.factories = append(provider.factories, factoryNodes)
+	provider.synced = append(provider.synced, informerNodes.Informer().HasSynced)
+	provider.nodeLister = informerNodes.Lister()
+	return provider
+}
+
+// Start starts watching pods and waits for the cache to be filled; watching stops when context is done
+func (p *ProviderK8SPods) Start(ctx context.Context) error {
+	for _, factory := range p.factories {
+		factory.Start(ctx.Done())
+	}
+
+	if !cache.WaitForCacheSync(ctx.Done(), p.synced...) {
+		return fmt.Errorf("unable to sync pods cache")
+	}
+
+	return nil
+}
+
+// GetPod returns pod from cache by namespace and name, if any
+func (p *ProviderK8SPods) GetPod(namespace, name string) *Pod {
+	pod, err := p.lister.Pods(namespace).Get(name)
+
+	if err != nil {
+		p.logger.Debugf("Unable to get pod '%s.%s' from cache: %s", namespace, name, err)
+		return nil
+	}
+
+	return CreatePod(pod)
+}
+
+// Extends returns pod and node metadata to extend pod entries with, if enrichment is enabled
+func (p *ProviderK8SPods) Extends(namespace, name string) common.EntryMap {
+	if !p.enrichment {
+		return nil
+	}
+
+	extends := make(common.EntryMap)
+
+	if pod := p.GetPod(namespace, name); pod != nil {
+		extends.Extend(pod.Extends(p.enrichmentLabels, p.enrichmentAnnotations))
+	}
+
+	if p.nodeLister == nil {
+		return extends
+	}
+
+	node, err := p.nodeLister.Get(p.nodeName)
+
+	if err != nil {
+		p.logger.Debugf("Unable to get node '%s' from cache: %s", p.nodeName, err)
+		return extends
+	}
+
+	setAllowed(extends, common.KubernetesNodeLabelsPrefix, node.GetLabels(), p.enrichmentNodeLabels)
+	return extends
+}
+
+func splitList(list string) []string {
+	result := make([]string, 0)
+
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			result = append(result, item)
+		}
+	}
+
+	return result
+}
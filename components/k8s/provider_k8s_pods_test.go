@@ -0,0 +1,82 @@
+package k8s
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	core "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/2gis/loggo/common"
+	"github.com/2gis/loggo/configuration"
+	"github.com/2gis/loggo/logging"
+)
+
+func TestProviderK8SPods_GetPod(t *testing.T) {
+	client := fake.NewSimpleClientset(&core.Pod{
+		ObjectMeta: v1.ObjectMeta{
+			Name:        "app-0",
+			Namespace:   "io",
+			Annotations: map[string]string{configuration.AnnotationParserDefault: "logfmt"},
+		},
+		Spec: core.PodSpec{NodeName: "node-0"},
+	})
+	ctx, stop := context.WithCancel(context.Background())
+	defer stop()
+
+	provider := NewProviderK8SPods(client, defaultPodsConfig(), "node-0", logging.NewLoggerDefault())
+	assert.NoError(t, provider.Start(ctx))
+
+	pod := provider.GetPod("io", "app-0")
+	assert.Equal(t, &Pod{
+		Name:        "app-0",
+		Namespace:   "io",
+		Annotations: map[string]string{configuration.AnnotationParserDefault: "logfmt"},
+	}, pod)
+	assert.Nil(t, provider.GetPod("io", "app-1"))
+}
+
+func TestProviderK8SPods_Extends(t *testing.T) {
+	client := fake.NewSimpleClientset(
+		&core.Pod{
+			ObjectMeta: v1.ObjectMeta{
+				Name:      "app-0",
+				Namespace: "io",
+				Labels:    map[string]string{"app": "app", "secret": "value"},
+			},
+			Spec:   core.PodSpec{NodeName: "node-0"},
+			Status: core.PodStatus{PodIP: "10.0.0.1"},
+		},
+		&core.Node{
+			ObjectMeta: v1.ObjectMeta{
+				Name:   "node-0",
+				Labels: map[string]string{"topology.kubernetes.io/zone": "zone-a", "other": "value"},
+			},
+		},
+	)
+	ctx, stop := context.WithCancel(context.Background())
+	defer stop()
+
+	config := defaultPodsConfig()
+	config.EnrichmentEnabled = true
+	config.EnrichmentLabels = "app, version"
+	config.EnrichmentNodeLabels = "topology.kubernetes.io/zone"
+
+	provider := NewProviderK8SPods(client, config, "node-0", logging.NewLoggerDefault())
+	assert.NoError(t, provider.Start(ctx))
+
+	assert.Equal(t, common.EntryMap{
+		common.KubernetesPodIP:                                            "10.0.0.1",
+		common.KubernetesLabelsPrefix + "app":                             "app",
+		common.KubernetesNodeLabelsPrefix + "topology.kubernetes.io/zone": "zone-a",
+	}, provider.Extends("io", "app-0"))
+	assert.Equal(t, common.EntryMap{
+		common.KubernetesNodeLabelsPrefix + "topology.kubernetes.io/zone": "zone-a",
+	}, provider.Extends("io", "app-1"))
+
+	provider = NewProviderK8SPods(client, defaultPodsConfig(), "node-0", logging.NewLoggerDefault())
+	assert.NoError(t, provider.Start(ctx))
+	assert.Nil(t, provider.Extends("io", "app-0"))
+}
@@ -1,5 +1,11 @@
 package k8s
 
+import (
+	"context"
+
+	"github.com/2gis/loggo/common"
+)
+
 // ProviderStub returns empty provider for test purposes and dry-run launches
 type ProviderStub struct{}
 
@@ -17,3 +23,18 @@ func (provider *ProviderStub) Retrieve() error {
 func (provider *ProviderStub) GetServiceByHost(host string) *Service {
 	return nil
 }
+
+// Start does nothing in stub
+func (provider *ProviderStub) Start(_ context.Context) error {
+	return nil
+}
+
+// GetPod does nothing in stub
+func (provider *ProviderStub) GetPod(_, _ string) *Pod {
+	return nil
+}
+
+// Extends does nothing in stub
+func (provider *ProviderStub) Extends(_, _ string) common.EntryMap {
+	return nil
+}
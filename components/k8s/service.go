@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"regexp"
+	"strconv"
 	"strings"
 
 	"github.com/2gis/loggo/configuration"
@@ -15,6 +16,9 @@ type Service struct {
 	Enabled bool
 	Paths   []PathSet
 	Domains []string
+	SLO     *SLO
+	// Buckets override default buckets of SLI histograms if not empty
+	Buckets []float64
 }
 
 // PathSet is shorthand structure that maps group of regular expressions with label
@@ -93,10 +97,29 @@ func CreateService(config configuration.SLIExporterConfig, annotations map[strin
 		return nil, err
 	}
 
+	slo, err := createSLO(config, annotations)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var buckets []float64
+
+	if value, ok := annotations[config.AnnotationSLABuckets]; ok {
+		buckets, err = createBuckets(value)
+
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse annotation '%s'='%s', with error '%s'",
+				config.AnnotationSLABuckets, value, err)
+		}
+	}
+
 	return &Service{
 		Enabled: true,
 		Domains: domainsSplitted,
 		Paths:   paths,
+		SLO:     slo,
+		Buckets: buckets,
 	}, nil
 }
 
@@ -156,6 +179,35 @@ func createPaths(pathAnnotation, paths string) ([]PathSet, error) {
 	return httpPaths, nil
 }
 
+// createBuckets parses space or comma separated increasing bucket upper borders
+func createBuckets(value string) ([]float64, error) {
+	fields := strings.FieldsFunc(value, func(r rune) bool {
+		return r == ',' || r == ' '
+	})
+
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("at least one bucket is required")
+	}
+
+	buckets := make([]float64, 0, len(fields))
+
+	for _, field := range fields {
+		bucket, err := strconv.ParseFloat(field, 64)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if len(buckets) > 0 && bucket <= buckets[len(buckets)-1] {
+			return nil, fmt.Errorf("buckets must be in increasing order")
+		}
+
+		buckets = append(buckets, bucket)
+	}
+
+	return buckets, nil
+}
+
 func splitDomains(domains string) []string {
 	if domains == "" {
 		return nil
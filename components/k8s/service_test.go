@@ -130,3 +130,41 @@ func TestCreateService(t *testing.T) {
 	assert.Equal(t, "abc", output.Paths[0].Regexps[1].String())
 	assert.Equal(t, "def", output.Paths[1].Regexps[0].String())
 }
+
+func TestCreateBuckets(t *testing.T) {
+	buckets, err := createBuckets("0.05 0.1, 0.5,1")
+	assert.NoError(t, err)
+	assert.Equal(t, []float64{0.05, 0.1, 0.5, 1}, buckets)
+
+	for _, value := range []string{"", " , ", "0.1 abc", "0.5 0.1", "0.1 0.1"} {
+		_, err = createBuckets(value)
+		assert.Error(t, err, value)
+	}
+}
+
+func TestCreateServiceBuckets(t *testing.T) {
+	config := c.SLIExporterConfig{
+		AnnotationExporterEnable: c.AnnotationExporterEnableDefault,
+		AnnotationExporterPaths:  c.AnnotationExporterPathsDefault,
+		AnnotationSLADomains:     c.AnnotationSLADomainsDefault,
+		AnnotationSLABuckets:     c.AnnotationSLABucketsDefault,
+	}
+	input := map[string]string{
+		c.AnnotationExporterEnableDefault: "true",
+		c.AnnotationExporterPathsDefault:  "[{\"label1\":[\".*\"]}]",
+		c.AnnotationSLADomainsDefault:     "abc",
+	}
+
+	output, err := CreateService(config, input)
+	assert.NoError(t, err)
+	assert.Nil(t, output.Buckets)
+
+	input[c.AnnotationSLABucketsDefault] = "0.1 1"
+	output, err = CreateService(config, input)
+	assert.NoError(t, err)
+	assert.Equal(t, []float64{0.1, 1}, output.Buckets)
+
+	input[c.AnnotationSLABucketsDefault] = "1 0.1"
+	_, err = CreateService(config, input)
+	assert.Error(t, err)
+}
@@ -0,0 +1,127 @@
+package k8s
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/common/model"
+
+	"github.com/2gis/loggo/configuration"
+)
+
+// SLO is a service level objective declared by service annotations
+type SLO struct {
+	// Objective is a target fraction of good requests, 0.999 for 99.9%
+	Objective float64
+	// LatencyThreshold is request time in seconds above which request is bad; zero means latency isn't considered
+	LatencyThreshold float64
+	Windows          []time.Duration
+}
+
+// Good checks if request with the status and request time in seconds is good in terms of the objective
+func (slo *SLO) Good(status int, requestTime float64) bool {
+	if status >= 500 {
+		return false
+	}
+
+	return slo.LatencyThreshold == 0 || requestTime <= slo.LatencyThreshold
+}
+
+// createSLO constructs SLO from annotations; nil is returned when there's no objective annotation
+func createSLO(config configuration.SLIExporterConfig, annotations map[string]string) (*SLO, error) {
+	objective, ok := annotations[config.AnnotationSLOObjective]
+
+	if !ok {
+		return nil, nil
+	}
+
+	percent, err := strconv.ParseFloat(strings.TrimSpace(objective), 64)
+
+	if err != nil || percent <= 0 || percent >= 100 {
+		return nil, fmt.Errorf("annotation '%s'='%s' must be a percent between 0 and 100 exclusive",
+			config.AnnotationSLOObjective, objective)
+	}
+
+	slo := &SLO{Objective: percent / 100}
+
+	if threshold, ok := annotations[config.AnnotationSLOLatencyThreshold]; ok {
+		slo.LatencyThreshold, err = parseLatencyThreshold(threshold)
+
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse annotation '%s'='%s', with error '%s'",
+				config.AnnotationSLOLatencyThreshold, threshold, err)
+		}
+	}
+
+	windows, ok := annotations[config.AnnotationSLOWindows]
+
+	if !ok {
+		windows = config.SLOWindows
+	}
+
+	slo.Windows, err = ParseWindows(windows)
+
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse windows '%s', with error '%s'", windows, err)
+	}
+
+	return slo, nil
+}
+
+// parseLatencyThreshold accepts durations like 300ms or plain seconds
+func parseLatencyThreshold(threshold string) (float64, error) {
+	threshold = strings.TrimSpace(threshold)
+
+	if seconds, err := strconv.ParseFloat(threshold, 64); err == nil {
+		if seconds <= 0 {
+			return 0, fmt.Errorf("threshold must be positive")
+		}
+
+		return seconds, nil
+	}
+
+	duration, err := time.ParseDuration(threshold)
+
+	if err != nil {
+		return 0, err
+	}
+
+	if duration <= 0 {
+		return 0, fmt.Errorf("threshold must be positive")
+	}
+
+	return duration.Seconds(), nil
+}
+
+// ParseWindows parses comma-separated list of prometheus-style durations, e.g. "5m,1h,3d"
+func ParseWindows(windows string) ([]time.Duration, error) {
+	result := make([]time.Duration, 0)
+
+	for _, window := range strings.Split(windows, ",") {
+		window = strings.TrimSpace(window)
+
+		if window == "" {
+			continue
+		}
+
+		duration, err := model.ParseDuration(window)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if duration <= 0 {
+			return nil, fmt.Errorf("window '%s' must be positive", window)
+		}
+
+		result = append(result, time.Duration(duration))
+	}
+
+	if len(result) == 0 {
+		return nil, fmt.Errorf("at least one window is required")
+	}
+
+	return result, nil
+}
@@ -0,0 +1,68 @@
+package k8s
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	c "github.com/2gis/loggo/configuration"
+)
+
+func TestCreateSLO(t *testing.T) {
+	config := c.SLIExporterConfig{
+		SLOWindows:                    "5m,1h",
+		AnnotationSLOObjective:        c.AnnotationSLOObjectiveDefault,
+		AnnotationSLOLatencyThreshold: c.AnnotationSLOLatencyThresholdDefault,
+		AnnotationSLOWindows:          c.AnnotationSLOWindowsDefault,
+	}
+
+	slo, err := createSLO(config, map[string]string{})
+	assert.NoError(t, err)
+	assert.Nil(t, slo)
+
+	slo, err = createSLO(config, map[string]string{c.AnnotationSLOObjectiveDefault: "99.9"})
+	assert.NoError(t, err)
+	assert.InDelta(t, 0.999, slo.Objective, 1e-9)
+	assert.Equal(t, 0.0, slo.LatencyThreshold)
+	assert.Equal(t, []time.Duration{5 * time.Minute, time.Hour}, slo.Windows)
+
+	slo, err = createSLO(config, map[string]string{
+		c.AnnotationSLOObjectiveDefault:        "99",
+		c.AnnotationSLOLatencyThresholdDefault: "300ms",
+		c.AnnotationSLOWindowsDefault:          "1h, 3d",
+	})
+	assert.NoError(t, err)
+	assert.InDelta(t, 0.3, slo.LatencyThreshold, 1e-9)
+	assert.Equal(t, []time.Duration{time.Hour, 72 * time.Hour}, slo.Windows)
+
+	slo, err = createSLO(config, map[string]string{
+		c.AnnotationSLOObjectiveDefault:        "99",
+		c.AnnotationSLOLatencyThresholdDefault: "0.5",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 0.5, slo.LatencyThreshold)
+
+	for _, annotations := range []map[string]string{
+		{c.AnnotationSLOObjectiveDefault: "100"},
+		{c.AnnotationSLOObjectiveDefault: "abc"},
+		{c.AnnotationSLOObjectiveDefault: "99", c.AnnotationSLOLatencyThresholdDefault: "-1"},
+		{c.AnnotationSLOObjectiveDefault: "99", c.AnnotationSLOLatencyThresholdDefault: "fast"},
+		{c.AnnotationSLOObjectiveDefault: "99", c.AnnotationSLOWindowsDefault: "1week"},
+		{c.AnnotationSLOObjectiveDefault: "99", c.AnnotationSLOWindowsDefault: ","},
+	} {
+		_, err = createSLO(config, annotations)
+		assert.Error(t, err, annotations)
+	}
+}
+
+func TestSLOGood(t *testing.T) {
+	slo := SLO{Objective: 0.99}
+	assert.True(t, slo.Good(200, 10))
+	assert.True(t, slo.Good(404, 10))
+	assert.False(t, slo.Good(503, 0.1))
+
+	slo.LatencyThreshold = 0.3
+	assert.True(t, slo.Good(200, 0.3))
+	assert.False(t, slo.Good(200, 0.31))
+}
@@ -5,4 +5,6 @@ type RateRecord struct {
 	Namespace string
 	Pod       string
 	Rate      float64
+	// Sampling is a map of severity to the ratio of entries to keep, entries of unlisted severities are kept
+	Sampling map[string]float64
 }
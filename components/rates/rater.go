@@ -99,23 +99,45 @@ func (rater *Rater) Rate(namespace string, pod string) float64 {
 	rater.RLock()
 	defer rater.RUnlock()
 
+	// sampling rules may leave the rate default
+	if rule := rater.match(namespace, pod, func(rule *Rule) bool { return rule.Rate > 0 }); rule != nil {
+		return rule.Rate
+	}
+
+	return rater.rateDefault
+}
+
+// Sampling tries to find a rule that matches namespace and pod and returns corresponding severity sampling ratios
+func (rater *Rater) Sampling(namespace string, pod string) map[string]float64 {
+	rater.RLock()
+	defer rater.RUnlock()
+
+	if rule := rater.match(namespace, pod, func(rule *Rule) bool { return len(rule.Sampling) > 0 }); rule != nil {
+		return rule.Sampling
+	}
+
+	return nil
+}
+
+// match returns the first rule matching namespace and pod among the rules that apply
+func (rater *Rater) match(namespace string, pod string, applies func(rule *Rule) bool) *Rule {
 	for _, rule := range rater.NamespacedPodRules {
-		if rule.Match(namespace, pod) {
-			return rule.Rate
+		if applies(&rule.Rule) && rule.Match(namespace, pod) {
+			return &rule.Rule
 		}
 	}
 
 	for _, rule := range rater.PodRules {
-		if rule.Match(pod) {
-			return rule.Rate
+		if applies(&rule.Rule) && rule.Match(pod) {
+			return &rule.Rule
 		}
 	}
 
 	for _, rule := range rater.NamespaceRules {
-		if rule.Match(namespace) {
-			return rule.Rate
+		if applies(&rule.Rule) && rule.Match(namespace) {
+			return &rule.Rule
 		}
 	}
 
-	return rater.rateDefault
+	return nil
 }
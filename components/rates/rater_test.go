@@ -62,3 +62,39 @@ func TestRater_RetrieveRates(t *testing.T) {
 	assert.Equal(t, rate, rater.Rate("namespace", "pod"))
 	assert.Equal(t, rateDefault, rater.Rate("0", "pod"))
 }
+
+func TestRater_Sampling(t *testing.T) {
+	sampling := map[string]float64{"debug": 0.1}
+	provider := newRatesProviderMock(
+		[]RateRecord{
+			{Namespace: "prod", Pod: "api.*", Rate: 10},
+			{Namespace: "prod", Sampling: sampling},
+		},
+	)
+	rater, err := NewRater(provider, 400)
+	assert.NoError(t, err)
+	assert.NoError(t, rater.Retrieve())
+
+	assert.Equal(t, 10.0, rater.Rate("prod", "api-0"))
+	// rules without sampling don't shadow sampling rules
+	assert.Equal(t, sampling, rater.Sampling("prod", "api-0"))
+	assert.Equal(t, 400.0, rater.Rate("prod", "web-0"))
+	assert.Equal(t, sampling, rater.Sampling("prod", "web-0"))
+	assert.Nil(t, rater.Sampling("stage", "web-0"))
+}
+
+func TestRater_SamplingRuleRate(t *testing.T) {
+	provider := newRatesProviderMock(
+		[]RateRecord{
+			{Namespace: "prod", Pod: "api.*", Sampling: map[string]float64{"debug": 0.1}},
+			{Namespace: "prod", Rate: 10},
+		},
+	)
+	rater, err := NewRater(provider, 400)
+	assert.NoError(t, err)
+	assert.NoError(t, rater.Retrieve())
+
+	// sampling only rule doesn't shadow the rate of the later rule
+	assert.Equal(t, 10.0, rater.Rate("prod", "api-0"))
+	assert.Equal(t, map[string]float64{"debug": 0.1}, rater.Sampling("prod", "api-0"))
+}
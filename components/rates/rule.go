@@ -2,15 +2,26 @@ package rates
 
 import (
 	"errors"
+	"fmt"
 	"regexp"
+	"strings"
+
+	"github.com/2gis/loggo/common"
 )
 
 // ErrRateInvalid is the error that signals about invalid rate value
 var ErrRateInvalid = errors.New("rate must be greater that zero")
 
-// Rule is the base class of rule
+// ErrSamplingInvalid is the error that signals about invalid sampling ratio value
+var ErrSamplingInvalid = errors.New("sampling ratio must be between zero and one")
+
+// ErrSamplingSeverity is the error that signals about sampling of unknown severity
+var ErrSamplingSeverity = errors.New("sampling severity must be one of canonical severities")
+
+// Rule is the base class of rule; zero rate means default rate, it's allowed only for sampling rules
 type Rule struct {
-	Rate float64
+	Rate     float64
+	Sampling map[string]float64
 }
 
 // NewRule is the constructor for Rule
@@ -22,6 +33,65 @@ func NewRule(rate float64) (*Rule, error) {
 	return &Rule{Rate: rate}, nil
 }
 
+func newRuleFromRecord(record RateRecord) (*Rule, error) {
+	sampling, err := newSampling(record.Sampling)
+	if err != nil {
+		return nil, err
+	}
+
+	if record.Rate == 0 && len(sampling) > 0 {
+		return &Rule{Sampling: sampling}, nil
+	}
+
+	rule, err := NewRule(record.Rate)
+	if err != nil {
+		return nil, err
+	}
+
+	rule.Sampling = sampling
+	return rule, nil
+}
+
+// newSampling validates sampling ratios, severities are lower-cased to match normalized ones
+func newSampling(ratios map[string]float64) (map[string]float64, error) {
+	if ratios == nil {
+		return nil, nil
+	}
+
+	sampling := make(map[string]float64, len(ratios))
+
+	for severity, ratio := range ratios {
+		if ratio < 0 || ratio > 1 {
+			return nil, fmt.Errorf("%w: severity '%s', ratio %v", ErrSamplingInvalid, severity, ratio)
+		}
+
+		key := strings.ToLower(severity)
+
+		if !isCanonicalSeverity(key) {
+			return nil, fmt.Errorf("%w: severity '%s'", ErrSamplingSeverity, severity)
+		}
+
+		if _, ok := sampling[key]; ok {
+			return nil, fmt.Errorf("%w: severity '%s' is duplicated", ErrSamplingSeverity, severity)
+		}
+
+		sampling[key] = ratio
+	}
+
+	return sampling, nil
+}
+
+// isCanonicalSeverity checks whether severity is produced by severity normalization
+func isCanonicalSeverity(severity string) bool {
+	for _, canonical := range common.Severities {
+		if severity == canonical {
+			return true
+		}
+	}
+
+	return false
+}
+
 // NamespaceRule is the one of the rule types
 type NamespaceRule struct {
 	Rule
@@ -30,7 +100,7 @@ type NamespaceRule struct {
 
 // NewNamespaceRule is the constructor for NamespaceRule
 func NewNamespaceRule(record RateRecord) (*NamespaceRule, error) {
-	ruleBase, err := NewRule(record.Rate)
+	ruleBase, err := newRuleFromRecord(record)
 	if err != nil {
 		return nil, err
 	}
@@ -62,7 +132,7 @@ type PodRule struct {
 
 // NewPodRule is the constructor for PodRule
 func NewPodRule(record RateRecord) (*PodRule, error) {
-	ruleBase, err := NewRule(record.Rate)
+	ruleBase, err := newRuleFromRecord(record)
 	if err != nil {
 		return nil, err
 	}
@@ -94,7 +164,7 @@ type NamespacedPodRule struct {
 
 // NewNamespacedPodRule is the constructor for NamespacedPodRule
 func NewNamespacedPodRule(record RateRecord) (*NamespacedPodRule, error) {
-	ruleBase, err := NewRule(record.Rate)
+	ruleBase, err := newRuleFromRecord(record)
 	if err != nil {
 		return nil, err
 	}
@@ -74,3 +74,29 @@ func TestNamespacedPodRule(t *testing.T) {
 	_, err = NewNamespacedPodRule(recordWrongRegexNamespace)
 	assert.Error(t, err)
 }
+
+func TestRuleSampling(t *testing.T) {
+	record := RateRecord{Namespace: `\w\d`, Sampling: map[string]float64{"debug": 0.1}}
+
+	rule, err := NewNamespaceRule(record)
+	assert.NoError(t, err)
+	assert.Equal(t, 0.0, rule.Rate)
+	assert.Equal(t, map[string]float64{"debug": 0.1}, rule.Sampling)
+
+	record.Sampling["info"] = 1.5
+	_, err = NewNamespaceRule(record)
+	assert.ErrorIs(t, err, ErrSamplingInvalid)
+
+	rule, err = NewNamespaceRule(RateRecord{Namespace: `\w\d`, Sampling: map[string]float64{"DEBUG": 0.1}})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]float64{"debug": 0.1}, rule.Sampling)
+
+	_, err = NewNamespaceRule(RateRecord{Namespace: `\w\d`, Sampling: map[string]float64{"warning": 0.1}})
+	assert.ErrorIs(t, err, ErrSamplingSeverity)
+
+	_, err = NewNamespaceRule(RateRecord{Namespace: `\w\d`, Sampling: map[string]float64{"Info": 0.1, "info": 0.2}})
+	assert.ErrorIs(t, err, ErrSamplingSeverity)
+
+	_, err = NewNamespaceRule(RateRecord{Namespace: `\w\d`, Sampling: map[string]float64{}})
+	assert.ErrorIs(t, err, ErrRateInvalid)
+}
@@ -0,0 +1,266 @@
+package watcher
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/fsnotify/fsnotify"
+
+	"github.com/2gis/loggo/logging"
+)
+
+// opsTree are operations changing directory tree contents, writes to files don't wake tree subscribers
+const opsTree = fsnotify.Create | fsnotify.Remove | fsnotify.Rename
+
+// Subscription receives a wakeup when the watched file or directory tree changes; wakeups are coalesced, so
+// subscriber must check the state itself after waking up. Nil subscription is valid and never wakes up
+type Subscription struct {
+	c       chan struct{}
+	watcher *Watcher
+	path    string
+	tree    bool
+	dirs    map[string]bool
+}
+
+// C returns wakeups channel; nil subscription returns nil channel, which blocks forever in select
+func (s *Subscription) C() <-chan struct{} {
+	if s == nil {
+		return nil
+	}
+
+	return s.c
+}
+
+// Close cancels subscription and stops watching directories no one else is interested in
+func (s *Subscription) Close() {
+	if s == nil {
+		return
+	}
+
+	s.watcher.unsubscribe(s)
+}
+
+func (s *Subscription) notify() {
+	select {
+	case s.c <- struct{}{}:
+	default:
+	}
+}
+
+// Watcher shares a single inotify instance among subscriptions; files are watched via their parent directories, so
+// appends, truncations, renames and creations of the file (rotation) wake the subscriber up. Directories are
+// watched while any subscription needs them
+type Watcher struct {
+	mu       sync.Mutex
+	watcher  *fsnotify.Watcher
+	dirs     map[string]map[*Subscription]bool
+	files    map[string]map[*Subscription]bool
+	logger   logging.Logger
+	finished bool
+}
+
+// NewWatcher is a Watcher constructor; error means inotify is unavailable and callers should fall back to polling
+func NewWatcher(logger logging.Logger) (*Watcher, error) {
+	watcher, err := fsnotify.NewWatcher()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &Watcher{
+		watcher: watcher,
+		dirs:    make(map[string]map[*Subscription]bool),
+		files:   make(map[string]map[*Subscription]bool),
+		logger:  logger,
+	}, nil
+}
+
+// Start dispatches filesystem events to subscribers until context is done
+func (w *Watcher) Start(ctx context.Context) {
+	defer w.finalize()
+
+	for {
+		select {
+		case event, ok := <-w.watcher.Events:
+			if !ok {
+				return
+			}
+
+			w.handle(event)
+		case err, ok := <-w.watcher.Errors:
+			if !ok {
+				return
+			}
+
+			// events may be lost on queue overflow, subscribers rely on polling then
+			w.logger.Warnf("watcher: %s", err)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// WatchFile subscribes to changes of the file; file itself may be absent, but its directory must exist
+func (w *Watcher) WatchFile(path string) (*Subscription, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	s := w.newSubscription(path, false)
+
+	if err := w.addDir(s, filepath.Dir(path)); err != nil {
+		return nil, err
+	}
+
+	if w.files[path] == nil {
+		w.files[path] = make(map[*Subscription]bool)
+	}
+
+	w.files[path][s] = true
+	return s, nil
+}
+
+// WatchTree subscribes to files and directories creations, removals and renames in the root directory and its
+// subdirectories; directories created later are watched as well
+func (w *Watcher) WatchTree(root string) (*Subscription, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	s := w.newSubscription(root, true)
+
+	if err := w.addDir(s, root); err != nil {
+		return nil, err
+	}
+
+	w.addSubdirs(s, root)
+	return s, nil
+}
+
+func (w *Watcher) newSubscription(path string, tree bool) *Subscription {
+	return &Subscription{
+		c:       make(chan struct{}, 1),
+		watcher: w,
+		path:    path,
+		tree:    tree,
+		dirs:    make(map[string]bool),
+	}
+}
+
+func (w *Watcher) handle(event fsnotify.Event) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for s := range w.files[event.Name] {
+		s.notify()
+	}
+
+	if event.Op&opsTree == 0 {
+		return
+	}
+
+	for s := range w.dirs[filepath.Dir(event.Name)] {
+		if !s.tree {
+			continue
+		}
+
+		if event.Op&fsnotify.Create != 0 {
+			if info, err := os.Lstat(event.Name); err == nil && info.IsDir() {
+				if err := w.addDir(s, event.Name); err != nil {
+					w.logger.Warnf("watcher: unable to watch '%s', %s", event.Name, err)
+				}
+
+				// contents may have been created before the directory is watched
+				w.addSubdirs(s, event.Name)
+			}
+		}
+
+		s.notify()
+	}
+
+	// watched directory has gone, it must be watched again if it's created once more
+	if event.Op&(fsnotify.Remove|fsnotify.Rename) != 0 {
+		if subscriptions, ok := w.dirs[event.Name]; ok {
+			for s := range subscriptions {
+				delete(s.dirs, event.Name)
+				s.notify()
+			}
+
+			delete(w.dirs, event.Name)
+			_ = w.watcher.Remove(event.Name)
+		}
+	}
+}
+
+// addSubdirs watches subdirectories of the directory for tree subscription; failures are logged only
+func (w *Watcher) addSubdirs(s *Subscription, dir string) {
+	_ = filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || !info.IsDir() || path == dir {
+			return nil
+		}
+
+		if err := w.addDir(s, path); err != nil {
+			w.logger.Warnf("watcher: unable to watch '%s', %s", path, err)
+		}
+
+		return nil
+	})
+}
+
+func (w *Watcher) addDir(s *Subscription, dir string) error {
+	if s.dirs[dir] {
+		return nil
+	}
+
+	if len(w.dirs[dir]) == 0 {
+		if err := w.watcher.Add(dir); err != nil {
+			return err
+		}
+
+		w.dirs[dir] = make(map[*Subscription]bool)
+	}
+
+	w.dirs[dir][s] = true
+	s.dirs[dir] = true
+	return nil
+}
+
+func (w *Watcher) unsubscribe(s *Subscription) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if !s.tree {
+		delete(w.files[s.path], s)
+
+		if len(w.files[s.path]) == 0 {
+			delete(w.files, s.path)
+		}
+	}
+
+	for dir := range s.dirs {
+		delete(w.dirs[dir], s)
+
+		if len(w.dirs[dir]) > 0 {
+			continue
+		}
+
+		delete(w.dirs, dir)
+
+		if !w.finished {
+			_ = w.watcher.Remove(dir)
+		}
+	}
+
+	s.dirs = make(map[string]bool)
+}
+
+func (w *Watcher) finalize() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.finished = true
+
+	if err := w.watcher.Close(); err != nil {
+		w.logger.Warnf("watcher: unable to close, %s", err)
+	}
+}
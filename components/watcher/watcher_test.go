@@ -0,0 +1,158 @@
+package watcher
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/2gis/loggo/logging"
+)
+
+const wakeupTimeout = 2 * time.Second
+
+func newTestWatcher(t *testing.T) *Watcher {
+	watcher, err := NewWatcher(logging.NewLoggerDefault())
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+		watcher.Start(ctx)
+	}()
+
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+	return watcher
+}
+
+func assertWakeup(t *testing.T, s *Subscription) {
+	select {
+	case <-s.C():
+	case <-time.After(wakeupTimeout):
+		assert.Fail(t, "subscriber hasn't been woken up")
+	}
+}
+
+// assertNoWakeup drains pending wakeups first, since events of the preceding actions may still be in flight
+func assertNoWakeup(t *testing.T, s *Subscription, action func()) {
+	time.Sleep(100 * time.Millisecond)
+
+	select {
+	case <-s.C():
+	default:
+	}
+
+	action()
+
+	select {
+	case <-s.C():
+		assert.Fail(t, "subscriber has been woken up")
+	case <-time.After(200 * time.Millisecond):
+	}
+}
+
+func appendLine(t *testing.T, path string) {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	assert.NoError(t, err)
+	_, err = file.WriteString("line\n")
+	assert.NoError(t, err)
+	assert.NoError(t, file.Close())
+}
+
+func TestWatcherWatchFile(t *testing.T) {
+	watcher := newTestWatcher(t)
+	dir := t.TempDir()
+	path := filepath.Join(dir, "0.log")
+	appendLine(t, path)
+
+	s, err := watcher.WatchFile(path)
+	assert.NoError(t, err)
+
+	appendLine(t, path)
+	assertWakeup(t, s)
+
+	// rotation
+	assert.NoError(t, os.Rename(path, path+".1"))
+	assertWakeup(t, s)
+	appendLine(t, path)
+	assertWakeup(t, s)
+
+	assertNoWakeup(t, s, func() { appendLine(t, filepath.Join(dir, "other.log")) })
+
+	s.Close()
+	assert.Empty(t, watcher.dirs)
+	assert.Empty(t, watcher.files)
+
+	_, err = watcher.WatchFile(filepath.Join(dir, "absent", "0.log"))
+	assert.Error(t, err)
+}
+
+func TestWatcherWatchTree(t *testing.T) {
+	watcher := newTestWatcher(t)
+	root := t.TempDir()
+	assert.NoError(t, os.Mkdir(filepath.Join(root, "existing"), 0755))
+	appendLine(t, filepath.Join(root, "existing", "0.log"))
+
+	s, err := watcher.WatchTree(root)
+	assert.NoError(t, err)
+
+	file, err := watcher.WatchFile(filepath.Join(root, "existing", "0.log"))
+	assert.NoError(t, err)
+
+	assertNoWakeup(t, s, func() { appendLine(t, filepath.Join(root, "existing", "0.log")) })
+	assertWakeup(t, file)
+
+	// new pod directory, then container directory and log file in it
+	pod := filepath.Join(root, "ns_pod_uid")
+	assert.NoError(t, os.Mkdir(pod, 0755))
+	assertWakeup(t, s)
+
+	time.Sleep(100 * time.Millisecond)
+	assert.NoError(t, os.Mkdir(filepath.Join(pod, "app"), 0755))
+	assertWakeup(t, s)
+
+	time.Sleep(100 * time.Millisecond)
+	appendLine(t, filepath.Join(pod, "app", "0.log"))
+	assertWakeup(t, s)
+
+	assert.NoError(t, os.RemoveAll(pod))
+	assertWakeup(t, s)
+
+	s.Close()
+	file.Close()
+	assert.Empty(t, watcher.dirs)
+}
+
+func TestSubscriptionNil(t *testing.T) {
+	var s *Subscription
+
+	assert.Nil(t, s.C())
+	s.Close()
+}
+
+func TestWatcherFinished(t *testing.T) {
+	watcher, err := NewWatcher(logging.NewLoggerDefault())
+	assert.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "0.log")
+	assert.NoError(t, ioutil.WriteFile(path, []byte{}, 0644))
+	s, err := watcher.WatchFile(path)
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	watcher.Start(ctx)
+
+	s.Close()
+	_, err = watcher.WatchFile(path)
+	assert.Error(t, err)
+}
@@ -37,6 +37,10 @@ type FollowerConfig struct {
 	NoRecordsSleepIntervalSec         int
 	ThrottlingLimitsUpdateIntervalSec int
 	FromTailFlag                      bool
+	ReadRotatedFlag                   bool
+	FSNotify                          bool
+	FSNotifyFallbackIntervalSec       int
+	MultilineFlushTimeoutMs           int
 }
 
 type ParserConfig struct {
@@ -64,9 +68,73 @@ type SLIExporterConfig struct {
 
 	ServiceUpdateIntervalSec int
 
+	Preset        string
+	FieldsMapping string
+
+	SeriesLimitPerService int
+	SeriesLimit           int
+
+	NativeHistograms bool
+
 	AnnotationExporterEnable string
 	AnnotationExporterPaths  string
 	AnnotationSLADomains     string
+	AnnotationSLABuckets     string
+
+	SLOWindows                    string
+	AnnotationSLOObjective        string
+	AnnotationSLOLatencyThreshold string
+	AnnotationSLOWindows          string
+}
+
+type PodsConfig struct {
+	Enabled bool
+
+	ResyncIntervalSec int
+
+	AnnotationParser           string
+	AnnotationParserRegexp     string
+	AnnotationMultilinePattern string
+	AnnotationExclude          string
+
+	EnrichmentEnabled bool
+
+	EnrichmentLabels      string
+	EnrichmentAnnotations string
+	EnrichmentNodeLabels  string
+}
+
+type EventsConfig struct {
+	Enabled bool
+
+	LeaseNamespace string
+	LeaseName      string
+	LeaseDuration  time.Duration
+
+	ResyncIntervalSec int
+}
+
+type TimestampConfig struct {
+	Enabled bool
+
+	Fields          string
+	Formats         string
+	OutputField     string
+	OutputFormat    string
+	CollectionField string
+}
+
+type SeverityConfig struct {
+	Enabled bool
+
+	Fields      string
+	OutputField string
+
+	SamplingEnabled bool
+}
+
+type TraceContextConfig struct {
+	Enabled bool
 }
 
 type RedisTransportConfig struct {
@@ -87,6 +155,15 @@ type FirehoseTransportConfig struct {
 	DeliveryStream string
 }
 
+type OTLPTransportConfig struct {
+	Protocol   string
+	Endpoint   string
+	Insecure   bool
+	Headers    string
+	Timeout    time.Duration
+	BodyFields string
+}
+
 // Config stores all configuration from environment or launch keys
 type Config struct {
 	K8SExtends              K8SExtends
@@ -94,23 +171,46 @@ type Config struct {
 	FollowerConfig          FollowerConfig
 	JournaldConfig          JournaldConfig
 	SLIExporterConfig       SLIExporterConfig
+	PodsConfig              PodsConfig
+	EventsConfig            EventsConfig
+	TimestampConfig         TimestampConfig
+	SeverityConfig          SeverityConfig
+	TraceContextConfig      TraceContextConfig
 	FirehostTransportConfig FirehoseTransportConfig
 	AMQPTransportConfig     AMQPTransportConfig
 	RedisTransportConfig    RedisTransportConfig
+	OTLPTransportConfig     OTLPTransportConfig
 
 	TransportBufferSizeMax int
 	Transport              string
 
 	LogsPath                 string
+	ContainersProvider       string
+	CRIOContainersPath       string
+	CRIORunrootPath          string
+	CRIRuntimeEndpoint       string
+	CRIRuntimeTimeoutSec     int
 	PositionFilePath         string
 	ContainersIgnoreFilePath string
+	ContainersSelectorsPath  string
+	FileInputsPath           string
 
 	ReadRateRulesPath string
-	ReadRateDefault   float64
 
-	TargetsRefreshIntervalSec int
-	FlushIntervalSec          int
-	MetricsResetIntervalSec   int
+	FilterExpression string
+	LogMetricsPath   string
+
+	LogMetricsSeriesLimit int
+
+	RedactionRulesPath   string
+	RedactionHMACKeyPath string
+	ReadRateDefault      float64
+
+	TargetsRefreshIntervalSec     int
+	FlushIntervalSec              int
+	MetricsResetIntervalSec       int
+	MetricsSeriesSweepIntervalSec int
+	MetricsSeriesTTLSec           int
 
 	LogLevel  string
 	LogFormat string
@@ -118,26 +218,68 @@ type Config struct {
 
 // GetConfig generates Config from options and env vars
 func GetConfig() Config {
-	config := Config{
+	config := declareConfig(kingpin.CommandLine)
+	kingpin.Parse()
+	return *config
+}
+
+// declareConfig declares options of the application, config returned is filled once they're parsed
+func declareConfig(app *kingpin.Application) *Config {
+	config := &Config{
 		K8SExtends:     K8SExtends{},
 		FollowerConfig: FollowerConfig{},
 		JournaldConfig: JournaldConfig{},
 	}
 
 	// common
-	kingpin.Flag("position-file-path", "Path to file where loggo stores log file cursors").
+	app.Flag("position-file-path", "Path to file where loggo stores log file cursors").
 		Default("/var/log/loggo-logs.pos").
 		Envar("POSITION_FILE_PATH").
 		StringVar(&config.PositionFilePath)
-	kingpin.Flag("containers-ignore-file-path", "Path to file where loggo stores ignored containers IDs").
+	app.Flag("containers-ignore-file-path", "Path to file where loggo stores stopped containers "+
+		"which logs have been read to the end").
 		Default("/var/log/loggo-containers-ignore").
 		Envar("CONTAINERS_IGNORE_FILE_PATH").
 		StringVar(&config.ContainersIgnoreFilePath)
-	kingpin.Flag("logs-path", "Path where loggo will watch for log files").
+	app.Flag("logs-path", "Path where loggo will watch for log files").
 		Default("/var/log/pods/").
 		Envar("LOGS_PATH").
 		StringVar(&config.LogsPath)
-	kingpin.Flag(
+	app.Flag("containers-provider", "Containers provider [logs | cri]; logs provider walks logs-path, cri one "+
+		"lists containers of CRI runtime at cri-runtime-endpoint").
+		Default("logs").
+		Envar("CONTAINERS_PROVIDER").
+		StringVar(&config.ContainersProvider)
+	app.Flag("crio-containers-path", "Path to CRI-O containers storage, its metadata is used to get CRI-O "+
+		"containers IDs and states; node is assumed to run containerd if the path doesn't exist").
+		Default("/var/lib/containers/storage/overlay-containers/").
+		Envar("CRIO_CONTAINERS_PATH").
+		StringVar(&config.CRIOContainersPath)
+	app.Flag("crio-runroot-path", "Path to CRI-O containers storage run directory, CRI-O containers states are "+
+		"taken from it").
+		Default("/run/containers/storage/overlay-containers/").
+		Envar("CRIO_RUNROOT_PATH").
+		StringVar(&config.CRIORunrootPath)
+	app.Flag("cri-runtime-endpoint", "CRI runtime socket, unix:///run/containerd/containerd.sock for instance; "+
+		"containerd containers IDs and states are taken from runtime if set, they're considered running otherwise").
+		Default("").
+		Envar("CRI_RUNTIME_ENDPOINT").
+		StringVar(&config.CRIRuntimeEndpoint)
+	app.Flag("cri-runtime-timeout-sec", "CRI runtime requests timeout").
+		Default("5").
+		Envar("CRI_RUNTIME_TIMEOUT_SEC").
+		IntVar(&config.CRIRuntimeTimeoutSec)
+	app.Flag("containers-selectors-path", "Path to yaml file with include and exclude selectors of containers "+
+		"to read; loggo containers are excluded if not set").
+		Default("").
+		Envar("CONTAINERS_SELECTORS_PATH").
+		StringVar(&config.ContainersSelectorsPath)
+	app.Flag("file-inputs-path", "Path to yaml file with host log files inputs declared by glob patterns; "+
+		"host files aren't read if not set").
+		Default("").
+		Envar("FILE_INPUTS_PATH").
+		StringVar(&config.FileInputsPath)
+	app.Flag(
 		"targets-refresh-interval-sec",
 		"How often reread logs-path directory searching for new log files").
 		Default("10").
@@ -145,103 +287,149 @@ func GetConfig() Config {
 		IntVar(&config.TargetsRefreshIntervalSec)
 
 	// transport
-	kingpin.Flag("transport", "Transport type for log messages [amqp | redis]").
+	app.Flag("transport", "Transport type for log messages [amqp | redis | firehose | otlp]").
 		Default("amqp").
 		Envar("TRANSPORT").
 		StringVar(&config.Transport)
-	kingpin.Flag("redis-hostname", "Redis host URL to use.").
+	app.Flag("redis-hostname", "Redis host URL to use.").
 		Default("localhost:6379").
 		Envar("REDIS_HOSTNAME").
 		StringVar(&config.RedisTransportConfig.URL)
-	kingpin.Flag("redis-username", "Redis username to use.").
+	app.Flag("redis-username", "Redis username to use.").
 		Envar("REDIS_USERNAME").
 		StringVar(&config.RedisTransportConfig.Username)
-	kingpin.Flag("redis-password", "Redis password to use.").
+	app.Flag("redis-password", "Redis password to use.").
 		Envar("REDIS_PASSWORD").
 		StringVar(&config.RedisTransportConfig.Password)
-	kingpin.Flag("redis-key", "Key of a list in Redis where to send messages.").
+	app.Flag("redis-key", "Key of a list in Redis where to send messages.").
 		Default("k8s-logs").
 		Envar("REDIS_KEY").
 		StringVar(&config.RedisTransportConfig.Key)
-	kingpin.Flag(
+	app.Flag(
 		"redis-max-conn-lifetime",
 		"Close connections older than this duration. If the value is zero, then the pool does not close connections based on age.").
 		Default("0").
 		Envar("REDIS_MAX_CONN_LIFETIME").
 		DurationVar(&config.RedisTransportConfig.MaxConnLifetime)
-	kingpin.Flag("amqp-url", "AMQP host URL to use.").
+	app.Flag("amqp-url", "AMQP host URL to use.").
 		Default("amqp://localhost/").
 		Envar("AMQP_URL").
 		StringVar(&config.AMQPTransportConfig.URL)
-	kingpin.Flag("amqp-exchange", "AMQP Exchange for log message delivery").
+	app.Flag("amqp-exchange", "AMQP Exchange for log message delivery").
 		Default("amq.direct").
 		Envar("AMQP_EXCHANGE").
 		StringVar(&config.AMQPTransportConfig.Exchange)
-	kingpin.Flag("amqp-routing-key", "AMQP routing key for message delivery").
+	app.Flag("amqp-routing-key", "AMQP routing key for message delivery").
 		Default("all-other").
 		Envar("AMQP_ROUTING_KEY").
 		StringVar(&config.AMQPTransportConfig.Key)
-	kingpin.Flag("firehose-delivery-stream", "AWS Firehose delivery stream.").
+	app.Flag("firehose-delivery-stream", "AWS Firehose delivery stream.").
 		Default("default-delivery").
 		Envar("FIREHOSE_DELIVERY_STREAM").
 		StringVar(&config.FirehostTransportConfig.DeliveryStream)
-	kingpin.Flag("flush-interval-sec", "How often to try sending data to transport").
+	app.Flag("otlp-protocol", "OTLP logs export protocol [grpc | http]").
+		Default("grpc").
+		Envar("OTLP_PROTOCOL").
+		StringVar(&config.OTLPTransportConfig.Protocol)
+	app.Flag("otlp-endpoint", "OTLP receiver host:port for grpc or logs url for http").
+		Default("localhost:4317").
+		Envar("OTLP_ENDPOINT").
+		StringVar(&config.OTLPTransportConfig.Endpoint)
+	app.Flag("otlp-insecure", "Whether to use plaintext grpc connection to OTLP receiver").
+		Default("false").
+		Envar("OTLP_INSECURE").
+		BoolVar(&config.OTLPTransportConfig.Insecure)
+	app.Flag("otlp-headers", "Comma-separated key=value headers of OTLP export requests").
+		Default("").
+		Envar("OTLP_HEADERS").
+		StringVar(&config.OTLPTransportConfig.Headers)
+	app.Flag("otlp-timeout", "OTLP export request timeout").
+		Default("10s").
+		Envar("OTLP_TIMEOUT").
+		DurationVar(&config.OTLPTransportConfig.Timeout)
+	app.Flag("otlp-body-fields", "Comma-separated user log fields to use as log record body, the first present one").
+		Default("message,msg,log").
+		Envar("OTLP_BODY_FIELDS").
+		StringVar(&config.OTLPTransportConfig.BodyFields)
+	app.Flag("flush-interval-sec", "How often to try sending data to transport").
 		Default("60").
 		Envar("FLUSH_INTERVAL_SEC").
 		IntVar(&config.FlushIntervalSec)
-	kingpin.Flag("buffer-max-size", "Maximum log messages to buffer before sending to storage").
+	app.Flag("buffer-max-size", "Maximum log messages to buffer before sending to storage").
 		Default("1000").
 		Envar("BUFFER_SIZE_MAX").
 		IntVar(&config.TransportBufferSizeMax)
 
 	// readers
-	kingpin.Flag(
+	app.Flag(
 		"reader-buffer-size",
 		"Size of readers internal buffer, bytes; hence, the maximum log message length").
 		Default("32000").
 		Envar("READER_BUFFER_SIZE").
 		IntVar(&config.FollowerConfig.ReaderBufferSize)
-	kingpin.Flag("throttling-limits-update-interval-sec", "How often to get updates from throttling config").
+	app.Flag("throttling-limits-update-interval-sec", "How often to get updates from throttling config").
 		Default("600").
 		Envar("THROTTLING_LIMITS_UPDATE_INTERVAL_SEC").
 		IntVar(&config.FollowerConfig.ThrottlingLimitsUpdateIntervalSec)
-	kingpin.Flag(
+	app.Flag(
 		"no-records-sleep-sec",
 		"How long to wait before start reading logfile which hadn't logs added recently").
 		Default("4").
 		Envar("NO_RECORDS_SLEEP_SEC").
 		IntVar(&config.FollowerConfig.NoRecordsSleepIntervalSec)
-	kingpin.Flag("cursor-commit-interval-sec", "How often to try sending data to transport").
+	app.Flag(
+		"multiline-flush-timeout-ms",
+		"How long to wait for continuation of multiline or partial record at the end of file before sending it").
+		Default("1000").
+		Envar("MULTILINE_FLUSH_TIMEOUT_MS").
+		IntVar(&config.FollowerConfig.MultilineFlushTimeoutMs)
+	app.Flag("fs-notify", "Whether to wake followers and rescan logs path on filesystem notifications, "+
+		"polling is used for files that can't be watched").
+		Default("true").
+		Envar("FS_NOTIFY").
+		BoolVar(&config.FollowerConfig.FSNotify)
+	app.Flag(
+		"fs-notify-fallback-interval-sec",
+		"How long followers of watched files wait for notifications before reading anyway").
+		Default("60").
+		Envar("FS_NOTIFY_FALLBACK_INTERVAL_SEC").
+		IntVar(&config.FollowerConfig.FSNotifyFallbackIntervalSec)
+	app.Flag("cursor-commit-interval-sec", "How often to try sending data to transport").
 		Default("60").
 		Envar("CURSOR_COMMIT_INTERVAL_SEC").
 		IntVar(&config.FollowerConfig.CursorCommitIntervalSec)
-	kingpin.Flag("from-tail", "Whether to start reading files without valid cursors from tail, default false").
+	app.Flag("from-tail", "Whether to start reading files without valid cursors from tail, default false").
 		Default("false").
 		Envar("FROM_TAIL_FLAG").
 		BoolVar(&config.FollowerConfig.FromTailFlag)
+	app.Flag("read-rotated", "Whether to read files rotated by kubelet (timestamp suffixed and gzipped) "+
+		"before the log file itself, if stored cursor points to unfinished one, default false").
+		Default("false").
+		Envar("READ_ROTATED_FLAG").
+		BoolVar(&config.FollowerConfig.ReadRotatedFlag)
 
 	// system journal reader
-	kingpin.Flag("log-journald", "Whether to log journald or not, default true").
+	app.Flag("log-journald", "Whether to log journald or not, default true").
 		Default("true").
 		Envar("LOG_JOURNALD").
 		BoolVar(&config.JournaldConfig.LogJournalD)
-	kingpin.Flag("machine-id-path", "Path to file with machine identifier").
+	app.Flag("machine-id-path", "Path to file with machine identifier").
 		Default("/etc/machine-id").
 		Envar("MACHINE_ID_PATH").
 		StringVar(&config.JournaldConfig.MachineIDPath)
-	kingpin.Flag("journald-path", "Journald journal directory path").
+	app.Flag("journald-path", "Journald journal directory path").
 		Default("/var/log/journal/").
 		Envar("JOURNALD_PATH").
 		StringVar(&config.JournaldConfig.JournaldPath)
 
 	// throttling
-	kingpin.Flag(
+	app.Flag(
 		"read-rate-rules-path",
 		"Path to file with throttling rules. If not specified, READ_RATE_DEFAULT will be used for all containers").
 		Default("").
 		Envar("READ_RATE_RULES_PATH").
 		StringVar(&config.ReadRateRulesPath)
-	kingpin.Flag(
+	app.Flag(
 		"read-rate-default",
 		"Maximum log messages per container to read per second by default. Must be greater than zero.").
 		Default("1000").
@@ -249,105 +437,304 @@ func GetConfig() Config {
 		Float64Var(&config.ReadRateDefault)
 
 	// extends
-	kingpin.Flag("dc", "Current datacenter id, will be included to each log message").
+	app.Flag("dc", "Current datacenter id, will be included to each log message").
 		Default("n3").
 		Envar("DC").
 		StringVar(&config.K8SExtends.DataCenter)
-	kingpin.Flag("purpose", "Current datacenter purpose, will be included to each log message").
+	app.Flag("purpose", "Current datacenter purpose, will be included to each log message").
 		Default("staging").
 		Envar("PURPOSE").
 		StringVar(&config.K8SExtends.Purpose)
-	kingpin.Flag("node-hostname", "Current node hostname, will be included to each log message").
+	app.Flag("node-hostname", "Current node hostname, will be included to each log message").
 		Default("localhost").
 		Envar("NODE_HOSTNAME").
 		StringVar(&config.K8SExtends.NodeHostname)
-	kingpin.Flag("log-type", "Current log type, will be included to each log message").
+	app.Flag("log-type", "Current log type, will be included to each log message").
 		Default("containers").
 		Envar("LOG_TYPE").
 		StringVar(&config.K8SExtends.LogType)
-	kingpin.Flag("logstash-prefix", "Current logstash prefix, will be included to each log message").
+	app.Flag("logstash-prefix", "Current logstash prefix, will be included to each log message").
 		Default("k8s-unknown").
 		Envar("LOGSTASH_PREFIX").
 		StringVar(&config.K8SExtends.LogstashPrefix)
 
 	// sla exporter
-	kingpin.Flag("sla-exporter", "Whether to export SLA or not, default true").
+	app.Flag("sla-exporter", "Whether to export SLA or not, default true").
 		Default("true").
 		Envar("SLA_EXPORTER").
 		BoolVar(&config.SLIExporterConfig.Enabled)
-	kingpin.Flag("k8s-config-path", "K8s config file path. Not required in current configuration").
+	app.Flag("k8s-config-path", "K8s config file path. Not required in current configuration").
 		Envar("K8S_CONFIG_PATH").
 		StringVar(&config.SLIExporterConfig.K8SConfigPath)
-	kingpin.Flag("sla-service-source-path", "Enables SLA in non-K8S mode; path to services declaration").
+	app.Flag("sla-service-source-path", "Enables SLA in non-K8S mode; path to services declaration").
 		Default("").
 		Envar("SLA_SERVICE_SOURCE_PATH").
 		StringVar(&config.SLIExporterConfig.ServiceSourcePath)
-	kingpin.Flag("default-service-domain", "").
+	app.Flag("default-service-domain", "").
 		Default("2gis.test").
 		Envar("SERVICE_DEFAULT_DOMAIN").
 		StringVar(&config.SLIExporterConfig.ServiceDefaultDomain)
-	kingpin.Flag("service-update-interval-sec", "How often to get updates from K8S Api Server").
+	app.Flag("service-update-interval-sec", "How often to get updates from K8S Api Server").
 		Default("60").
 		Envar("SERVICE_UPDATE_INTERVAL_SEC").
 		IntVar(&config.SLIExporterConfig.ServiceUpdateIntervalSec)
-	kingpin.Flag("sla-buckets", "Space-delimited float values of histogram bucket upper borders to use").
+	app.Flag("sla-buckets", "Space-delimited float values of histogram bucket upper borders to use").
 		Default("0.01 0.02 0.04 0.06 0.08 0.1 0.15 0.2 0.25 0.3 0.4 0.5 0.6 0.7 0.8 0.9 1 1.2 1.5 1.75 2 3 4 5 8 10 20 60").
 		Envar("SLA_BUCKETS").
 		StringVar(&config.SLIExporterConfig.Buckets)
+	app.Flag("sla-preset", "Access log schema to read SLI fields from [nginx | envoy | traefik | haproxy]").
+		Default("nginx").
+		Envar("SLA_PRESET").
+		StringVar(&config.SLIExporterConfig.Preset)
+	app.Flag("sla-fields-mapping",
+		"Comma-separated key=field overrides of preset SLI fields, e.g. host=authority,request_time_unit=ms").
+		Default("").
+		Envar("SLA_FIELDS_MAPPING").
+		StringVar(&config.SLIExporterConfig.FieldsMapping)
+	app.Flag("sla-series-limit-per-service",
+		"Max distinct method, path and upstream pod label sets of a service; 0 is unlimited").
+		Default("1000").
+		Envar("SLA_SERIES_LIMIT_PER_SERVICE").
+		IntVar(&config.SLIExporterConfig.SeriesLimitPerService)
+	app.Flag("sla-series-limit", "Max distinct method, path and upstream pod label sets in total; 0 is unlimited").
+		Default("20000").
+		Envar("SLA_SERIES_LIMIT").
+		IntVar(&config.SLIExporterConfig.SeriesLimit)
+	app.Flag("sla-native-histograms", "Whether to expose native histograms along with buckets").
+		Default("false").
+		Envar("SLA_NATIVE_HISTOGRAMS").
+		BoolVar(&config.SLIExporterConfig.NativeHistograms)
 	// backward compatibility with annotations
-	kingpin.Flag("sla-annotation-enable", "K8S service default enable annotation rewrite").
+	app.Flag("sla-annotation-enable", "K8S service default enable annotation rewrite").
 		Default(AnnotationExporterEnableDefault).
 		Envar("SLA_SERVICE_ANNOTATION_ENABLE").
 		StringVar(&config.SLIExporterConfig.AnnotationExporterEnable)
-	kingpin.Flag("sla-annotation-paths", "K8S service default paths annotation rewrite").
+	app.Flag("sla-annotation-paths", "K8S service default paths annotation rewrite").
 		Default(AnnotationExporterPathsDefault).
 		Envar("SLA_SERVICE_ANNOTATION_PATHS").
 		StringVar(&config.SLIExporterConfig.AnnotationExporterPaths)
-	kingpin.Flag("sla-annotation-domains", "K8S service default domains annotation rewrite").
+	app.Flag("sla-annotation-domains", "K8S service default domains annotation rewrite").
 		Default(AnnotationSLADomainsDefault).
 		Envar("SLA_SERVICE_ANNOTATION_DOMAINS").
 		StringVar(&config.SLIExporterConfig.AnnotationSLADomains)
+	app.Flag("sla-annotation-buckets", "K8S service annotation with histogram buckets overriding default ones").
+		Default(AnnotationSLABucketsDefault).
+		Envar("SLA_SERVICE_ANNOTATION_BUCKETS").
+		StringVar(&config.SLIExporterConfig.AnnotationSLABuckets)
+	app.Flag("slo-windows", "Comma-separated default burn rate windows of services with objective").
+		Default("5m,30m,1h,6h,1d,3d").
+		Envar("SLO_WINDOWS").
+		StringVar(&config.SLIExporterConfig.SLOWindows)
+	app.Flag("slo-annotation-objective", "K8S service annotation with objective, percent of good requests").
+		Default(AnnotationSLOObjectiveDefault).
+		Envar("SLO_ANNOTATION_OBJECTIVE").
+		StringVar(&config.SLIExporterConfig.AnnotationSLOObjective)
+	app.Flag("slo-annotation-latency-threshold", "K8S service annotation with latency threshold of good requests").
+		Default(AnnotationSLOLatencyThresholdDefault).
+		Envar("SLO_ANNOTATION_LATENCY_THRESHOLD").
+		StringVar(&config.SLIExporterConfig.AnnotationSLOLatencyThreshold)
+	app.Flag("slo-annotation-windows", "K8S service annotation with burn rate windows").
+		Default(AnnotationSLOWindowsDefault).
+		Envar("SLO_ANNOTATION_WINDOWS").
+		StringVar(&config.SLIExporterConfig.AnnotationSLOWindows)
+
+	// pods
+	app.Flag("pods-watch", "Whether to watch node pods for per-container settings set by annotations").
+		Default("false").
+		Envar("PODS_WATCH").
+		BoolVar(&config.PodsConfig.Enabled)
+	app.Flag("pods-resync-interval-sec", "How often to resync pods cache with K8S Api Server").
+		Default("600").
+		Envar("PODS_RESYNC_INTERVAL_SEC").
+		IntVar(&config.PodsConfig.ResyncIntervalSec)
+	app.Flag("pod-annotation-parser", "K8S pod annotation selecting user log parser [json | logfmt | regex]").
+		Default(AnnotationParserDefault).
+		Envar("POD_ANNOTATION_PARSER").
+		StringVar(&config.PodsConfig.AnnotationParser)
+	app.Flag("pod-annotation-regexp", "K8S pod annotation with regular expression for regex parser").
+		Default(AnnotationParserRegexpDefault).
+		Envar("POD_ANNOTATION_REGEXP").
+		StringVar(&config.PodsConfig.AnnotationParserRegexp)
+	app.Flag("pod-annotation-multiline-pattern", "K8S pod annotation with pattern of the first line of multiline record").
+		Default(AnnotationMultilinePatternDefault).
+		Envar("POD_ANNOTATION_MULTILINE_PATTERN").
+		StringVar(&config.PodsConfig.AnnotationMultilinePattern)
+	app.Flag("pod-annotation-exclude", "K8S pod annotation excluding pod containers from reading").
+		Default(AnnotationExcludeDefault).
+		Envar("POD_ANNOTATION_EXCLUDE").
+		StringVar(&config.PodsConfig.AnnotationExclude)
+	app.Flag("pods-enrichment", "Whether to extend entries with pod IP, owner workload and allowed labels, "+
+		"requires pods watch").
+		Default("false").
+		Envar("PODS_ENRICHMENT").
+		BoolVar(&config.PodsConfig.EnrichmentEnabled)
+	app.Flag("pods-enrichment-labels", "Comma-separated list of pod labels to extend entries with").
+		Default("").
+		Envar("PODS_ENRICHMENT_LABELS").
+		StringVar(&config.PodsConfig.EnrichmentLabels)
+	app.Flag("pods-enrichment-annotations", "Comma-separated list of pod annotations to extend entries with").
+		Default("").
+		Envar("PODS_ENRICHMENT_ANNOTATIONS").
+		StringVar(&config.PodsConfig.EnrichmentAnnotations)
+	app.Flag("pods-enrichment-node-labels", "Comma-separated list of node labels to extend entries with").
+		Default("").
+		Envar("PODS_ENRICHMENT_NODE_LABELS").
+		StringVar(&config.PodsConfig.EnrichmentNodeLabels)
 
-	kingpin.Flag("user-log-fields-key", "Entry field where user log should be put.").
+	// events
+	app.Flag("k8s-events", "Whether to collect K8S events; events are collected by a single loggo instance "+
+		"elected through the lease").
+		Default("false").
+		Envar("K8S_EVENTS").
+		BoolVar(&config.EventsConfig.Enabled)
+	app.Flag("k8s-events-lease-namespace", "Namespace of the lease electing K8S events collecting instance").
+		Default("default").
+		Envar("K8S_EVENTS_LEASE_NAMESPACE").
+		StringVar(&config.EventsConfig.LeaseNamespace)
+	app.Flag("k8s-events-lease-name", "Name of the lease electing K8S events collecting instance").
+		Default("loggo-events").
+		Envar("K8S_EVENTS_LEASE_NAME").
+		StringVar(&config.EventsConfig.LeaseName)
+	app.Flag("k8s-events-lease-duration", "Duration the other instances wait before taking over "+
+		"the lease of the collecting instance").
+		Default("15s").
+		Envar("K8S_EVENTS_LEASE_DURATION").
+		DurationVar(&config.EventsConfig.LeaseDuration)
+	app.Flag("k8s-events-resync-interval-sec", "How often to resync events cache with K8S Api Server").
+		Default("600").
+		Envar("K8S_EVENTS_RESYNC_INTERVAL_SEC").
+		IntVar(&config.EventsConfig.ResyncIntervalSec)
+
+	// timestamp
+	app.Flag("timestamp-normalization", "Whether to extract event time from user log fields and normalize it").
+		Default("false").
+		Envar("TIMESTAMP_NORMALIZATION").
+		BoolVar(&config.TimestampConfig.Enabled)
+	app.Flag("timestamp-fields", "Comma-separated list of user log fields to take event time from, "+
+		"checked in order").
+		Default("timestamp,ts,@timestamp,time").
+		Envar("TIMESTAMP_FIELDS").
+		StringVar(&config.TimestampConfig.Fields)
+	app.Flag("timestamp-formats", "Comma-separated list of event time formats, checked in order "+
+		"[rfc3339 | epoch | epoch_s | epoch_ms | epoch_us | epoch_ns | Go time layout]").
+		Default("rfc3339,epoch").
+		Envar("TIMESTAMP_FORMATS").
+		StringVar(&config.TimestampConfig.Formats)
+	app.Flag("timestamp-output-field", "Entry field where normalized event time should be put").
+		Default("@timestamp").
+		Envar("TIMESTAMP_OUTPUT_FIELD").
+		StringVar(&config.TimestampConfig.OutputField)
+	app.Flag("timestamp-output-format", "Normalized event time format "+
+		"[rfc3339 | rfc3339nano | epoch_s | epoch_ms | epoch_us | epoch_ns | Go time layout]").
+		Default("rfc3339nano").
+		Envar("TIMESTAMP_OUTPUT_FORMAT").
+		StringVar(&config.TimestampConfig.OutputFormat)
+	app.Flag("timestamp-collection-field", "Entry field where collection time should be put, "+
+		"in the same format as event time").
+		Default("collection_time").
+		Envar("TIMESTAMP_COLLECTION_FIELD").
+		StringVar(&config.TimestampConfig.CollectionField)
+
+	// severity
+	app.Flag("severity-normalization", "Whether to put canonical severity of user log to the separate field").
+		Default("false").
+		Envar("SEVERITY_NORMALIZATION").
+		BoolVar(&config.SeverityConfig.Enabled)
+	app.Flag("severity-fields", "Comma-separated list of user log fields to take severity from, "+
+		"checked in order").
+		Default("level,severity,lvl,log.level").
+		Envar("SEVERITY_FIELDS").
+		StringVar(&config.SeverityConfig.Fields)
+	app.Flag("severity-output-field", "Entry field where canonical severity should be put").
+		Default("severity_normalized").
+		Envar("SEVERITY_OUTPUT_FIELD").
+		StringVar(&config.SeverityConfig.OutputField)
+	app.Flag("severity-sampling", "Whether to sample entries by severity according to read rate rules, "+
+		"requires severity normalization").
+		Default("false").
+		Envar("SEVERITY_SAMPLING").
+		BoolVar(&config.SeverityConfig.SamplingEnabled)
+
+	app.Flag("trace-context-normalization", "Whether to put trace and span ids found in user log "+
+		"(traceparent, b3, trace_id and similar fields) to top-level trace_id and span_id fields").
+		Default("false").
+		Envar("TRACE_CONTEXT_NORMALIZATION").
+		BoolVar(&config.TraceContextConfig.Enabled)
+
+	app.Flag("filter-expression", "Expression over entry fields, entries not matching it are dropped, "+
+		"e.g. 'kubernetes.namespace_name != \"kube-system\" || status >= 500'").
+		Default("").
+		Envar("FILTER_EXPRESSION").
+		StringVar(&config.FilterExpression)
+
+	app.Flag("log-metrics-path", "Path to yaml file with declarations of metrics derived from log entries").
+		Default("").
+		Envar("LOG_METRICS_PATH").
+		StringVar(&config.LogMetricsPath)
+	app.Flag("log-metrics-series-limit",
+		"Max distinct label sets of every log derived metric, the rest are folded; 0 is unlimited").
+		Default("1000").
+		Envar("LOG_METRICS_SERIES_LIMIT").
+		IntVar(&config.LogMetricsSeriesLimit)
+
+	// redaction
+	app.Flag("redaction-rules-path", "Path to yaml file with redaction rules, redaction is off if not set").
+		Default("").
+		Envar("REDACTION_RULES_PATH").
+		StringVar(&config.RedactionRulesPath)
+	app.Flag("redaction-hmac-key-path", "Path to file with key for HMAC of values redacted by rules with hash action").
+		Default("").
+		Envar("REDACTION_HMAC_KEY_PATH").
+		StringVar(&config.RedactionHMACKeyPath)
+
+	app.Flag("user-log-fields-key", "Entry field where user log should be put.").
 		Default("").
 		Envar("USER_LOG_FIELDS_KEY").
 		StringVar(&config.ParserConfig.UserLogFieldsKey)
 
-	kingpin.Flag("cri-fields-key", "Entry field where docker/containerd engine fields map should be put.").
+	app.Flag("cri-fields-key", "Entry field where docker/containerd engine fields map should be put.").
 		Default("").
 		Envar("cri_FIELDS_KEY").
 		StringVar(&config.ParserConfig.CRIFieldsKey)
 
-	kingpin.Flag("extends-fields-key", "Entry field where loggo and k8s extends fields map should be put.").
+	app.Flag("extends-fields-key", "Entry field where loggo and k8s extends fields map should be put.").
 		Default("").
 		Envar("EXTENDS_FIELDS_KEY").
 		StringVar(&config.ParserConfig.ExtendsFieldsKey)
-	kingpin.Flag("raw-log-field-key", "Entry field inside user log fields map. Used for non-json messages.").
+	app.Flag("raw-log-field-key", "Entry field inside user log fields map. Used for non-json messages.").
 		Default("msg").
 		Envar("RAW_LOG_FIELD_KEY").
 		StringVar(&config.ParserConfig.RawLogFieldKey)
 
-	kingpin.Flag("flatten-user-log", "Whether to flatten user log or not.").
+	app.Flag("flatten-user-log", "Whether to flatten user log or not.").
 		Default("true").
 		Envar("FLATTEN_USER_LOG").
 		BoolVar(&config.ParserConfig.FlattenUserLog)
 
-	kingpin.Flag("metrics-reset-interval-sec", "Prometheus metrics reset interval.").
-		Default("172800").
+	app.Flag("metrics-reset-interval-sec", "Prometheus metrics reset interval; 0 (default) disables reset. "+
+		"Deprecated, stale series are deleted according to metrics-series-ttl-sec.").
+		Default("0").
 		Envar("METRICS_RESET_INTERVAL_SEC").
 		IntVar(&config.MetricsResetIntervalSec)
+	app.Flag("metrics-series-sweep-interval-sec", "How often to delete stale Prometheus metrics series.").
+		Default("60").
+		Envar("METRICS_SERIES_SWEEP_INTERVAL_SEC").
+		IntVar(&config.MetricsSeriesSweepIntervalSec)
+	app.Flag("metrics-series-ttl-sec", "Prometheus metrics series not updated for this time are deleted.").
+		Default("3600").
+		Envar("METRICS_SERIES_TTL_SEC").
+		IntVar(&config.MetricsSeriesTTLSec)
 
 	// logging
-	kingpin.Flag("log-level", "Loggo main log level").
+	app.Flag("log-level", "Loggo main log level").
 		Default("warning").
 		Envar("LOG_LEVEL").
 		StringVar(&config.LogLevel)
-	kingpin.Flag("log-format", "Loggo main log format").
+	app.Flag("log-format", "Loggo main log format").
 		Default("json").
 		Envar("LOG_FORMAT").
 		StringVar(&config.LogFormat)
 
-	kingpin.Parse()
 	return config
 }
 
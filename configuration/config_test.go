@@ -0,0 +1,19 @@
+package configuration
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+func TestConfigDefaults(t *testing.T) {
+	app := kingpin.New("loggo", "")
+	config := declareConfig(app)
+	_, err := app.Parse([]string{})
+	assert.NoError(t, err)
+
+	// stale series are deleted by TTL, metrics are reset only if the deprecated option is set explicitly
+	assert.Equal(t, 0, config.MetricsResetIntervalSec)
+	assert.Equal(t, 60, config.MetricsSeriesSweepIntervalSec)
+}
@@ -11,11 +11,41 @@ const (
 	// AnnotationSLADomainsDefault name of the annotation with served domain list
 	AnnotationSLADomainsDefault = "loggo.sla/domains"
 
+	// AnnotationSLABucketsDefault name of the annotation with service histogram buckets
+	AnnotationSLABucketsDefault = "loggo.sla/buckets"
+
 	// AnnotationSLADomainsDeprecated deprecated name of the annotation with served domain list
 	AnnotationSLADomainsDeprecated = "router.deis.io/domains"
 
 	AnnotationExporterEnableTrueDeprecated = "enable"
 	AnnotationExporterEnableTrue           = "true"
+)
+
+// Pods related
+const (
+	// AnnotationParserDefault name of the annotation with user log parser name
+	AnnotationParserDefault = "loggo.io/parser"
+
+	// AnnotationParserRegexpDefault name of the annotation with regular expression for regex parser
+	AnnotationParserRegexpDefault = "loggo.io/regex"
+
+	// AnnotationMultilinePatternDefault name of the annotation with pattern of multiline record first line
+	AnnotationMultilinePatternDefault = "loggo.io/multiline-pattern"
+
+	// AnnotationExcludeDefault name of the annotation for excluding pod containers from reading
+	AnnotationExcludeDefault = "loggo.io/exclude"
+
+	AnnotationExcludeTrue = "true"
+)
+
+// SLO related
+const (
+	// AnnotationSLOObjectiveDefault name of the annotation with service level objective, percent of good requests
+	AnnotationSLOObjectiveDefault = "loggo.sla/objective"
 
+	// AnnotationSLOLatencyThresholdDefault name of the annotation with request time above which request is bad
+	AnnotationSLOLatencyThresholdDefault = "loggo.sla/latency-threshold"
 
-)
\ No newline at end of file
+	// AnnotationSLOWindowsDefault name of the annotation with comma-separated burn rate windows
+	AnnotationSLOWindowsDefault = "loggo.sla/windows"
+)
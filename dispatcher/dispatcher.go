@@ -7,29 +7,49 @@ import (
 
 	"github.com/2gis/loggo/common"
 	"github.com/2gis/loggo/components/containers"
+	"github.com/2gis/loggo/components/files"
+	"github.com/2gis/loggo/components/k8s"
+	"github.com/2gis/loggo/components/watcher"
 	"github.com/2gis/loggo/configuration"
 	"github.com/2gis/loggo/dispatcher/workers"
 	"github.com/2gis/loggo/logging"
 )
 
-// Dispatcher starts and stops followers according to ContainersProvider targets, multiplexes their outputs
-// into output; based on config, starts system journal special follower and pipes its output separately
+// filesMissingGracePeriod is how long followers and cursors of files not matching inputs anymore are kept: rotated
+// file is missing until the new one is created, its follower is restarted from the beginning otherwise
+const filesMissingGracePeriod = time.Minute
+
+// seenFile is a file matched by inputs along with the time it has been matched last
+type seenFile struct {
+	file *files.File
+	seen time.Time
+}
+
+// Dispatcher starts and stops followers according to ContainersProvider and FilesProvider targets, multiplexes
+// their outputs into output; based on config, starts system journal special follower and pipes its output separately
 type Dispatcher struct {
 	ticker *time.Ticker
 
 	config configuration.Config
 
 	followerPool       FollowerPool
-	ignoredPool        map[string]bool
+	ignoreList         *IgnoreList
+	selectors          *Selectors
 	containersProvider ContainersProvider
+	podsProvider       PodsProvider
+	filesProvider      FilesProvider
+	watcher            TreeWatcher
 
 	followerFabric workers.FollowerFabric
 	cursorStorage  workers.Storage
 
 	startJournald bool
 
+	filesSeen map[string]*seenFile
+	now       func() time.Time
+
 	output         chan *common.Entry
-	outputJournald chan string
+	outputJournald chan common.EntryMap
 
 	wg     *sync.WaitGroup
 	logger logging.Logger
@@ -41,31 +61,39 @@ func (d *Dispatcher) Out() <-chan *common.Entry {
 }
 
 // OutJournald is a dispatcher outputJournald channel accessor
-func (d *Dispatcher) OutJournald() <-chan string {
+func (d *Dispatcher) OutJournald() <-chan common.EntryMap {
 	return d.outputJournald
 }
 
-// NewDispatcher is a Dispatcher constructor
+// NewDispatcher is a Dispatcher constructor; watcher may be nil, logs path is only rescanned periodically then
 func NewDispatcher(
 	config configuration.Config, followerFabric workers.FollowerFabric, containersProvider ContainersProvider,
-	cursorStorage workers.Storage, logger logging.Logger) *Dispatcher {
+	podsProvider PodsProvider, filesProvider FilesProvider, watcher TreeWatcher, cursorStorage workers.Storage,
+	ignoreList *IgnoreList, selectors *Selectors, logger logging.Logger) *Dispatcher {
 	return &Dispatcher{
 		config: config,
 		ticker: time.NewTicker(time.Duration(config.TargetsRefreshIntervalSec) * time.Second),
 
 		followerPool: NewFollowerPool(),
-		ignoredPool:  make(map[string]bool),
+		ignoreList:   ignoreList,
+		selectors:    selectors,
 
 		followerFabric:     followerFabric,
 		containersProvider: containersProvider,
+		podsProvider:       podsProvider,
+		filesProvider:      filesProvider,
+		watcher:            watcher,
 		cursorStorage:      cursorStorage,
 		startJournald:      config.JournaldConfig.LogJournalD,
 
+		filesSeen: make(map[string]*seenFile),
+		now:       time.Now,
+
 		wg:     &sync.WaitGroup{},
 		logger: logger,
 
 		output:         make(chan *common.Entry),
-		outputJournald: make(chan string),
+		outputJournald: make(chan common.EntryMap),
 	}
 }
 
@@ -84,15 +112,23 @@ func (d *Dispatcher) Start(ctx context.Context) {
 }
 
 func (d *Dispatcher) startDispatching(ctx context.Context) {
+	subscription := d.watchLogsPath()
+	defer subscription.Close()
+
 	if err := d.dispatch(ctx); err != nil {
-		d.logger.Errorf("unable to get containers list, %d", err)
+		d.logger.Errorf("unable to get containers list, %s", err)
 	}
 
 	for {
 		select {
 		case <-d.ticker.C:
 			if err := d.dispatch(ctx); err != nil {
-				d.logger.Errorf("unable to get containers list, %d", err)
+				d.logger.Errorf("unable to get containers list, %s", err)
+			}
+		case <-subscription.C():
+			// new pods and containers are started without waiting for the next tick
+			if err := d.dispatch(ctx); err != nil {
+				d.logger.Errorf("unable to get containers list, %s", err)
 			}
 		case <-ctx.Done():
 			return
@@ -100,38 +136,106 @@ func (d *Dispatcher) startDispatching(ctx context.Context) {
 	}
 }
 
+// watchLogsPath subscribes to logs path tree changes; nil subscription is returned if it can't be watched
+func (d *Dispatcher) watchLogsPath() *watcher.Subscription {
+	if d.watcher == nil {
+		return nil
+	}
+
+	subscription, err := d.watcher.WatchTree(d.config.LogsPath)
+
+	if err != nil {
+		d.logger.Warnf("dispatcher: unable to watch '%s', falling back to polling: %s", d.config.LogsPath, err)
+		return nil
+	}
+
+	return subscription
+}
+
+// dispatch starts followers of new targets and stops orphan ones; file followers are started even if containers can't
+// be listed, orphans are removed only once they are
 func (d *Dispatcher) dispatch(ctx context.Context) error {
+	filesActual, filesMissing := d.files()
 	containersActual, err := d.containersProvider.Containers()
 
 	if err != nil {
+		d.startFileFollowers(ctx, filesActual, filesMissing)
 		return err
 	}
 
-	d.removeOrphans(containersActual)
+	d.removeOrphans(containersActual, filesActual)
 	d.startFollowers(ctx, containersActual)
+	d.startFileFollowers(ctx, filesActual, filesMissing)
 	return nil
 }
 
-func (d *Dispatcher) removeOrphans(containers containers.Containers) {
+// files returns files matched by inputs along with files missing for less than filesMissingGracePeriod; the latter
+// are returned separately as well: their followers and cursors are kept, but no followers are started for them
+func (d *Dispatcher) files() (files.Files, files.Files) {
+	now := d.now()
+	matched := d.filesProvider.Files()
+	actual := make(files.Files, len(matched))
+	missing := make(files.Files)
+
+	for path, file := range matched {
+		actual[path] = file
+		d.filesSeen[path] = &seenFile{file: file, seen: now}
+	}
+
+	for path, seen := range d.filesSeen {
+		if matched.Present(path) {
+			continue
+		}
+
+		if now.Sub(seen.seen) >= filesMissingGracePeriod {
+			delete(d.filesSeen, path)
+			continue
+		}
+
+		actual[path] = seen.file
+		missing[path] = seen.file
+	}
+
+	return actual, missing
+}
+
+func (d *Dispatcher) removeOrphans(containers containers.Containers, files files.Files) {
 	// remove orphan followers from the pool
 	for path, follower := range d.followerPool.Pool() {
-		if !containers.Present(path) {
+		if files.Present(path) {
+			// follower of the file has failed, it's restarted on the next dispatch
+			if !follower.GetActiveFlag() {
+				d.followerPool.Remove(path)
+			}
+
+			continue
+		}
+
+		container, present := containers[path]
+
+		if !present {
 			follower.Stop()
 		}
 
-		if containers.Present(path) && !containers[path].Running() {
+		stopped := present && !container.Running()
+
+		if stopped {
 			follower.SetEOFShutdownFlag()
-			d.ignoredPool[path] = true
 		}
 
 		if !follower.GetActiveFlag() {
 			d.followerPool.Remove(path)
+
+			// log file of the stopped container has been read to the end
+			if stopped {
+				d.ignoreList.Add(container)
+			}
 		}
 	}
 
 	keys, err := d.cursorStorage.Keys()
 	if err != nil {
-		d.logger.Errorf("dispatcher: unable to read storage: %d", err)
+		d.logger.Errorf("dispatcher: unable to read storage: %s", err)
 		keys = []string{}
 	}
 
@@ -143,23 +247,17 @@ func (d *Dispatcher) removeOrphans(containers containers.Containers) {
 			continue
 		}
 
-		if containers.Present(key) {
+		if containers.Present(key) || files.Present(key) {
 			continue
 		}
 
 		if err := d.cursorStorage.Delete(key); err != nil {
-			d.logger.Errorf("dispatcher: unable to delete key '%d' from storage: %d", key, err)
+			d.logger.Errorf("dispatcher: unable to delete key '%s' from storage: %s", key, err)
 		}
 	}
 
 	// remove orphan ignore records from storage
-	for path := range d.ignoredPool {
-		if containers.Present(path) {
-			continue
-		}
-
-		delete(d.ignoredPool, path)
-	}
+	d.ignoreList.Reconcile(containers)
 }
 
 func (d *Dispatcher) startFollowers(ctx context.Context, containers containers.Containers) {
@@ -170,7 +268,19 @@ func (d *Dispatcher) startFollowers(ctx context.Context, containers containers.C
 			continue
 		}
 
-		if ignored := d.ignoredPool[container.LogPath]; ignored {
+		if d.ignoreList.Ignored(container) {
+			continue
+		}
+
+		pod := d.podsProvider.GetPod(container.GetPodNamespace(), container.GetPodName())
+
+		if !d.selectors.Selected(container, pod) {
+			continue
+		}
+
+		settings := d.parsingSettings(container, pod)
+
+		if settings != nil && settings.Exclude {
 			continue
 		}
 
@@ -179,6 +289,7 @@ func (d *Dispatcher) startFollowers(ctx context.Context, containers containers.C
 			container.LogPath,
 			container.Type,
 			d.containerExtends(container),
+			settings,
 		)
 
 		if err != nil {
@@ -197,6 +308,59 @@ func (d *Dispatcher) startFollowers(ctx context.Context, containers containers.C
 	}
 }
 
+func (d *Dispatcher) startFileFollowers(ctx context.Context, files, missing files.Files) {
+	for _, file := range files {
+		if _, present := d.followerPool.Get(file.Path); present {
+			continue
+		}
+
+		if missing.Present(file.Path) {
+			continue
+		}
+
+		follower, err := d.followerFabric.NewFollower(
+			d.output,
+			file.Path,
+			common.FormatFile,
+			file.Extends,
+			file.Settings,
+		)
+
+		if err != nil {
+			d.logger.Error(err)
+			continue
+		}
+
+		d.wg.Add(1)
+
+		go func() {
+			defer d.wg.Done()
+			follower.Start(ctx)
+		}()
+
+		d.followerPool.Add(file.Path, follower)
+	}
+}
+
+// parsingSettings returns parsing settings of the container set by its pod annotations, if any
+func (d *Dispatcher) parsingSettings(c *containers.Container, pod *k8s.Pod) *k8s.ParsingSettings {
+	if pod == nil {
+		return nil
+	}
+
+	settings, err := pod.ParsingSettings(d.config.PodsConfig, c.GetName())
+
+	if err != nil {
+		d.logger.Warnf(
+			"dispatcher: invalid parsing annotations of pod '%s.%s', defaults are used: %s",
+			c.GetPodNamespace(), c.GetPodName(), err,
+		)
+		return nil
+	}
+
+	return settings
+}
+
 func (d *Dispatcher) startFollowerJournald(ctx context.Context) {
 	followerJournald, err := d.followerFabric.NewFollowerJournald(d.outputJournald, d.config.ParserConfig, d.logger)
 
@@ -223,6 +387,7 @@ func (d *Dispatcher) containerExtends(c *containers.Container) (extends common.E
 	extends[common.KubernetesPodName] = c.GetPodName()
 	extends[common.KubernetesNamespaceName] = c.GetPodNamespace()
 	extends[common.KubernetesContainerName] = c.GetName()
+	extends.Extend(d.podsProvider.Extends(c.GetPodNamespace(), c.GetPodName()))
 
 	return
 }
@@ -0,0 +1,325 @@
+package dispatcher
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/2gis/loggo/common"
+	"github.com/2gis/loggo/components/containers"
+	"github.com/2gis/loggo/components/files"
+	"github.com/2gis/loggo/components/k8s"
+	"github.com/2gis/loggo/components/watcher"
+	"github.com/2gis/loggo/configuration"
+	"github.com/2gis/loggo/dispatcher/workers"
+	"github.com/2gis/loggo/logging"
+)
+
+type containersProviderStub struct {
+	containers containers.Containers
+	err        error
+}
+
+func (p *containersProviderStub) Containers() (containers.Containers, error) {
+	return p.containers, p.err
+}
+
+type filesProviderStub struct {
+	files files.Files
+}
+
+func (p *filesProviderStub) Files() files.Files {
+	return p.files
+}
+
+type followerStub struct {
+	active bool
+	eof    bool
+	format string
+}
+
+func (f *followerStub) Start(_ context.Context) {}
+
+func (f *followerStub) Stop() {
+	f.active = false
+}
+
+func (f *followerStub) GetActiveFlag() bool {
+	return f.active
+}
+
+func (f *followerStub) SetEOFShutdownFlag() {
+	f.eof = true
+}
+
+type followerFabricStub struct {
+	followers map[string]*followerStub
+}
+
+func (f *followerFabricStub) NewFollower(_ chan<- *common.Entry, filePath, format string, _ common.EntryMap,
+	_ *k8s.ParsingSettings) (workers.Follower, error) {
+	follower := &followerStub{active: true, format: format}
+	f.followers[filePath] = follower
+	return follower, nil
+}
+
+func (f *followerFabricStub) NewFollowerJournald(
+	_ chan<- common.EntryMap, _ configuration.ParserConfig, _ logging.Logger) (workers.FollowerJournald, error) {
+	return nil, nil
+}
+
+type podsProviderStub struct {
+	k8s.ProviderStub
+	pods map[string]*k8s.Pod
+}
+
+func (p *podsProviderStub) GetPod(namespace, name string) *k8s.Pod {
+	return p.pods[namespace+"/"+name]
+}
+
+func newTestContainer(id, namespace, pod, name string) *containers.Container {
+	return &containers.Container{
+		ID:      id,
+		LogPath: "/var/log/pods/" + namespace + "_" + pod + "_" + id + "/" + name + "/0.log",
+		State:   containers.StateSection{Running: true},
+		Config: containers.ConfigSection{Labels: map[string]string{
+			common.LabelKubernetesPodNamespace:  namespace,
+			common.LabelKubernetesPodName:       pod,
+			common.LabelKubernetesContainerName: name,
+		}},
+	}
+}
+
+func TestDispatcherIgnoresStoppedContainers(t *testing.T) {
+	logger := logging.NewLoggerDefault()
+	ctx := context.Background()
+	cursorStorage := newTestStorage(t, "cursors")
+	ignoreStorage := newTestStorage(t, "ignore")
+
+	container := &containers.Container{ID: "id", LogPath: "/var/log/pods/app/0.log", State: containers.StateSection{
+		Running: true,
+	}}
+	provider := &containersProviderStub{containers: containers.Containers{container.LogPath: container}}
+	newDispatcher := func() (*Dispatcher, *followerFabricStub) {
+		ignoreList, err := NewIgnoreList(ignoreStorage, logger)
+		assert.NoError(t, err)
+
+		fabric := &followerFabricStub{followers: make(map[string]*followerStub)}
+		return NewDispatcher(
+			configuration.Config{TargetsRefreshIntervalSec: 1}, fabric, provider, k8s.NewProviderStub(),
+			&filesProviderStub{}, nil, cursorStorage, ignoreList, testSelectors(t, SelectorsRecords{}), logger,
+		), fabric
+	}
+
+	d, fabric := newDispatcher()
+	assert.NoError(t, d.dispatch(ctx))
+	follower := fabric.followers[container.LogPath]
+	assert.NotNil(t, follower)
+
+	// the stopped container is read to the end first
+	container.State.Running = false
+	assert.NoError(t, d.dispatch(ctx))
+	assert.True(t, follower.eof)
+	assert.False(t, d.ignoreList.Ignored(container))
+
+	follower.active = false
+	assert.NoError(t, d.dispatch(ctx))
+	assert.True(t, d.ignoreList.Ignored(container))
+	assert.Len(t, fabric.followers, 1)
+
+	// restart
+	d, fabric = newDispatcher()
+	assert.NoError(t, d.dispatch(ctx))
+	assert.Empty(t, fabric.followers)
+
+	// container is removed
+	provider.containers = containers.Containers{}
+	assert.NoError(t, d.dispatch(ctx))
+
+	keys, err := ignoreStorage.Keys()
+	assert.NoError(t, err)
+	assert.Empty(t, keys)
+}
+
+func TestDispatcherSelectors(t *testing.T) {
+	selectors := testSelectors(t, SelectorsRecords{
+		Include: []SelectorRecord{{Namespace: "tenant-*"}, {Labels: map[string]string{"logging": "enabled"}}},
+		Exclude: []SelectorRecord{{Container: "istio-proxy"}},
+	})
+	actual := containers.Containers{}
+
+	for _, container := range []*containers.Container{
+		newTestContainer("1", "tenant-a", "api", "app"),
+		newTestContainer("2", "tenant-a", "api", "istio-proxy"),
+		newTestContainer("3", "kube-system", "dns", "coredns"),
+		newTestContainer("4", "shared", "db", "postgres"),
+	} {
+		actual[container.LogPath] = container
+	}
+
+	fabric := &followerFabricStub{followers: make(map[string]*followerStub)}
+	pods := &podsProviderStub{pods: map[string]*k8s.Pod{
+		"shared/db": {Labels: map[string]string{"logging": "enabled"}},
+	}}
+	ignoreList, err := NewIgnoreList(newTestStorage(t, "ignore"), logging.NewLoggerDefault())
+	assert.NoError(t, err)
+
+	d := NewDispatcher(
+		configuration.Config{TargetsRefreshIntervalSec: 1}, fabric, &containersProviderStub{containers: actual}, pods,
+		&filesProviderStub{}, nil, newTestStorage(t, "cursors"), ignoreList, selectors, logging.NewLoggerDefault(),
+	)
+	assert.NoError(t, d.dispatch(context.Background()))
+
+	started := make([]string, 0)
+
+	for path := range fabric.followers {
+		started = append(started, actual[path].ID)
+	}
+
+	assert.ElementsMatch(t, []string{"1", "4"}, started)
+}
+
+func TestDispatcherFiles(t *testing.T) {
+	logger := logging.NewLoggerDefault()
+	ctx := context.Background()
+	cursorStorage := newTestStorage(t, "cursors")
+	ignoreList, err := NewIgnoreList(newTestStorage(t, "ignore"), logger)
+	assert.NoError(t, err)
+
+	file := &files.File{Path: "/var/log/audit/audit.log", Extends: common.EntryMap{"type": "audit"}}
+	provider := &filesProviderStub{files: files.Files{file.Path: file}}
+	fabric := &followerFabricStub{followers: make(map[string]*followerStub)}
+	assert.NoError(t, cursorStorage.Set(file.Path, "1 2 3"))
+	assert.NoError(t, cursorStorage.Set("/var/log/removed.log", "1 2 3"))
+
+	d := NewDispatcher(
+		configuration.Config{TargetsRefreshIntervalSec: 1}, fabric, &containersProviderStub{}, k8s.NewProviderStub(),
+		provider, nil, cursorStorage, ignoreList, testSelectors(t, SelectorsRecords{}), logger,
+	)
+	assert.NoError(t, d.dispatch(ctx))
+
+	follower := fabric.followers[file.Path]
+	assert.NotNil(t, follower)
+	assert.Equal(t, common.FormatFile, follower.format)
+
+	keys, err := cursorStorage.Keys()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{file.Path}, keys)
+
+	// failed follower is restarted
+	follower.active = false
+	assert.NoError(t, d.dispatch(ctx))
+	assert.NoError(t, d.dispatch(ctx))
+	assert.NotSame(t, follower, fabric.followers[file.Path])
+	assert.True(t, fabric.followers[file.Path].active)
+
+	// file is missing for a while during rotation, its follower and cursor are kept
+	now := time.Now()
+	d.now = func() time.Time { return now }
+	assert.NoError(t, d.dispatch(ctx))
+	follower = fabric.followers[file.Path]
+	provider.files = files.Files{}
+	assert.NoError(t, d.dispatch(ctx))
+	assert.True(t, follower.active)
+
+	keys, err = cursorStorage.Keys()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{file.Path}, keys)
+
+	// rotated file is back
+	provider.files = files.Files{file.Path: file}
+	assert.NoError(t, d.dispatch(ctx))
+	assert.Same(t, follower, fabric.followers[file.Path])
+
+	// file doesn't match patterns anymore
+	provider.files = files.Files{}
+	assert.NoError(t, d.dispatch(ctx))
+	assert.True(t, follower.active)
+	now = now.Add(filesMissingGracePeriod)
+	assert.NoError(t, d.dispatch(ctx))
+	assert.False(t, follower.active)
+
+	keys, err = cursorStorage.Keys()
+	assert.NoError(t, err)
+	assert.Empty(t, keys)
+}
+
+func TestDispatcherFilesContainersError(t *testing.T) {
+	logger := logging.NewLoggerDefault()
+	cursorStorage := newTestStorage(t, "cursors")
+	ignoreList, err := NewIgnoreList(newTestStorage(t, "ignore"), logger)
+	assert.NoError(t, err)
+
+	file := &files.File{Path: "/var/log/audit/audit.log"}
+	fabric := &followerFabricStub{followers: make(map[string]*followerStub)}
+	assert.NoError(t, cursorStorage.Set("/var/log/pods/app/0.log", "1 2 3"))
+
+	d := NewDispatcher(
+		configuration.Config{TargetsRefreshIntervalSec: 1}, fabric,
+		&containersProviderStub{err: errors.New("runtime is unavailable")}, k8s.NewProviderStub(),
+		&filesProviderStub{files: files.Files{file.Path: file}}, nil, cursorStorage, ignoreList,
+		testSelectors(t, SelectorsRecords{}), logger,
+	)
+
+	// files are followed regardless of containers, cursors of containers are kept until they're listed
+	assert.Error(t, d.dispatch(context.Background()))
+	assert.NotNil(t, fabric.followers[file.Path])
+
+	keys, err := cursorStorage.Keys()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"/var/log/pods/app/0.log"}, keys)
+}
+
+type notifyingProviderStub struct {
+	containersProviderStub
+	calls chan struct{}
+}
+
+func (p *notifyingProviderStub) Containers() (containers.Containers, error) {
+	p.calls <- struct{}{}
+	return p.containersProviderStub.Containers()
+}
+
+func TestDispatcherWatchesLogsPath(t *testing.T) {
+	logger := logging.NewLoggerDefault()
+	ctx, cancel := context.WithCancel(context.Background())
+	root := t.TempDir()
+
+	fsWatcher, err := watcher.NewWatcher(logger)
+	assert.NoError(t, err)
+	go fsWatcher.Start(ctx)
+
+	ignoreList, err := NewIgnoreList(newTestStorage(t, "ignore"), logger)
+	assert.NoError(t, err)
+
+	provider := &notifyingProviderStub{calls: make(chan struct{}, 10)}
+	d := NewDispatcher(
+		configuration.Config{TargetsRefreshIntervalSec: 60, LogsPath: root},
+		&followerFabricStub{followers: make(map[string]*followerStub)}, provider, k8s.NewProviderStub(),
+		&filesProviderStub{}, fsWatcher, newTestStorage(t, "cursors"), ignoreList,
+		testSelectors(t, SelectorsRecords{}), logger,
+	)
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+		d.Start(ctx)
+	}()
+
+	<-provider.calls
+	assert.NoError(t, os.Mkdir(filepath.Join(root, "ns_pod_uid"), 0755))
+
+	select {
+	case <-provider.calls:
+	case <-time.After(5 * time.Second):
+		assert.Fail(t, "containers haven't been dispatched on the new pod directory")
+	}
+
+	cancel()
+	<-done
+}
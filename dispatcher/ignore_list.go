@@ -0,0 +1,78 @@
+package dispatcher
+
+import (
+	"github.com/2gis/loggo/components/containers"
+	"github.com/2gis/loggo/dispatcher/workers"
+	"github.com/2gis/loggo/logging"
+)
+
+// IgnoreList keeps log files of stopped containers that have been read to the end, so they aren't read again after
+// restart; records are keyed by container ID and log path and are kept in storage while containers are present
+type IgnoreList struct {
+	storage workers.Storage
+	records map[string]bool
+	logger  logging.Logger
+}
+
+// NewIgnoreList is an IgnoreList constructor, records are loaded from storage
+func NewIgnoreList(storage workers.Storage, logger logging.Logger) (*IgnoreList, error) {
+	keys, err := storage.Keys()
+
+	if err != nil {
+		return nil, err
+	}
+
+	records := make(map[string]bool, len(keys))
+
+	for _, key := range keys {
+		records[key] = true
+	}
+
+	return &IgnoreList{
+		storage: storage,
+		records: records,
+		logger:  logger,
+	}, nil
+}
+
+// Ignored checks if log file of the container has been read to the end
+func (l *IgnoreList) Ignored(container *containers.Container) bool {
+	return l.records[ignoreKey(container)]
+}
+
+// Add adds container to the list; the record is kept in memory even if storage fails, so the file isn't read again
+// until restart
+func (l *IgnoreList) Add(container *containers.Container) {
+	key := ignoreKey(container)
+	l.records[key] = true
+
+	if err := l.storage.Set(key, container.LogPath); err != nil {
+		l.logger.Errorf("dispatcher: unable to store ignored container '%s': %s", key, err)
+	}
+}
+
+// Reconcile removes records of containers that aren't present anymore
+func (l *IgnoreList) Reconcile(actual containers.Containers) {
+	present := make(map[string]bool, len(actual))
+
+	for _, container := range actual {
+		present[ignoreKey(container)] = true
+	}
+
+	for key := range l.records {
+		if present[key] {
+			continue
+		}
+
+		delete(l.records, key)
+
+		if err := l.storage.Delete(key); err != nil {
+			l.logger.Errorf("dispatcher: unable to delete ignored container '%s' from storage: %s", key, err)
+		}
+	}
+}
+
+// ignoreKey makes record key of container; log path alone may be reused by another container with the same name
+func ignoreKey(container *containers.Container) string {
+	return container.ID + ":" + container.LogPath
+}
@@ -0,0 +1,46 @@
+package dispatcher
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/2gis/loggo/components/containers"
+	"github.com/2gis/loggo/logging"
+	"github.com/2gis/loggo/storage"
+)
+
+func newTestStorage(t *testing.T, name string) *storage.Storage {
+	s, err := storage.NewStorage(filepath.Join(t.TempDir(), name), 1)
+	assert.NoError(t, err)
+	t.Cleanup(func() { _ = s.Close() })
+	return s
+}
+
+func TestIgnoreList(t *testing.T) {
+	logger := logging.NewLoggerDefault()
+	ignoreStorage := newTestStorage(t, "ignore")
+	container := &containers.Container{ID: "id", LogPath: "/var/log/pods/app/0.log"}
+	reused := &containers.Container{ID: "other", LogPath: "/var/log/pods/app/0.log"}
+
+	ignoreList, err := NewIgnoreList(ignoreStorage, logger)
+	assert.NoError(t, err)
+	assert.False(t, ignoreList.Ignored(container))
+
+	ignoreList.Add(container)
+	assert.True(t, ignoreList.Ignored(container))
+	assert.False(t, ignoreList.Ignored(reused))
+
+	// restart
+	ignoreList, err = NewIgnoreList(ignoreStorage, logger)
+	assert.NoError(t, err)
+	assert.True(t, ignoreList.Ignored(container))
+
+	ignoreList.Reconcile(containers.Containers{reused.LogPath: reused})
+	assert.False(t, ignoreList.Ignored(container))
+
+	keys, err := ignoreStorage.Keys()
+	assert.NoError(t, err)
+	assert.Empty(t, keys)
+}
@@ -1,10 +1,30 @@
 package dispatcher
 
 import (
+	"github.com/2gis/loggo/common"
 	"github.com/2gis/loggo/components/containers"
+	"github.com/2gis/loggo/components/files"
+	"github.com/2gis/loggo/components/k8s"
+	"github.com/2gis/loggo/components/watcher"
 )
 
 // ContainersProvider is a containers provider interface for dispatcher
 type ContainersProvider interface {
 	Containers() (containers.Containers, error)
 }
+
+// PodsProvider is a pods metadata provider interface for dispatcher
+type PodsProvider interface {
+	GetPod(namespace, name string) *k8s.Pod
+	Extends(namespace, name string) common.EntryMap
+}
+
+// FilesProvider is a host log files provider interface for dispatcher
+type FilesProvider interface {
+	Files() files.Files
+}
+
+// TreeWatcher is a directory tree changes notifier interface for dispatcher
+type TreeWatcher interface {
+	WatchTree(root string) (*watcher.Subscription, error)
+}
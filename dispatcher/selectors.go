@@ -0,0 +1,236 @@
+package dispatcher
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/2gis/loggo/components/containers"
+	"github.com/2gis/loggo/components/k8s"
+)
+
+// selectorRegexpPrefix marks selector patterns that are regular expressions, other patterns are globs
+const selectorRegexpPrefix = "~"
+
+// SelectorRecord is the struct to unmarshal yaml item of include or exclude list of selectors file to; empty fields
+// match anything
+type SelectorRecord struct {
+	Namespace   string
+	Pod         string
+	Container   string
+	Labels      map[string]string
+	Annotations map[string]string
+}
+
+// SelectorsRecords is the struct to unmarshal yaml selectors file to
+type SelectorsRecords struct {
+	Include []SelectorRecord
+	Exclude []SelectorRecord
+}
+
+// selectorRecordLoggo excludes loggo own containers, it precedes exclude selectors from the file; reading own log
+// would make loggo log about every own log entry it reads
+var selectorRecordLoggo = SelectorRecord{Container: "*loggo*"}
+
+type matcher func(value string) bool
+
+// selector matches containers with all the patterns matching
+type selector struct {
+	namespace   matcher
+	pod         matcher
+	container   matcher
+	labels      map[string]matcher
+	annotations map[string]matcher
+}
+
+// Selectors decide which containers are read: a container is read if it matches any include selector (or there
+// are none) and doesn't match any exclude selector. Selectors with labels or annotations match only containers
+// of pods known to pods provider
+type Selectors struct {
+	include []*selector
+	exclude []*selector
+}
+
+// LoadSelectorsRecords reads selectors from yaml file
+func LoadSelectorsRecords(filePath string) (SelectorsRecords, error) {
+	records := SelectorsRecords{}
+	data, err := ioutil.ReadFile(filePath)
+
+	if err != nil {
+		return records, err
+	}
+
+	err = yaml.UnmarshalStrict(data, &records)
+	return records, err
+}
+
+// NewSelectors is a Selectors constructor; loggo containers are always excluded
+func NewSelectors(records SelectorsRecords) (*Selectors, error) {
+	include, err := newSelectorList(records.Include)
+
+	if err != nil {
+		return nil, fmt.Errorf("invalid include selector, %w", err)
+	}
+
+	exclude, err := newSelectorList(append([]SelectorRecord{selectorRecordLoggo}, records.Exclude...))
+
+	if err != nil {
+		return nil, fmt.Errorf("invalid exclude selector, %w", err)
+	}
+
+	return &Selectors{include: include, exclude: exclude}, nil
+}
+
+// Selected checks if container should be read; pod may be nil
+func (s *Selectors) Selected(container *containers.Container, pod *k8s.Pod) bool {
+	for _, selector := range s.exclude {
+		if selector.match(container, pod) {
+			return false
+		}
+	}
+
+	if len(s.include) == 0 {
+		return true
+	}
+
+	for _, selector := range s.include {
+		if selector.match(container, pod) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// UsesPods checks if any selector requires pod labels or annotations
+func (s *Selectors) UsesPods() bool {
+	for _, list := range [][]*selector{s.include, s.exclude} {
+		for _, selector := range list {
+			if len(selector.labels) > 0 || len(selector.annotations) > 0 {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func newSelectorList(records []SelectorRecord) ([]*selector, error) {
+	result := make([]*selector, 0, len(records))
+
+	for _, record := range records {
+		selector, err := newSelector(record)
+
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, selector)
+	}
+
+	return result, nil
+}
+
+func newSelector(record SelectorRecord) (*selector, error) {
+	var err error
+	s := &selector{}
+
+	if s.namespace, err = newMatcher(record.Namespace); err != nil {
+		return nil, err
+	}
+
+	if s.pod, err = newMatcher(record.Pod); err != nil {
+		return nil, err
+	}
+
+	if s.container, err = newMatcher(record.Container); err != nil {
+		return nil, err
+	}
+
+	if s.labels, err = newMatchers(record.Labels); err != nil {
+		return nil, err
+	}
+
+	if s.annotations, err = newMatchers(record.Annotations); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+func (s *selector) match(container *containers.Container, pod *k8s.Pod) bool {
+	if !s.namespace(container.GetPodNamespace()) || !s.pod(container.GetPodName()) ||
+		!s.container(container.GetName()) {
+		return false
+	}
+
+	if len(s.labels) == 0 && len(s.annotations) == 0 {
+		return true
+	}
+
+	if pod == nil {
+		return false
+	}
+
+	return matchAll(s.labels, pod.Labels) && matchAll(s.annotations, pod.Annotations)
+}
+
+// matchAll checks that every key is present in values and its value matches
+func matchAll(matchers map[string]matcher, values map[string]string) bool {
+	for key, match := range matchers {
+		value, ok := values[key]
+
+		if !ok || !match(value) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func newMatchers(patterns map[string]string) (map[string]matcher, error) {
+	result := make(map[string]matcher, len(patterns))
+
+	for key, pattern := range patterns {
+		match, err := newMatcher(pattern)
+
+		if err != nil {
+			return nil, err
+		}
+
+		result[key] = match
+	}
+
+	return result, nil
+}
+
+// newMatcher makes matcher of glob or regular expression prefixed with ~; both match the whole value, empty pattern
+// matches anything
+func newMatcher(pattern string) (matcher, error) {
+	if pattern == "" {
+		return func(string) bool { return true }, nil
+	}
+
+	if strings.HasPrefix(pattern, selectorRegexpPrefix) {
+		expression, err := regexp.Compile("^(?:" + strings.TrimPrefix(pattern, selectorRegexpPrefix) + ")$")
+
+		if err != nil {
+			return nil, err
+		}
+
+		return expression.MatchString, nil
+	}
+
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, fmt.Errorf("%w: '%s'", err, pattern)
+	}
+
+	return func(value string) bool {
+		matched, _ := path.Match(pattern, value)
+		return matched
+	}, nil
+}
@@ -0,0 +1,92 @@
+package dispatcher
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/2gis/loggo/components/k8s"
+)
+
+func testSelectors(t *testing.T, records SelectorsRecords) *Selectors {
+	selectors, err := NewSelectors(records)
+	assert.NoError(t, err)
+	return selectors
+}
+
+func TestSelectors(t *testing.T) {
+	app := newTestContainer("1", "tenant-a", "api-7f9c", "app")
+	sidecar := newTestContainer("2", "tenant-b", "api-5d8f", "istio-proxy")
+	system := newTestContainer("3", "kube-system", "coredns-1", "coredns")
+	loggo := newTestContainer("4", "logging", "loggo-x2v", "loggo")
+	pod := &k8s.Pod{
+		Labels:      map[string]string{"team": "search"},
+		Annotations: map[string]string{"example.com/logs": "debug"},
+	}
+
+	selectors := testSelectors(t, SelectorsRecords{})
+	assert.True(t, selectors.Selected(app, nil))
+	assert.False(t, selectors.Selected(loggo, nil))
+	assert.False(t, selectors.UsesPods())
+
+	selectors = testSelectors(t, SelectorsRecords{Exclude: []SelectorRecord{
+		{Namespace: "kube-system"},
+		{Namespace: `~tenant-[b-z]`, Container: "istio-*"},
+	}})
+	assert.True(t, selectors.Selected(app, nil))
+	assert.False(t, selectors.Selected(sidecar, nil))
+	assert.False(t, selectors.Selected(system, nil))
+	// loggo containers are excluded along with the selectors from the file
+	assert.False(t, selectors.Selected(loggo, nil))
+
+	// regular expressions match the whole value
+	selectors = testSelectors(t, SelectorsRecords{Include: []SelectorRecord{{Pod: `~api|web`}}})
+	assert.False(t, selectors.Selected(app, nil))
+	selectors = testSelectors(t, SelectorsRecords{Include: []SelectorRecord{{Pod: `~api-.*|web-.*`}}})
+	assert.True(t, selectors.Selected(app, nil))
+
+	selectors = testSelectors(t, SelectorsRecords{Include: []SelectorRecord{
+		{Pod: "api-*", Labels: map[string]string{"team": "search"}},
+		{Annotations: map[string]string{"example.com/logs": ""}, Namespace: "kube-*"},
+	}})
+	assert.True(t, selectors.UsesPods())
+	assert.True(t, selectors.Selected(app, pod))
+	assert.False(t, selectors.Selected(app, nil))
+	assert.False(t, selectors.Selected(app, &k8s.Pod{Labels: map[string]string{"team": "maps"}}))
+	assert.True(t, selectors.Selected(system, pod))
+	assert.False(t, selectors.Selected(loggo, pod))
+
+	for _, records := range []SelectorsRecords{
+		{Include: []SelectorRecord{{Namespace: "~("}}},
+		{Exclude: []SelectorRecord{{Pod: "api-["}}},
+		{Exclude: []SelectorRecord{{Labels: map[string]string{"team": "~+"}}}},
+	} {
+		_, err := NewSelectors(records)
+		assert.Error(t, err)
+	}
+}
+
+func TestLoadSelectorsRecords(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "selectors.yaml")
+	assert.NoError(t, ioutil.WriteFile(path, []byte(`
+include:
+  - namespace: "tenant-*"
+exclude:
+  - container: "*loggo*"
+  - labels:
+      logging: disabled
+`), 0600))
+
+	records, err := LoadSelectorsRecords(path)
+	assert.NoError(t, err)
+	assert.Equal(t, SelectorsRecords{
+		Include: []SelectorRecord{{Namespace: "tenant-*"}},
+		Exclude: []SelectorRecord{{Container: "*loggo*"}, {Labels: map[string]string{"logging": "disabled"}}},
+	}, records)
+
+	assert.NoError(t, ioutil.WriteFile(path, []byte("exclude:\n  - nmespace: kube-system\n"), 0600))
+	_, err = LoadSelectorsRecords(path)
+	assert.Error(t, err)
+}
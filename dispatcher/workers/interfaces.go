@@ -4,6 +4,8 @@ import (
 	"context"
 
 	"github.com/2gis/loggo/common"
+	"github.com/2gis/loggo/components/k8s"
+	"github.com/2gis/loggo/components/watcher"
 	"github.com/2gis/loggo/configuration"
 	"github.com/2gis/loggo/logging"
 	"github.com/2gis/loggo/readers"
@@ -18,7 +20,8 @@ type Rater interface {
 type MetricsCollector interface {
 	IncrementLogMessageCount(namespace, podName, containerName string)
 	IncrementThrottlingDelay(namespace, podName, containerName string, value float64)
-	DeleteThrottlingDelay(namespace, podName, containerName string) bool
+	IncrementTruncationsCount(namespace, podName, containerName string)
+	DeleteContainerSeries(namespace, podName, containerName string) bool
 }
 
 // Storage is the cursor storage interface for dispatcher
@@ -29,6 +32,11 @@ type Storage interface {
 	Keys() ([]string, error)
 }
 
+// FileWatcher is a file changes notifier interface for workers
+type FileWatcher interface {
+	WatchFile(path string) (*watcher.Subscription, error)
+}
+
 // JournaldReader is specific journal reader interface
 type JournaldReader interface {
 	EntryRead() (entryMap common.EntryMap, err error)
@@ -61,7 +69,8 @@ type FollowerJournald interface {
 // FollowerFabric is a follower fabric interface for dispatcher
 type FollowerFabric interface {
 	NewFollower(
-		output chan<- *common.Entry, filePath, format string, extends common.EntryMap) (Follower, error)
+		output chan<- *common.Entry, filePath, format string, extends common.EntryMap,
+		settings *k8s.ParsingSettings) (Follower, error)
 	NewFollowerJournald(
-		output chan<- string, config configuration.ParserConfig, logger logging.Logger) (FollowerJournald, error)
+		output chan<- common.EntryMap, config configuration.ParserConfig, logger logging.Logger) (FollowerJournald, error)
 }
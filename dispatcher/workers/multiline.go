@@ -0,0 +1,94 @@
+package workers
+
+import (
+	"regexp"
+
+	"github.com/2gis/loggo/parsers"
+)
+
+// multilineLinesMax limits the count of lines joined into the single record
+const multilineLinesMax = 1000
+
+// multilineBuffer joins consecutive lines into records; record starts with the line matching the pattern
+type multilineBuffer struct {
+	format  string
+	pattern *regexp.Regexp
+	lines   [][]byte
+}
+
+func newMultilineBuffer(format string, pattern *regexp.Regexp) *multilineBuffer {
+	return &multilineBuffer{
+		format:  format,
+		pattern: pattern,
+	}
+}
+
+// Push adds line to the buffer, returns records completed by the line
+func (buffer *multilineBuffer) Push(line []byte) [][]byte {
+	content, err := parsers.LineContent(buffer.format, line)
+
+	// lines that can't be parsed or start the new record complete the previous one
+	if err != nil || buffer.pattern.MatchString(content) || len(buffer.lines) >= multilineLinesMax {
+		records := buffer.Flush()
+		buffer.lines = append(buffer.lines, line)
+		return records
+	}
+
+	buffer.lines = append(buffer.lines, line)
+	return nil
+}
+
+// Flush returns buffered lines as the records and resets the buffer
+func (buffer *multilineBuffer) Flush() [][]byte {
+	if len(buffer.lines) == 0 {
+		return nil
+	}
+
+	lines := buffer.lines
+	buffer.lines = nil
+	merged, err := parsers.MergeLines(buffer.format, lines)
+
+	if err != nil {
+		return lines
+	}
+
+	return [][]byte{merged}
+}
+
+// partialBuffer joins partial lines of CRI runtimes with the full line completing them
+type partialBuffer struct {
+	format string
+	lines  [][]byte
+}
+
+func newPartialBuffer(format string) *partialBuffer {
+	return &partialBuffer{format: format}
+}
+
+// Push adds line to the buffer, returns lines completed by the line
+func (buffer *partialBuffer) Push(line []byte) [][]byte {
+	buffer.lines = append(buffer.lines, line)
+
+	if parsers.PartialLine(buffer.format, line) && len(buffer.lines) < multilineLinesMax {
+		return nil
+	}
+
+	return buffer.Flush()
+}
+
+// Flush returns buffered lines joined into the single line and resets the buffer
+func (buffer *partialBuffer) Flush() [][]byte {
+	if len(buffer.lines) == 0 {
+		return nil
+	}
+
+	lines := buffer.lines
+	buffer.lines = nil
+	joined, err := parsers.JoinPartialLines(buffer.format, lines)
+
+	if err != nil {
+		return lines
+	}
+
+	return [][]byte{joined}
+}
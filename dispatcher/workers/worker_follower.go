@@ -2,13 +2,16 @@ package workers
 
 import (
 	"context"
+	"regexp"
 	"sync"
 	"time"
 
 	"golang.org/x/time/rate"
 
 	"github.com/2gis/loggo/common"
+	"github.com/2gis/loggo/components/watcher"
 	"github.com/2gis/loggo/logging"
+	"github.com/2gis/loggo/readers"
 )
 
 // workerFollower is an entity which collects log entries obtained by reader and sends them to Out channel
@@ -28,11 +31,17 @@ type workerFollower struct {
 	reader           LineReader
 	metricsCollector MetricsCollector
 	extends          common.EntryMap
+	userLogParser    common.UserLogParser
+	multiline        *multilineBuffer
+	partial          *partialBuffer
+	subscription     *watcher.Subscription
 
 	tickerCursorCommit  *time.Ticker
 	tickerLimiterUpdate *time.Ticker
 
-	sleepNoRecords time.Duration
+	sleepNoRecords        time.Duration
+	multilineFlushTimeout time.Duration
+	lastRead              time.Time
 
 	rater           Rater
 	readRateCurrent float64
@@ -40,16 +49,26 @@ type workerFollower struct {
 
 	output chan<- *common.Entry
 	stop   chan struct{}
+	wakeup chan struct{}
 }
 
-// NewFollower is a workerFollower constructor
+// NewFollower is a workerFollower constructor; follower waits for subscription wakeups on EOF, but no longer than
+// sleepNoRecordsIntervalSec, subscription may be nil; buffered records are sent once nothing has been read
+// for multilineFlushTimeoutMs
 func newFollower(output chan<- *common.Entry, filePath, format string,
 	reader LineReader, collector MetricsCollector, storage Storage, rater Rater, extends common.EntryMap,
-	sleepNoRecordsIntervalSec, commitIntervalSec, limitUpdateInterval int, logger logging.Logger) *workerFollower {
+	userLogParser common.UserLogParser, multilinePattern *regexp.Regexp, subscription *watcher.Subscription,
+	sleepNoRecordsIntervalSec, commitIntervalSec, limitUpdateInterval, multilineFlushTimeoutMs int,
+	logger logging.Logger) *workerFollower {
 	podName := extends.PodName()
 	containerName := extends.ContainerName()
 	namespace := extends.NamespaceName()
 
+	// host files don't belong to containers, their series are told apart by path
+	if format == common.FormatFile {
+		containerName = filePath
+	}
+
 	worker := &workerFollower{
 		worker: worker{
 			wg:     &sync.WaitGroup{},
@@ -67,15 +86,27 @@ func newFollower(output chan<- *common.Entry, filePath, format string,
 		metricsCollector: collector,
 		rater:            rater,
 		extends:          extends,
+		userLogParser:    userLogParser,
+		subscription:     subscription,
 
-		sleepNoRecords:      time.Duration(sleepNoRecordsIntervalSec) * time.Second,
-		tickerCursorCommit:  time.NewTicker(time.Duration(commitIntervalSec) * time.Second),
-		tickerLimiterUpdate: time.NewTicker(time.Duration(limitUpdateInterval) * time.Second),
+		sleepNoRecords:        time.Duration(sleepNoRecordsIntervalSec) * time.Second,
+		multilineFlushTimeout: time.Duration(multilineFlushTimeoutMs) * time.Millisecond,
+		tickerCursorCommit:    time.NewTicker(time.Duration(commitIntervalSec) * time.Second),
+		tickerLimiterUpdate:   time.NewTicker(time.Duration(limitUpdateInterval) * time.Second),
 
 		output:     output,
 		stop:       make(chan struct{}),
+		wakeup:     make(chan struct{}, 1),
 		activeFlag: true,
 	}
+	if multilinePattern != nil {
+		worker.multiline = newMultilineBuffer(format, multilinePattern)
+	}
+
+	if format == common.CRITypeContainerD || format == common.CRITypeCRIO {
+		worker.partial = newPartialBuffer(format)
+	}
+
 	worker.setRate(rater.Rate(namespace, podName))
 	return worker
 }
@@ -124,6 +155,11 @@ func (worker *workerFollower) GetActiveFlag() bool {
 // SetEOFShutdownFlag may be used to signal worker to shutdown after first EOF
 func (worker *workerFollower) SetEOFShutdownFlag() {
 	worker.EOFShutdownFlag = true
+
+	select {
+	case worker.wakeup <- struct{}{}:
+	default:
+	}
 }
 
 func (worker *workerFollower) startReader(ctx context.Context) {
@@ -160,7 +196,7 @@ func (worker *workerFollower) startReader(ctx context.Context) {
 					return
 				}
 
-				time.Sleep(worker.sleepNoRecords)
+				worker.wait(ctx)
 
 			default:
 				worker.logger.WithError(err).Infof(
@@ -179,17 +215,59 @@ func (worker *workerFollower) startReader(ctx context.Context) {
 	}
 }
 
+// wait blocks until the file changes, shutdown on EOF is requested or no records sleep interval passes;
+// buffered record is waited for no longer than its flush timeout
+func (worker *workerFollower) wait(ctx context.Context) {
+	timeout := worker.sleepNoRecords
+
+	if worker.buffered() {
+		if remaining := worker.multilineFlushTimeout - time.Since(worker.lastRead); remaining < timeout {
+			timeout = remaining
+		}
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case <-worker.subscription.C():
+	case <-worker.wakeup:
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+}
+
 func (worker *workerFollower) entryProceed() error {
 	entry, prefixFlag, err := worker.reader.EntryRead()
 
+	if err == readers.ErrTruncated {
+		worker.truncated()
+		return nil
+	}
+
+	if err == readers.ErrRotatedPositionUnknown {
+		worker.logger.Warnf(
+			"position in rotated files of '%s' is unknown, reading the latest compressed one from the beginning",
+			worker.filePath,
+		)
+		return nil
+	}
+
 	if err != nil {
 		return err
 	}
 
 	if entry == nil {
+		// continuation of buffered record may be not written yet, so record waits for it a while
+		if time.Since(worker.lastRead) >= worker.multilineFlushTimeout {
+			worker.flush()
+		}
+
 		return ErrNoRecords
 	}
 
+	worker.lastRead = time.Now()
+
 	if prefixFlag {
 		worker.logger.Warnf(
 			"Reader for '%s' has encountered the long string "+
@@ -200,15 +278,70 @@ func (worker *workerFollower) entryProceed() error {
 		)
 	}
 
-	worker.output <- &common.Entry{Origin: entry, Format: worker.format, Extends: worker.extends}
-	worker.metricsCollector.IncrementLogMessageCount(
-		worker.namespace,
-		worker.podName,
-		worker.containerName,
-	)
+	if worker.partial == nil {
+		worker.proceed(entry)
+		return nil
+	}
+
+	for _, line := range worker.partial.Push(entry) {
+		worker.proceed(line)
+	}
+
 	return nil
 }
 
+// proceed sends the full line, multiline records are sent once completed
+func (worker *workerFollower) proceed(line []byte) {
+	if worker.multiline != nil {
+		worker.send(worker.multiline.Push(line)...)
+		return
+	}
+
+	worker.send(line)
+}
+
+// flush sends buffered records, there will be no continuation for them
+func (worker *workerFollower) flush() {
+	if worker.partial != nil {
+		for _, line := range worker.partial.Flush() {
+			worker.proceed(line)
+		}
+	}
+
+	if worker.multiline != nil {
+		worker.send(worker.multiline.Flush()...)
+	}
+}
+
+// buffered checks whether there are lines waiting for continuation
+func (worker *workerFollower) buffered() bool {
+	return (worker.partial != nil && len(worker.partial.lines) > 0) ||
+		(worker.multiline != nil && len(worker.multiline.lines) > 0)
+}
+
+// truncated accounts in place truncation of the file; buffered record belongs to the content before truncation
+func (worker *workerFollower) truncated() {
+	worker.logger.Warnf("file '%s' has been truncated, reading from the beginning", worker.filePath)
+	worker.metricsCollector.IncrementTruncationsCount(worker.namespace, worker.podName, worker.containerName)
+	worker.flush()
+}
+
+func (worker *workerFollower) send(entries ...[]byte) {
+	for _, entry := range entries {
+		worker.output <- &common.Entry{
+			Origin:        entry,
+			Format:        worker.format,
+			Extends:       worker.extends,
+			UserLogParser: worker.userLogParser,
+		}
+		worker.metricsCollector.IncrementLogMessageCount(
+			worker.namespace,
+			worker.podName,
+			worker.containerName,
+		)
+	}
+}
+
 func (worker *workerFollower) startCursorCommitter(ctx context.Context) {
 	for {
 		select {
@@ -252,6 +385,9 @@ func (worker *workerFollower) setRate(readRate float64) {
 }
 
 func (worker *workerFollower) finalize() {
+	// cursor is committed past buffered lines, so they would be lost otherwise
+	worker.flush()
+
 	if worker.reader.GetAcquireFlag() {
 		worker.commitCursor()
 	}
@@ -260,7 +396,9 @@ func (worker *workerFollower) finalize() {
 		worker.logger.Warnf("worker on '%s' failed closing its reader", worker.filePath)
 	}
 
-	if !worker.metricsCollector.DeleteThrottlingDelay(
+	worker.subscription.Close()
+
+	if !worker.metricsCollector.DeleteContainerSeries(
 		worker.namespace,
 		worker.podName,
 		worker.containerName,
@@ -2,13 +2,19 @@ package workers
 
 import (
 	"context"
+	"io/ioutil"
 	"os"
+	"path/filepath"
+	"regexp"
 	"sync"
+	"syscall"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 
 	"github.com/2gis/loggo/components/rates"
+	"github.com/2gis/loggo/components/watcher"
 	"github.com/2gis/loggo/tests/mocks"
 
 	"github.com/2gis/loggo/common"
@@ -18,18 +24,20 @@ import (
 )
 
 const (
-	FilePathTemp           = "/tmp/test_line_reader"
-	FilePathTempRegistry   = "/tmp/test_storage"
+	FilePathTemp           = "/tmp/test_follower"
+	FilePathTempRegistry   = "/tmp/test_follower_storage"
 	FormatTest             = "test_format"
 	readRate               = 1000
 	commitInterval         = 1
 	limiterUpdateInterval  = 1
 	sleepNoRecordsInterval = 1
+	multilineFlushTimeout  = 100
 )
 
 func TestFollower_Positive(t *testing.T) {
 	output := make(chan *common.Entry)
-	ctx, _ := context.WithCancel(context.Background())
+	ctx, stop := context.WithCancel(context.Background())
+	defer stop()
 	cursorStorage, err := storage.NewStorage(FilePathTempRegistry, 10)
 	assert.NoError(t, err)
 
@@ -54,9 +62,13 @@ func TestFollower_Positive(t *testing.T) {
 		cursorStorage,
 		rater,
 		extends,
+		nil,
+		nil,
+		nil,
 		sleepNoRecordsInterval,
 		commitInterval,
 		limiterUpdateInterval,
+		multilineFlushTimeout,
 		logging.NewLoggerDefault(),
 	)
 	wg.Add(1)
@@ -95,9 +107,13 @@ func TestFollowerBase_ShutdownOnFileMissing(t *testing.T) {
 		cursorStorage,
 		rater,
 		common.EntryMap{},
+		nil,
+		nil,
+		nil,
 		sleepNoRecordsInterval,
 		commitInterval,
 		limiterUpdateInterval,
+		multilineFlushTimeout,
 		logging.NewLoggerDefault(),
 	)
 	wg.Add(1)
@@ -134,9 +150,13 @@ func TestFollowerBase_CommitCursorOnStop(t *testing.T) {
 		cursorStorage,
 		rater,
 		common.EntryMap{},
+		nil,
+		nil,
+		nil,
 		sleepNoRecordsInterval,
 		commitInterval,
 		limiterUpdateInterval,
+		multilineFlushTimeout,
 		logging.NewLoggerDefault(),
 	)
 	wg.Add(1)
@@ -174,9 +194,13 @@ func TestFollowerBase_CommitCursorOnStop(t *testing.T) {
 		cursorStorage,
 		rater,
 		common.EntryMap{},
+		nil,
+		nil,
+		nil,
 		sleepNoRecordsInterval,
 		commitInterval,
 		limiterUpdateInterval,
+		multilineFlushTimeout,
 		logging.NewLoggerDefault(),
 	)
 	wg.Add(1)
@@ -196,3 +220,416 @@ func TestFollowerBase_CommitCursorOnStop(t *testing.T) {
 	assert.Equal(t, "", value)
 	_ = os.Remove(FilePathTempRegistry)
 }
+
+func TestFollower_Multiline(t *testing.T) {
+	output := make(chan *common.Entry)
+	ctx, stop := context.WithCancel(context.Background())
+	defer stop()
+	cursorStorage, err := storage.NewStorage(FilePathTempRegistry, 10)
+	assert.NoError(t, err)
+
+	lines := [][]byte{
+		[]byte(`2020-09-10T07:00:03.585507743Z stderr F Exception in thread "main"`),
+		[]byte(`2020-09-10T07:00:03.585507744Z stderr F 	at Main.main(Main.java:5)`),
+		[]byte(`2020-09-10T07:00:04.585507743Z stdout F Started`),
+	}
+
+	reader := mocks.NewLineReaderMock(lines...)
+	wg := &sync.WaitGroup{}
+	rater, err := rates.NewRater(rates.NewRuleRecordsProviderStub(), readRate)
+	assert.NoError(t, err)
+
+	follower := newFollower(
+		output,
+		FilePathTemp,
+		common.CRITypeContainerD,
+		reader,
+		mocks.NewCollectorMock(),
+		cursorStorage,
+		rater,
+		common.EntryMap{},
+		nil,
+		regexp.MustCompile(`^\S`),
+		nil,
+		sleepNoRecordsInterval,
+		commitInterval,
+		limiterUpdateInterval,
+		multilineFlushTimeout,
+		logging.NewLoggerDefault(),
+	)
+	wg.Add(1)
+
+	go func() {
+		follower.Start(ctx)
+		wg.Done()
+	}()
+
+	assert.Equal(
+		t,
+		"2020-09-10T07:00:03.585507743Z stderr F Exception in thread \"main\"\n\tat Main.main(Main.java:5)",
+		string((<-output).Origin),
+	)
+	// the last record is flushed when there are no more lines for the flush timeout
+	assert.Equal(t, string(lines[2]), string((<-output).Origin))
+
+	follower.Stop()
+	wg.Wait()
+	close(output)
+	_ = os.Remove(FilePathTempRegistry)
+}
+
+func TestFollower_MultilineStop(t *testing.T) {
+	output := make(chan *common.Entry)
+	ctx, stop := context.WithCancel(context.Background())
+	defer stop()
+	cursorStorage, err := storage.NewStorage(filepath.Join(t.TempDir(), "storage"), 10)
+	assert.NoError(t, err)
+
+	lines := [][]byte{
+		[]byte(`2020-09-10T07:00:03.585507743Z stdout F Started`),
+		[]byte(`2020-09-10T07:00:04.585507743Z stderr F Exception in thread "main"`),
+		[]byte(`2020-09-10T07:00:04.585507744Z stderr F 	at Main.main(Main.java:5)`),
+	}
+
+	rater, err := rates.NewRater(rates.NewRuleRecordsProviderStub(), readRate)
+	assert.NoError(t, err)
+
+	follower := newFollower(
+		output,
+		filepath.Join(t.TempDir(), "0.log"),
+		common.CRITypeContainerD,
+		mocks.NewLineReaderMock(lines...),
+		mocks.NewCollectorMock(),
+		cursorStorage,
+		rater,
+		common.EntryMap{},
+		nil,
+		regexp.MustCompile(`^\S`),
+		nil,
+		sleepNoRecordsInterval,
+		commitInterval,
+		limiterUpdateInterval,
+		60000,
+		logging.NewLoggerDefault(),
+	)
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+
+	go func() {
+		follower.Start(ctx)
+		wg.Done()
+	}()
+
+	assert.Equal(t, string(lines[0]), string((<-output).Origin))
+
+	// the last record waits for continuation
+	select {
+	case entry := <-output:
+		assert.Fail(t, "record has been flushed before the flush timeout", string(entry.Origin))
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	// and is flushed before the cursor is committed past it on stop
+	follower.Stop()
+	assert.Equal(
+		t,
+		"2020-09-10T07:00:04.585507743Z stderr F Exception in thread \"main\"\n\tat Main.main(Main.java:5)",
+		string((<-output).Origin),
+	)
+	wg.Wait()
+}
+
+func TestFollower_PartialLines(t *testing.T) {
+	output := make(chan *common.Entry)
+	ctx, stop := context.WithCancel(context.Background())
+	defer stop()
+	cursorStorage, err := storage.NewStorage(filepath.Join(t.TempDir(), "storage"), 10)
+	assert.NoError(t, err)
+
+	lines := [][]byte{
+		[]byte(`2020-09-10T07:00:03.585507743Z stdout P {"msg":`),
+		[]byte(`2020-09-10T07:00:03.585507744Z stdout F "hello"}`),
+		[]byte(`2020-09-10T07:00:04.585507743Z stdout F {"msg":"world"}`),
+		[]byte(`2020-09-10T07:00:05.585507743Z stdout P {"msg":`),
+	}
+
+	rater, err := rates.NewRater(rates.NewRuleRecordsProviderStub(), readRate)
+	assert.NoError(t, err)
+
+	follower := newFollower(
+		output,
+		filepath.Join(t.TempDir(), "0.log"),
+		common.CRITypeContainerD,
+		mocks.NewLineReaderMock(lines...),
+		mocks.NewCollectorMock(),
+		cursorStorage,
+		rater,
+		common.EntryMap{},
+		nil,
+		nil,
+		nil,
+		sleepNoRecordsInterval,
+		commitInterval,
+		limiterUpdateInterval,
+		multilineFlushTimeout,
+		logging.NewLoggerDefault(),
+	)
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+
+	go func() {
+		follower.Start(ctx)
+		wg.Done()
+	}()
+
+	assert.Equal(t, `2020-09-10T07:00:03.585507743Z stdout F {"msg":"hello"}`, string((<-output).Origin))
+	assert.Equal(t, string(lines[2]), string((<-output).Origin))
+	// partial line which is never completed is flushed as is
+	assert.Equal(t, string(lines[3]), string((<-output).Origin))
+
+	follower.Stop()
+	wg.Wait()
+}
+
+func TestFollower_WatchedFile(t *testing.T) {
+	output := make(chan *common.Entry)
+	ctx, stop := context.WithCancel(context.Background())
+	defer stop()
+	cursorStorage, err := storage.NewStorage(filepath.Join(t.TempDir(), "storage"), 10)
+	assert.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "0.log")
+	assert.NoError(t, ioutil.WriteFile(path, []byte("value_0\n"), 0644))
+	reader, err := readers.NewLineReader(path, 1024, &readers.Cursor{}, false, false)
+	assert.NoError(t, err)
+
+	fsWatcher, err := watcher.NewWatcher(logging.NewLoggerDefault())
+	assert.NoError(t, err)
+	go fsWatcher.Start(ctx)
+	subscription, err := fsWatcher.WatchFile(path)
+	assert.NoError(t, err)
+
+	rater, err := rates.NewRater(rates.NewRuleRecordsProviderStub(), readRate)
+	assert.NoError(t, err)
+
+	// the interval is long enough for the test to fail if the follower isn't woken up
+	follower := newFollower(
+		output,
+		path,
+		FormatTest,
+		reader,
+		mocks.NewCollectorMock(),
+		cursorStorage,
+		rater,
+		common.EntryMap{},
+		nil,
+		nil,
+		subscription,
+		60,
+		commitInterval,
+		limiterUpdateInterval,
+		multilineFlushTimeout,
+		logging.NewLoggerDefault(),
+	)
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+
+	go func() {
+		follower.Start(ctx)
+		wg.Done()
+	}()
+
+	receive := func() string {
+		select {
+		case entry := <-output:
+			return string(entry.Origin)
+		case <-time.After(5 * time.Second):
+			assert.Fail(t, "entry hasn't been read")
+			return ""
+		}
+	}
+
+	assert.Equal(t, "value_0", receive())
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	assert.NoError(t, err)
+	_, err = file.WriteString("value_1\n")
+	assert.NoError(t, err)
+	assert.NoError(t, file.Close())
+	assert.Equal(t, "value_1", receive())
+
+	// stopped container is read to the end without waiting for the interval
+	follower.SetEOFShutdownFlag()
+	done := make(chan struct{})
+
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		assert.Fail(t, "follower hasn't been stopped")
+	}
+
+	assert.False(t, follower.GetActiveFlag())
+}
+
+type truncationsCollector struct {
+	mocks.CollectorMock
+	truncations chan string
+}
+
+func (c *truncationsCollector) IncrementTruncationsCount(namespace, _, _ string) {
+	c.truncations <- namespace
+}
+
+func TestFollower_Truncation(t *testing.T) {
+	output := make(chan *common.Entry)
+	ctx, stop := context.WithCancel(context.Background())
+	defer stop()
+	cursorStorage, err := storage.NewStorage(filepath.Join(t.TempDir(), "storage"), 10)
+	assert.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "0.log")
+	assert.NoError(t, ioutil.WriteFile(path, []byte("value_0\nvalue_1\n"), 0644))
+	reader, err := readers.NewLineReader(path, 1024, &readers.Cursor{}, false, false)
+	assert.NoError(t, err)
+
+	rater, err := rates.NewRater(rates.NewRuleRecordsProviderStub(), readRate)
+	assert.NoError(t, err)
+	collector := &truncationsCollector{truncations: make(chan string, 1)}
+
+	follower := newFollower(
+		output,
+		path,
+		FormatTest,
+		reader,
+		collector,
+		cursorStorage,
+		rater,
+		common.EntryMap{common.KubernetesNamespaceName: "prod"},
+		nil,
+		nil,
+		nil,
+		sleepNoRecordsInterval,
+		commitInterval,
+		limiterUpdateInterval,
+		multilineFlushTimeout,
+		logging.NewLoggerDefault(),
+	)
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+
+	go func() {
+		follower.Start(ctx)
+		wg.Done()
+	}()
+
+	assert.Equal(t, "value_0", string((<-output).Origin))
+	assert.Equal(t, "value_1", string((<-output).Origin))
+
+	// copytruncate
+	file, err := os.OpenFile(path, os.O_TRUNC|os.O_WRONLY, 0644)
+	assert.NoError(t, err)
+	_, err = file.WriteString("value_2\n")
+	assert.NoError(t, err)
+	assert.NoError(t, file.Close())
+
+	assert.Equal(t, "prod", <-collector.truncations)
+	assert.Equal(t, "value_2", string((<-output).Origin))
+
+	follower.Stop()
+	wg.Wait()
+}
+
+func TestFollower_TruncationOnStart(t *testing.T) {
+	output := make(chan *common.Entry)
+	ctx, stop := context.WithCancel(context.Background())
+	defer stop()
+	cursorStorage, err := storage.NewStorage(filepath.Join(t.TempDir(), "storage"), 10)
+	assert.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "0.log")
+	assert.NoError(t, ioutil.WriteFile(path, []byte("value_0\n"), 0644))
+	stat, err := os.Stat(path)
+	assert.NoError(t, err)
+
+	statInfo := stat.Sys().(*syscall.Stat_t)
+
+	// the file has been truncated while the cursor was stored
+	cursor := &readers.Cursor{Inode: statInfo.Ino, Device: uint64(statInfo.Dev), Value: 100}
+	reader, err := readers.NewLineReader(path, 1024, cursor, false, false)
+	assert.NoError(t, err)
+
+	rater, err := rates.NewRater(rates.NewRuleRecordsProviderStub(), readRate)
+	assert.NoError(t, err)
+	collector := &truncationsCollector{truncations: make(chan string, 1)}
+
+	follower := newFollower(
+		output,
+		path,
+		FormatTest,
+		reader,
+		collector,
+		cursorStorage,
+		rater,
+		common.EntryMap{common.KubernetesNamespaceName: "prod"},
+		nil,
+		nil,
+		nil,
+		sleepNoRecordsInterval,
+		commitInterval,
+		limiterUpdateInterval,
+		multilineFlushTimeout,
+		logging.NewLoggerDefault(),
+	)
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+
+	go func() {
+		follower.Start(ctx)
+		wg.Done()
+	}()
+
+	assert.Equal(t, "prod", <-collector.truncations)
+	assert.Equal(t, "value_0", string((<-output).Origin))
+
+	follower.Stop()
+	wg.Wait()
+}
+
+func TestFollower_FileSeries(t *testing.T) {
+	cursorStorage, err := storage.NewStorage(filepath.Join(t.TempDir(), "storage"), 10)
+	assert.NoError(t, err)
+	rater, err := rates.NewRater(rates.NewRuleRecordsProviderStub(), readRate)
+	assert.NoError(t, err)
+
+	newFileFollower := func(path string) *workerFollower {
+		return newFollower(
+			make(chan *common.Entry),
+			path,
+			common.FormatFile,
+			mocks.NewLineReaderMock(),
+			mocks.NewCollectorMock(),
+			cursorStorage,
+			rater,
+			common.EntryMap{common.LabelFilePath: path},
+			nil,
+			nil,
+			nil,
+			sleepNoRecordsInterval,
+			commitInterval,
+			limiterUpdateInterval,
+			multilineFlushTimeout,
+			logging.NewLoggerDefault(),
+		)
+	}
+
+	// followers of host files must not share series, each of them deletes its own ones on stop
+	audit := newFileFollower("/var/log/audit/audit.log")
+	syslog := newFileFollower("/var/log/syslog")
+	assert.Equal(t, "/var/log/audit/audit.log", audit.containerName)
+	assert.Equal(t, "/var/log/syslog", syslog.containerName)
+}
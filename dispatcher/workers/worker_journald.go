@@ -2,7 +2,6 @@ package workers
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"strconv"
 	"sync"
@@ -26,11 +25,11 @@ type workerJournald struct {
 	sleepNoRecords     time.Duration
 	cursorCommitTicker *time.Ticker
 
-	output chan<- string
+	output chan<- common.EntryMap
 }
 
 // newFollowerJournald constructor
-func newFollowerJournald(output chan<- string, reader JournaldReader, config configuration.ParserConfig,
+func newFollowerJournald(output chan<- common.EntryMap, reader JournaldReader, config configuration.ParserConfig,
 	extends common.EntryMap, cursorStorage Storage,
 	commitIntervalSec, readTimeout int, logger logging.Logger) *workerJournald {
 	return &workerJournald{
@@ -132,19 +131,17 @@ func (worker *workerJournald) entryProceed() error {
 	}
 
 	if worker.config.ExtendsFieldsKey != "" {
-		result[worker.config.ExtendsFieldsKey] = worker.extends
+		// entries are processed in place, so they don't share extends
+		extends := common.EntryMap{}
+		extends.Extend(worker.extends)
+		result[worker.config.ExtendsFieldsKey] = extends
 	} else {
 		result.Extend(worker.extends)
 	}
 
-	result[common.LabelTime] = time.Unix(0, usec*int64(time.Microsecond)).Format(time.RFC3339)
-	entryByteString, err := json.Marshal(result)
-
-	if err != nil {
-		return fmt.Errorf("journald: error marshalling entry %v", result)
-	}
-
-	worker.output <- string(entryByteString)
+	// precision is kept for timestamp normalization
+	result[common.LabelTime] = time.Unix(0, usec*int64(time.Microsecond)).UTC().Format(time.RFC3339Nano)
+	worker.output <- result
 	return nil
 }
 
@@ -0,0 +1,60 @@
+package workers
+
+import (
+	"context"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/2gis/loggo/common"
+	"github.com/2gis/loggo/configuration"
+	"github.com/2gis/loggo/logging"
+	"github.com/2gis/loggo/storage"
+	"github.com/2gis/loggo/tests/mocks"
+)
+
+func TestFollowerJournald(t *testing.T) {
+	output := make(chan common.EntryMap)
+	ctx, stop := context.WithCancel(context.Background())
+	defer stop()
+	cursorStorage, err := storage.NewStorage(filepath.Join(t.TempDir(), "storage"), 10)
+	assert.NoError(t, err)
+
+	reader := mocks.NewReaderJournaldMock(
+		common.EntryMap{"time": "1599721203585507", "MESSAGE": "hello", "_SYSTEMD_UNIT": "kubelet.service"},
+		common.EntryMap{"time": "1599721204000000", "MESSAGE": "world", "_SYSTEMD_UNIT": "kubelet.service"},
+	)
+	follower := newFollowerJournald(
+		output,
+		reader,
+		configuration.ParserConfig{UserLogFieldsKey: "log", ExtendsFieldsKey: "extends"},
+		common.EntryMap{common.KubernetesNamespaceName: common.NamespaceJournald},
+		cursorStorage,
+		commitInterval,
+		sleepNoRecordsInterval,
+		logging.NewLoggerDefault(),
+	)
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+
+	go func() {
+		follower.Start(ctx)
+		wg.Done()
+	}()
+
+	first := <-output
+	assert.Equal(t, "2020-09-10T07:00:03.585507Z", first["time"])
+	assert.Equal(t, common.EntryMap{"MESSAGE": "hello", "SYSTEMD_UNIT": "kubelet.service"}, first["log"])
+	assert.Equal(t, common.EntryMap{common.KubernetesNamespaceName: common.NamespaceJournald}, first["extends"])
+
+	// entries are processed in place downstream, so they must not share extends
+	first["extends"].(common.EntryMap)[common.KubernetesNamespaceName] = "redacted"
+	second := <-output
+	assert.Equal(t, "2020-09-10T07:00:04Z", second["time"])
+	assert.Equal(t, common.EntryMap{common.KubernetesNamespaceName: common.NamespaceJournald}, second["extends"])
+
+	stop()
+	wg.Wait()
+}
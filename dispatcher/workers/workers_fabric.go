@@ -4,8 +4,11 @@ import (
 	"fmt"
 
 	"github.com/2gis/loggo/common"
+	"github.com/2gis/loggo/components/k8s"
+	"github.com/2gis/loggo/components/watcher"
 	"github.com/2gis/loggo/configuration"
 	"github.com/2gis/loggo/logging"
+	"github.com/2gis/loggo/parsers"
 	"github.com/2gis/loggo/readers"
 )
 
@@ -14,22 +17,36 @@ type FollowersFabric struct {
 	collector MetricsCollector
 	storage   Storage
 	rater     Rater
+	watcher   FileWatcher
 	logger    logging.Logger
 }
 
+// NewFollowersFabric is a FollowersFabric constructor; watcher may be nil, followers poll their files then
 func NewFollowersFabric(config configuration.Config,
-	collector MetricsCollector, storage Storage, rater Rater, logger logging.Logger) *FollowersFabric {
+	collector MetricsCollector, storage Storage, rater Rater, watcher FileWatcher,
+	logger logging.Logger) *FollowersFabric {
 	return &FollowersFabric{
 		config:    config,
 		collector: collector,
 		storage:   storage,
 		rater:     rater,
+		watcher:   watcher,
 		logger:    logger,
 	}
 }
 
 func (f *FollowersFabric) NewFollower(output chan<- *common.Entry, filePath, format string,
-	containerExtends common.EntryMap) (Follower, error) {
+	containerExtends common.EntryMap, settings *k8s.ParsingSettings) (Follower, error) {
+	if settings == nil {
+		settings = &k8s.ParsingSettings{}
+	}
+
+	userLogParser, err := parsers.NewUserLogParser(settings.Parser, settings.Regexp)
+
+	if err != nil {
+		return nil, fmt.Errorf("unable to initiate user log parser for path %s, %s", filePath, err)
+	}
+
 	cursorString, _ := f.storage.Get(filePath)
 	cursor, _ := readers.NewCursorFromString(cursorString)
 	lineReader, err := readers.NewLineReader(
@@ -37,6 +54,7 @@ func (f *FollowersFabric) NewFollower(output chan<- *common.Entry, filePath, for
 		f.config.FollowerConfig.ReaderBufferSize,
 		cursor,
 		f.config.FollowerConfig.FromTailFlag,
+		f.config.FollowerConfig.ReadRotatedFlag,
 	)
 
 	if err != nil {
@@ -46,6 +64,8 @@ func (f *FollowersFabric) NewFollower(output chan<- *common.Entry, filePath, for
 	extends := f.config.K8SExtends.EntryMap()
 	extends.Extend(containerExtends)
 
+	subscription, sleepNoRecordsIntervalSec := f.watch(filePath)
+
 	worker := newFollower(
 		output,
 		filePath,
@@ -55,16 +75,36 @@ func (f *FollowersFabric) NewFollower(output chan<- *common.Entry, filePath, for
 		f.storage,
 		f.rater,
 		extends,
-		f.config.FollowerConfig.NoRecordsSleepIntervalSec,
+		userLogParser,
+		settings.MultilinePattern,
+		subscription,
+		sleepNoRecordsIntervalSec,
 		f.config.FollowerConfig.CursorCommitIntervalSec,
 		f.config.FollowerConfig.ThrottlingLimitsUpdateIntervalSec,
+		f.config.FollowerConfig.MultilineFlushTimeoutMs,
 		f.logger,
 	)
 	return worker, nil
 }
 
+// watch subscribes to the file changes; followers of watched files poll them rarely, in case events are lost
+func (f *FollowersFabric) watch(filePath string) (*watcher.Subscription, int) {
+	if f.watcher == nil {
+		return nil, f.config.FollowerConfig.NoRecordsSleepIntervalSec
+	}
+
+	subscription, err := f.watcher.WatchFile(filePath)
+
+	if err != nil {
+		f.logger.Warnf("unable to watch '%s', falling back to polling: %s", filePath, err)
+		return nil, f.config.FollowerConfig.NoRecordsSleepIntervalSec
+	}
+
+	return subscription, f.config.FollowerConfig.FSNotifyFallbackIntervalSec
+}
+
 // NewFollowerJournald constructor
-func (f *FollowersFabric) NewFollowerJournald(output chan<- string, config configuration.ParserConfig,
+func (f *FollowersFabric) NewFollowerJournald(output chan<- common.EntryMap, config configuration.ParserConfig,
 	logger logging.Logger) (FollowerJournald, error) {
 	journaldPath, err := readers.JournaldPath(
 		f.config.JournaldConfig.MachineIDPath,
@@ -0,0 +1,183 @@
+package expression
+
+import (
+	"regexp"
+	"strconv"
+
+	"github.com/2gis/loggo/common"
+)
+
+func constant(value interface{}) evaluator {
+	return func(_ common.EntryMap) interface{} {
+		return value
+	}
+}
+
+func field(path string) evaluator {
+	return func(entryMap common.EntryMap) interface{} {
+		value, _ := entryMap.Lookup(path)
+		return value
+	}
+}
+
+func or(left, right evaluator) evaluator {
+	return func(entryMap common.EntryMap) interface{} {
+		return truthy(left(entryMap)) || truthy(right(entryMap))
+	}
+}
+
+func and(left, right evaluator) evaluator {
+	return func(entryMap common.EntryMap) interface{} {
+		return truthy(left(entryMap)) && truthy(right(entryMap))
+	}
+}
+
+func match(operand evaluator, pattern *regexp.Regexp, positive bool) evaluator {
+	return func(entryMap common.EntryMap) interface{} {
+		value, ok := operand(entryMap).(string)
+		return ok && pattern.MatchString(value) == positive
+	}
+}
+
+func compare(left, right evaluator, operator string) evaluator {
+	return func(entryMap common.EntryMap) interface{} {
+		a, b := left(entryMap), right(entryMap)
+
+		switch operator {
+		case "==":
+			return equal(a, b)
+		case "!=":
+			return !equal(a, b)
+		}
+
+		result, ok := order(a, b)
+
+		if !ok {
+			return false
+		}
+
+		switch operator {
+		case "<":
+			return result < 0
+		case "<=":
+			return result <= 0
+		case ">":
+			return result > 0
+		}
+
+		return result >= 0
+	}
+}
+
+// equal compares values; numbers are compared to numeric strings as numbers, since user log parsers
+// other than json produce strings only
+func equal(a, b interface{}) bool {
+	if x, y, ok := numbers(a, b); ok {
+		return x == y
+	}
+
+	switch typed := a.(type) {
+	case string:
+		value, ok := b.(string)
+		return ok && typed == value
+	case bool:
+		value, ok := b.(bool)
+		return ok && typed == value
+	case nil:
+		return b == nil
+	}
+
+	return false
+}
+
+// order returns sign of comparison of numbers or strings; false is returned for values of other types
+func order(a, b interface{}) (int, bool) {
+	if x, y, ok := numbers(a, b); ok {
+		switch {
+		case x < y:
+			return -1, true
+		case x > y:
+			return 1, true
+		}
+
+		return 0, true
+	}
+
+	x, okA := a.(string)
+	y, okB := b.(string)
+
+	if !okA || !okB {
+		return 0, false
+	}
+
+	switch {
+	case x < y:
+		return -1, true
+	case x > y:
+		return 1, true
+	}
+
+	return 0, true
+}
+
+// numbers converts values to numbers if at least one of them is a number and the other is a number
+// or numeric string
+func numbers(a, b interface{}) (float64, float64, bool) {
+	x, numberA := number(a)
+	y, numberB := number(b)
+
+	if !numberA && !numberB {
+		return 0, 0, false
+	}
+
+	if !numberA {
+		x, numberA = numericString(a)
+	}
+
+	if !numberB {
+		y, numberB = numericString(b)
+	}
+
+	return x, y, numberA && numberB
+}
+
+func number(value interface{}) (float64, bool) {
+	switch typed := value.(type) {
+	case float64:
+		return typed, true
+	case int:
+		return float64(typed), true
+	case int64:
+		return float64(typed), true
+	}
+
+	return 0, false
+}
+
+func numericString(value interface{}) (float64, bool) {
+	typed, ok := value.(string)
+
+	if !ok {
+		return 0, false
+	}
+
+	result, err := strconv.ParseFloat(typed, 64)
+	return result, err == nil
+}
+
+func truthy(value interface{}) bool {
+	switch typed := value.(type) {
+	case bool:
+		return typed
+	case nil:
+		return false
+	case string:
+		return typed != ""
+	}
+
+	if result, ok := number(value); ok {
+		return result != 0
+	}
+
+	return true
+}
@@ -0,0 +1,251 @@
+package expression
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+
+	"github.com/2gis/loggo/common"
+)
+
+var (
+	ErrSyntax = errors.New("expression syntax error")
+)
+
+type evaluator func(entryMap common.EntryMap) interface{}
+
+// Expression is a compiled expression over EntryMap, safe for concurrent use; identifiers are the paths of entry
+// fields (see common.EntryMap Lookup), missing fields are null. Supported are string, number, true, false and null
+// literals, comparisons (==, !=, <, <=, >, >=), regular expression matching with string literal (=~, !~),
+// logical operators (!, &&, ||), parentheses and exists(path) function
+type Expression struct {
+	source   string
+	evaluate evaluator
+}
+
+// Compile parses expression source
+func Compile(source string) (*Expression, error) {
+	tokens, err := tokenize(source)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens}
+	evaluate, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+
+	if current := p.current(); current.kind != tokenEOF {
+		return nil, fmt.Errorf("%w: unexpected '%s' at %d", ErrSyntax, current.value, current.position)
+	}
+
+	return &Expression{source: source, evaluate: evaluate}, nil
+}
+
+// Evaluate checks if expression result is truthy for the entry
+func (e *Expression) Evaluate(entryMap common.EntryMap) bool {
+	return truthy(e.evaluate(entryMap))
+}
+
+// Value returns expression result for the entry
+func (e *Expression) Value(entryMap common.EntryMap) interface{} {
+	return e.evaluate(entryMap)
+}
+
+// String returns expression source
+func (e *Expression) String() string {
+	return e.source
+}
+
+type parser struct {
+	tokens   []token
+	position int
+}
+
+func (p *parser) current() token {
+	return p.tokens[p.position]
+}
+
+func (p *parser) next() token {
+	current := p.tokens[p.position]
+
+	if current.kind != tokenEOF {
+		p.position++
+	}
+
+	return current
+}
+
+func (p *parser) acceptOperator(operators ...string) (string, bool) {
+	current := p.current()
+
+	if current.kind != tokenOperator {
+		return "", false
+	}
+
+	for _, operator := range operators {
+		if current.value == operator {
+			p.position++
+			return operator, true
+		}
+	}
+
+	return "", false
+}
+
+func (p *parser) parseOr() (evaluator, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		if _, ok := p.acceptOperator("||"); !ok {
+			return left, nil
+		}
+
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+
+		left = or(left, right)
+	}
+}
+
+func (p *parser) parseAnd() (evaluator, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		if _, ok := p.acceptOperator("&&"); !ok {
+			return left, nil
+		}
+
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+
+		left = and(left, right)
+	}
+}
+
+func (p *parser) parseUnary() (evaluator, error) {
+	if _, ok := p.acceptOperator("!"); ok {
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+
+		return func(entryMap common.EntryMap) interface{} {
+			return !truthy(operand(entryMap))
+		}, nil
+	}
+
+	return p.parseComparison()
+}
+
+func (p *parser) parseComparison() (evaluator, error) {
+	left, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+
+	if operator, ok := p.acceptOperator("=~", "!~"); ok {
+		current := p.next()
+
+		if current.kind != tokenString {
+			return nil, fmt.Errorf("%w: string literal expected after '%s' at %d", ErrSyntax, operator,
+				current.position)
+		}
+
+		pattern, err := regexp.Compile(current.value)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid regular expression at %d, %s", ErrSyntax, current.position, err)
+		}
+
+		return match(left, pattern, operator == "=~"), nil
+	}
+
+	operator, ok := p.acceptOperator("==", "!=", "<", "<=", ">", ">=")
+
+	if !ok {
+		return left, nil
+	}
+
+	right, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+
+	return compare(left, right, operator), nil
+}
+
+func (p *parser) parsePrimary() (evaluator, error) {
+	current := p.next()
+
+	switch current.kind {
+	case tokenString:
+		return constant(current.value), nil
+	case tokenNumber:
+		return constant(current.number), nil
+	case tokenParenOpen:
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+
+		if closing := p.next(); closing.kind != tokenParenClose {
+			return nil, fmt.Errorf("%w: ')' expected at %d", ErrSyntax, closing.position)
+		}
+
+		return inner, nil
+	case tokenIdentifier:
+		switch current.value {
+		case "true":
+			return constant(true), nil
+		case "false":
+			return constant(false), nil
+		case "null":
+			return constant(nil), nil
+		}
+
+		if p.current().kind == tokenParenOpen {
+			return p.parseCall(current)
+		}
+
+		return field(current.value), nil
+	case tokenEOF:
+		return nil, fmt.Errorf("%w: unexpected end of expression", ErrSyntax)
+	}
+
+	return nil, fmt.Errorf("%w: unexpected '%s' at %d", ErrSyntax, current.value, current.position)
+}
+
+func (p *parser) parseCall(function token) (evaluator, error) {
+	if function.value != "exists" {
+		return nil, fmt.Errorf("%w: unknown function '%s' at %d", ErrSyntax, function.value, function.position)
+	}
+
+	p.next()
+	argument := p.next()
+
+	if argument.kind != tokenIdentifier {
+		return nil, fmt.Errorf("%w: field path expected at %d", ErrSyntax, argument.position)
+	}
+
+	if closing := p.next(); closing.kind != tokenParenClose {
+		return nil, fmt.Errorf("%w: ')' expected at %d", ErrSyntax, closing.position)
+	}
+
+	path := argument.value
+
+	return func(entryMap common.EntryMap) interface{} {
+		_, ok := entryMap.Lookup(path)
+		return ok
+	}, nil
+}
@@ -0,0 +1,115 @@
+package expression
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/2gis/loggo/common"
+)
+
+var entryMapTest = common.EntryMap{
+	common.KubernetesNamespaceName: "prod",
+	common.KubernetesPodName:       "api-0",
+	"status":                       float64(503),
+	"code":                         "404",
+	"level":                        "error",
+	"sla":                          true,
+	"request": map[string]interface{}{
+		"method": "GET",
+		"time":   0.25,
+	},
+}
+
+func TestExpression_Evaluate(t *testing.T) {
+	testCases := map[string]bool{
+		`kubernetes.namespace_name == "prod" && status >= 500`: true,
+		`kubernetes.namespace_name == 'stage' || status < 500`: false,
+		`status == 503`:                              true,
+		`status != 503`:                              false,
+		`code == 404 && code > 400`:                  true,
+		`code == "404"`:                              true,
+		`request.method == "GET"`:                    true,
+		`request.time > 0.1 && request.time <= 0.25`: true,
+		`level =~ "^(error|fatal)$"`:                 true,
+		`level !~ "^(error|fatal)$"`:                 false,
+		`kubernetes.pod_name =~ "^api-"`:             true,
+		`missing =~ ".*"`:                            false,
+		`sla`:                                        true,
+		`!sla`:                                       false,
+		`sla == true`:                                true,
+		`missing`:                                    false,
+		`missing == null`:                            true,
+		`missing > 0`:                                false,
+		`!(status >= 500 && level == "info")`:        true,
+		`exists(request.method) && !exists(request.body)`: true,
+		`status >= -1e3`:         true,
+		`"a" < "b"`:              true,
+		`level == 1`:             false,
+		`false || true && false`: false,
+	}
+
+	for source, expected := range testCases {
+		expression, err := Compile(source)
+		assert.NoError(t, err, source)
+		assert.Equal(t, expected, expression.Evaluate(entryMapTest), source)
+		assert.Equal(t, source, expression.String())
+	}
+}
+
+func TestCompileInvalid(t *testing.T) {
+	for _, source := range []string{
+		``,
+		`status >=`,
+		`status == 500 &&`,
+		`(status == 500`,
+		`status == 500)`,
+		`level =~ level`,
+		`level =~ "("`,
+		`level = "error"`,
+		`"unterminated`,
+		`lower(level)`,
+		`exists("level")`,
+		`status 500`,
+		`1.2.3 == status`,
+	} {
+		_, err := Compile(source)
+		assert.ErrorIs(t, err, ErrSyntax, source)
+	}
+}
+
+// mustCompile is like Compile but panics if the expression can't be parsed
+func mustCompile(source string) *Expression {
+	expression, err := Compile(source)
+	if err != nil {
+		panic(err)
+	}
+
+	return expression
+}
+
+func BenchmarkCompile(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		_, _ = Compile(`kubernetes.namespace_name == "prod" && status >= 500 && level =~ "^(error|fatal)$"`)
+	}
+}
+
+func BenchmarkExpression_Evaluate(b *testing.B) {
+	expression := mustCompile(`kubernetes.namespace_name == "prod" && status >= 500`)
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		expression.Evaluate(entryMapTest)
+	}
+}
+
+func BenchmarkExpression_EvaluateNested(b *testing.B) {
+	expression := mustCompile(`request.method == "GET" && request.time > 0.1 || level =~ "^(error|fatal)$"`)
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		expression.Evaluate(entryMapTest)
+	}
+}
@@ -0,0 +1,145 @@
+package expression
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdentifier
+	tokenString
+	tokenNumber
+	tokenOperator
+	tokenParenOpen
+	tokenParenClose
+)
+
+type token struct {
+	kind     tokenKind
+	value    string
+	number   float64
+	position int
+}
+
+// operators are ordered so that longer ones are checked first
+var operators = []string{"&&", "||", "==", "!=", "<=", ">=", "=~", "!~", "<", ">", "!"}
+
+func tokenize(source string) ([]token, error) {
+	tokens := make([]token, 0)
+	position := 0
+
+	for position < len(source) {
+		char := source[position]
+
+		switch {
+		case char == ' ' || char == '\t' || char == '\n' || char == '\r':
+			position++
+		case char == '(':
+			tokens = append(tokens, token{kind: tokenParenOpen, value: "(", position: position})
+			position++
+		case char == ')':
+			tokens = append(tokens, token{kind: tokenParenClose, value: ")", position: position})
+			position++
+		case char == '"' || char == '\'':
+			value, end, err := scanString(source, position)
+			if err != nil {
+				return nil, err
+			}
+
+			tokens = append(tokens, token{kind: tokenString, value: value, position: position})
+			position = end
+		case isDigit(char) || (char == '-' && position+1 < len(source) && isDigit(source[position+1])):
+			end := position + 1
+
+			for end < len(source) && (isDigit(source[end]) || source[end] == '.' || source[end] == 'e' ||
+				source[end] == 'E' || ((source[end] == '-' || source[end] == '+') &&
+				(source[end-1] == 'e' || source[end-1] == 'E'))) {
+				end++
+			}
+
+			number, err := strconv.ParseFloat(source[position:end], 64)
+			if err != nil {
+				return nil, fmt.Errorf("%w: invalid number '%s' at %d", ErrSyntax, source[position:end], position)
+			}
+
+			tokens = append(tokens, token{kind: tokenNumber, value: source[position:end], number: number,
+				position: position})
+			position = end
+		case isIdentifierStart(char):
+			end := position + 1
+
+			for end < len(source) && isIdentifierPart(source[end]) {
+				end++
+			}
+
+			tokens = append(tokens, token{kind: tokenIdentifier, value: source[position:end], position: position})
+			position = end
+		default:
+			operator := matchOperator(source[position:])
+
+			if operator == "" {
+				return nil, fmt.Errorf("%w: unexpected character '%c' at %d", ErrSyntax, char, position)
+			}
+
+			tokens = append(tokens, token{kind: tokenOperator, value: operator, position: position})
+			position += len(operator)
+		}
+	}
+
+	return append(tokens, token{kind: tokenEOF, position: position}), nil
+}
+
+func scanString(source string, start int) (string, int, error) {
+	quote := source[start]
+	builder := strings.Builder{}
+
+	for position := start + 1; position < len(source); position++ {
+		char := source[position]
+
+		switch {
+		case char == '\\' && position+1 < len(source):
+			position++
+
+			switch source[position] {
+			case 'n':
+				builder.WriteByte('\n')
+			case 't':
+				builder.WriteByte('\t')
+			default:
+				builder.WriteByte(source[position])
+			}
+		case char == quote:
+			return builder.String(), position + 1, nil
+		default:
+			builder.WriteByte(char)
+		}
+	}
+
+	return "", 0, fmt.Errorf("%w: unterminated string at %d", ErrSyntax, start)
+}
+
+func matchOperator(source string) string {
+	for _, operator := range operators {
+		if strings.HasPrefix(source, operator) {
+			return operator
+		}
+	}
+
+	return ""
+}
+
+func isDigit(char byte) bool {
+	return char >= '0' && char <= '9'
+}
+
+func isIdentifierStart(char byte) bool {
+	return char == '_' || char == '@' || (char >= 'a' && char <= 'z') || (char >= 'A' && char <= 'Z')
+}
+
+func isIdentifierPart(char byte) bool {
+	return isIdentifierStart(char) || isDigit(char) || char == '.' || char == '-'
+}
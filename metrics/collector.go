@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
@@ -15,7 +16,11 @@ import (
 // ServeHTTPRequests starts http service for handle metrics
 func ServeHTTPRequests(addr string, path string) {
 	log.Printf("Start serving metrics on '%s/%s'", addr, path)
-	http.Handle(path, promhttp.Handler())
+	// OpenMetrics format is required to expose exemplars
+	http.Handle(path, promhttp.InstrumentMetricHandler(
+		prometheus.DefaultRegisterer,
+		promhttp.HandlerFor(prometheus.DefaultGatherer, promhttp.HandlerOpts{EnableOpenMetrics: true}),
+	))
 	http.HandleFunc("/healthcheck", func(w http.ResponseWriter, r *http.Request) {
 		fmt.Fprint(w, "Ok")
 	})
@@ -24,18 +29,28 @@ func ServeHTTPRequests(addr string, path string) {
 
 // Collector provides interface for accessing and modification of metrics
 type Collector struct {
-	httpRequestCount              *prometheus.CounterVec
-	httpRequestTotalCount         *prometheus.CounterVec
-	httpRequestTime               *prometheus.HistogramVec
-	httpUpstreamResponseTimeTotal *prometheus.HistogramVec
-	logMessageCount               *prometheus.CounterVec
-	throttlingDelay               *prometheus.CounterVec
+	httpRequestCount              counterVec
+	httpRequestTotalCount         counterVec
+	httpRequestTime               *serviceHistogramVec
+	httpUpstreamResponseTimeTotal *serviceHistogramVec
+	logMessageCount               counterVec
+	throttlingDelay               counterVec
+	truncationsCount              counterVec
+	sampledOutCount               counterVec
+	redactionHitsCount            counterVec
+	traceContextCount             counterVec
+	sloEventsCount                counterVec
+	seriesOverflowCount           counterVec
+	sloTracker                    *SLOTracker
+
+	seriesTTL time.Duration
 }
 
 var collector *Collector
 
-// NewCollector is a constructor for Collector singleton
-func NewCollector(bucketsString string) (*Collector, error) {
+// NewCollector is a constructor for Collector singleton; series that haven't been updated for seriesTTL
+// are deleted on Retrieve. Native histograms are exposed along with classic buckets when enabled
+func NewCollector(bucketsString string, nativeHistograms bool, seriesTTL time.Duration) (*Collector, error) {
 	if collector != nil {
 		return collector, nil
 	}
@@ -45,33 +60,70 @@ func NewCollector(bucketsString string) (*Collector, error) {
 		return &Collector{}, err
 	}
 
-	httpRequestCount := prometheus.NewCounterVec(prometheus.CounterOpts{
+	httpRequestCount := newCounterVec(prometheus.CounterOpts{
 		Name: "http_request_count",
 		Help: "Count requests",
 	}, []string{"method", "service", "path", "status", "upstream_pod_name"})
 
-	httpRequestTotalCount := prometheus.NewCounterVec(prometheus.CounterOpts{
+	httpRequestTotalCount := newCounterVec(prometheus.CounterOpts{
 		Name: "http_request_total_count",
 		Help: "The total number of requests processed",
 	}, []string{"service"})
-	httpRequestTime := prometheus.NewHistogramVec(prometheus.HistogramOpts{
-		Name:    "http_request_time",
-		Help:    "Histogram for HTTP requests time",
-		Buckets: buckets,
+	bucketFactor := 0.0
+
+	if nativeHistograms {
+		bucketFactor = nativeHistogramBucketFactor
+	}
+
+	httpRequestTime := newServiceHistogramVec(prometheus.HistogramOpts{
+		Name:                        "http_request_time",
+		Help:                        "Histogram for HTTP requests time",
+		Buckets:                     buckets,
+		NativeHistogramBucketFactor: bucketFactor,
 	}, []string{"method", "service", "path", "upstream_pod_name"})
-	httpUpstreamResponseTimeTotal := prometheus.NewHistogramVec(prometheus.HistogramOpts{
-		Name:    "http_upstream_response_time_total",
-		Help:    "Histogram for HTTP upstream response time, all the upstreams",
-		Buckets: buckets,
+	httpUpstreamResponseTimeTotal := newServiceHistogramVec(prometheus.HistogramOpts{
+		Name:                        "http_upstream_response_time_total",
+		Help:                        "Histogram for HTTP upstream response time, all the upstreams",
+		Buckets:                     buckets,
+		NativeHistogramBucketFactor: bucketFactor,
 	}, []string{"method", "service", "path", "upstream_pod_name"})
-	logMessageCount := prometheus.NewCounterVec(prometheus.CounterOpts{
+	logMessageCount := newCounterVec(prometheus.CounterOpts{
 		Name: "log_message_count",
 		Help: "Store all processed log messages per one container",
 	}, []string{"namespace", "pod", "container"})
-	throttlingDelay := prometheus.NewCounterVec(prometheus.CounterOpts{
+	throttlingDelay := newCounterVec(prometheus.CounterOpts{
 		Name: "container_throttling_delay_seconds_total",
 		Help: "Indicates particular container's total throttle time",
 	}, []string{"namespace", "pod", "container"})
+	truncationsCount := newCounterVec(prometheus.CounterOpts{
+		Name: "container_log_truncations_count",
+		Help: "Count in place truncations of log file detected, file is read from the beginning after them",
+	}, []string{"namespace", "pod", "container"})
+
+	sampledOutCount := newCounterVec(prometheus.CounterOpts{
+		Name: "log_message_sampled_out_count",
+		Help: "Count log messages dropped by severity sampling",
+	}, []string{"namespace", "severity"})
+
+	redactionHitsCount := newCounterVec(prometheus.CounterOpts{
+		Name: "redaction_hits_count",
+		Help: "Count values redacted by redaction rule",
+	}, []string{"rule"})
+
+	traceContextCount := newCounterVec(prometheus.CounterOpts{
+		Name: "log_message_trace_context_count",
+		Help: "Count log messages with and without trace context",
+	}, []string{"namespace", "trace_context"})
+
+	sloEventsCount := newCounterVec(prometheus.CounterOpts{
+		Name: "slo_events_count",
+		Help: "Count requests of services with objective, by result: good or bad",
+	}, []string{"service", "result"})
+	seriesOverflowCount := newCounterVec(prometheus.CounterOpts{
+		Name: "http_request_series_overflow_count",
+		Help: "Count requests with labels folded due to exceeded series limits",
+	}, []string{"service"})
+	sloTracker := NewSLOTracker()
 
 	if err = prometheus.Register(httpRequestCount); err != nil {
 		return &Collector{}, err
@@ -91,6 +143,27 @@ func NewCollector(bucketsString string) (*Collector, error) {
 	if err = prometheus.Register(throttlingDelay); err != nil {
 		return &Collector{}, err
 	}
+	if err = prometheus.Register(truncationsCount); err != nil {
+		return &Collector{}, err
+	}
+	if err = prometheus.Register(sampledOutCount); err != nil {
+		return &Collector{}, err
+	}
+	if err = prometheus.Register(redactionHitsCount); err != nil {
+		return &Collector{}, err
+	}
+	if err = prometheus.Register(traceContextCount); err != nil {
+		return &Collector{}, err
+	}
+	if err = prometheus.Register(sloEventsCount); err != nil {
+		return &Collector{}, err
+	}
+	if err = prometheus.Register(seriesOverflowCount); err != nil {
+		return &Collector{}, err
+	}
+	if err = prometheus.Register(sloTracker); err != nil {
+		return &Collector{}, err
+	}
 
 	collector = &Collector{
 		httpRequestCount:              httpRequestCount,
@@ -99,79 +172,137 @@ func NewCollector(bucketsString string) (*Collector, error) {
 		httpUpstreamResponseTimeTotal: httpUpstreamResponseTimeTotal,
 		logMessageCount:               logMessageCount,
 		throttlingDelay:               throttlingDelay,
+		truncationsCount:              truncationsCount,
+		sampledOutCount:               sampledOutCount,
+		redactionHitsCount:            redactionHitsCount,
+		traceContextCount:             traceContextCount,
+		sloEventsCount:                sloEventsCount,
+		seriesOverflowCount:           seriesOverflowCount,
+		sloTracker:                    sloTracker,
+		seriesTTL:                     seriesTTL,
 	}
 	return collector, nil
 }
 
-// Retrieve resets metrics
+// Retrieve deletes series that haven't been updated for series TTL; vectors aren't reset as a whole, so counters
+// of active series stay monotonic
 func (collector *Collector) Retrieve() error {
-	collector.httpRequestCount.Reset()
-	collector.httpRequestTotalCount.Reset()
-	collector.httpRequestTime.Reset()
-	collector.httpUpstreamResponseTimeTotal.Reset()
-	collector.logMessageCount.Reset()
-	collector.throttlingDelay.Reset()
+	collector.evict(collector.seriesTTL)
 	return nil
 }
 
+// Reset deletes all the series, counters of active series start over
+func (collector *Collector) Reset() error {
+	collector.evict(0)
+	return nil
+}
+
+func (collector *Collector) evict(ttl time.Duration) {
+	for _, series := range []*seriesTracker{
+		collector.httpRequestCount.series,
+		collector.httpRequestTotalCount.series,
+		collector.logMessageCount.series,
+		collector.throttlingDelay.series,
+		collector.truncationsCount.series,
+		collector.sampledOutCount.series,
+		collector.redactionHitsCount.series,
+		collector.traceContextCount.series,
+		collector.sloEventsCount.series,
+		collector.seriesOverflowCount.series,
+	} {
+		series.evict(ttl)
+	}
+
+	collector.httpRequestTime.evict(ttl)
+	collector.httpUpstreamResponseTimeTotal.evict(ttl)
+	collector.sloTracker.evict(ttl)
+}
+
 // IncrementHTTPRequestCount i.golovchenko: don't like this interface actually, probably needs refactoring
 func (collector *Collector) IncrementHTTPRequestCount(podName, method, service, path string, status int) {
-	collector.httpRequestCount.With(
-		prometheus.Labels{
-			"upstream_pod_name": podName,
-			"method":            method,
-			"service":           service,
-			"path":              path,
-			"status":            strconv.Itoa(status),
-		},
-	).Inc()
+	collector.httpRequestCount.with(method, service, path, strconv.Itoa(status), podName).Inc()
 }
 
 // IncrementHTTPRequestsTotalCount increments corresponding metric
 func (collector *Collector) IncrementHTTPRequestsTotalCount(service string) {
-	collector.httpRequestTotalCount.With(prometheus.Labels{"service": service}).Inc()
+	collector.httpRequestTotalCount.with(service).Inc()
 }
 
 // IncrementLogMessageCount increments corresponding metric
 func (collector *Collector) IncrementLogMessageCount(
 	namespace string, podName string, containerName string) {
-	collector.logMessageCount.WithLabelValues(namespace, podName, containerName).Inc()
+	collector.logMessageCount.with(namespace, podName, containerName).Inc()
 }
 
-// ObserveHTTPRequestTime should be used to make observations of corresponding metric
+// ObserveHTTPRequestTime should be used to make observations of corresponding metric; service buckets
+// are used instead of default ones if not empty, exemplar labels are attached if not empty
 func (collector *Collector) ObserveHTTPRequestTime(
-	podName, method, service, path string, value float64) {
-	collector.httpRequestTime.With(
-		prometheus.Labels{
-			"upstream_pod_name": podName,
-			"service":           service,
-			"method":            method,
-			"path":              path,
-		},
-	).Observe(value)
-}
-
-// ObserveHTTPUpstreamResponseTimeTotal should be used to make observations of corresponding metric
+	podName, method, service, path string, value float64, buckets []float64, exemplar map[string]string) {
+	observe(collector.httpRequestTime.with(buckets, method, service, path, podName), value, exemplar)
+}
+
+// ObserveHTTPUpstreamResponseTimeTotal should be used to make observations of corresponding metric, the same way
+// as ObserveHTTPRequestTime
 func (collector *Collector) ObserveHTTPUpstreamResponseTimeTotal(
-	podName, method, service, path string, value float64) {
-	collector.httpUpstreamResponseTimeTotal.With(
-		prometheus.Labels{
-			"upstream_pod_name": podName,
-			"service":           service,
-			"method":            method,
-			"path":              path,
-		},
-	).Observe(value)
+	podName, method, service, path string, value float64, buckets []float64, exemplar map[string]string) {
+	observe(collector.httpUpstreamResponseTimeTotal.with(buckets, method, service, path, podName), value, exemplar)
 }
 
 // IncrementThrottlingDelay increments value of corresponding metric
 func (collector *Collector) IncrementThrottlingDelay(namespace string, podName string, containerName string, value float64) {
-	collector.throttlingDelay.WithLabelValues(namespace, podName, containerName).Add(value)
+	collector.throttlingDelay.with(namespace, podName, containerName).Add(value)
+}
+
+// DeleteContainerSeries should be used to delete series of the container that has gone
+func (collector *Collector) DeleteContainerSeries(namespace string, podName string, containerName string) bool {
+	deletedMessages := collector.logMessageCount.series.delete(namespace, podName, containerName)
+	deletedDelay := collector.throttlingDelay.series.delete(namespace, podName, containerName)
+	collector.truncationsCount.series.delete(namespace, podName, containerName)
+	return deletedMessages || deletedDelay
+}
+
+// IncrementTruncationsCount increments corresponding metric
+func (collector *Collector) IncrementTruncationsCount(namespace, podName, containerName string) {
+	collector.truncationsCount.with(namespace, podName, containerName).Inc()
+}
+
+// IncrementSampledOutCount increments corresponding metric
+func (collector *Collector) IncrementSampledOutCount(namespace, severity string) {
+	collector.sampledOutCount.with(namespace, severity).Inc()
+}
+
+// IncrementRedactionHitsCount increments corresponding metric by count of redacted values
+func (collector *Collector) IncrementRedactionHitsCount(rule string, count int) {
+	collector.redactionHitsCount.with(rule).Add(float64(count))
+}
+
+// IncrementTraceContextCount increments corresponding metric, trace_context label is present or absent
+func (collector *Collector) IncrementTraceContextCount(namespace string, present bool) {
+	traceContext := "absent"
+
+	if present {
+		traceContext = "present"
+	}
+
+	collector.traceContextCount.with(namespace, traceContext).Inc()
+}
+
+// ObserveSLOEvent accounts good or bad request of the service with objective; burn rates are computed over
+// sliding windows, services are dropped from them after series TTL as well
+func (collector *Collector) ObserveSLOEvent(service string, objective float64, windows []time.Duration, good bool) {
+	result := "good"
+
+	if !good {
+		result = "bad"
+	}
+
+	collector.sloEventsCount.with(service, result).Inc()
+	collector.sloTracker.Observe(service, objective, windows, good)
 }
 
-// DeleteThrottlingDelay should be used to delete gauge series with specified labels
-func (collector *Collector) DeleteThrottlingDelay(namespace string, podName string, containerName string) bool {
-	return collector.throttlingDelay.DeleteLabelValues(namespace, podName, containerName)
+// IncrementHTTPRequestSeriesOverflowCount increments corresponding metric
+func (collector *Collector) IncrementHTTPRequestSeriesOverflowCount(service string) {
+	collector.seriesOverflowCount.with(service).Inc()
 }
 
 func buckets(bucketsString string) ([]float64, error) {
@@ -0,0 +1,150 @@
+package metrics
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/model"
+)
+
+// serviceLabel is a label of SLI metrics the buckets are chosen by
+const serviceLabel = "service"
+
+// nativeHistogramBucketFactor is a growth factor of native histogram buckets, about 8 buckets per power of 2
+const nativeHistogramBucketFactor = 1.1
+
+// serviceHistogramVec is a histogram vector with buckets set per service; series of every buckets layout are kept
+// in separate vectors exposed as a single metric
+type serviceHistogramVec struct {
+	mu           sync.RWMutex
+	opts         prometheus.HistogramOpts
+	labelNames   []string
+	serviceIndex int
+	desc         *prometheus.Desc
+
+	layouts  map[string]histogramVec
+	services map[string]string
+}
+
+func newServiceHistogramVec(opts prometheus.HistogramOpts, labelNames []string) *serviceHistogramVec {
+	vector := &serviceHistogramVec{
+		opts:       opts,
+		labelNames: labelNames,
+		desc:       prometheus.NewDesc(opts.Name, opts.Help, labelNames, opts.ConstLabels),
+		layouts:    make(map[string]histogramVec),
+		services:   make(map[string]string),
+	}
+
+	for i, name := range labelNames {
+		if name == serviceLabel {
+			vector.serviceIndex = i
+		}
+	}
+
+	return vector
+}
+
+// with returns observer of the series; empty buckets mean default ones. When service buckets change, service series
+// of the previous layout are deleted, so the same series isn't exposed twice
+func (vector *serviceHistogramVec) with(buckets []float64, labelValues ...string) prometheus.Observer {
+	key := ""
+
+	if len(buckets) > 0 {
+		key = fmt.Sprint(buckets)
+	}
+
+	service := labelValues[vector.serviceIndex]
+
+	vector.mu.RLock()
+	layout, ok := vector.layouts[key]
+	current, known := vector.services[service]
+	vector.mu.RUnlock()
+
+	if !ok || !known || current != key {
+		layout = vector.setLayout(service, key, buckets)
+	}
+
+	return layout.with(labelValues...)
+}
+
+func (vector *serviceHistogramVec) setLayout(service, key string, buckets []float64) histogramVec {
+	vector.mu.Lock()
+	defer vector.mu.Unlock()
+
+	layout, ok := vector.layouts[key]
+
+	if !ok {
+		opts := vector.opts
+
+		if len(buckets) > 0 {
+			opts.Buckets = buckets
+		}
+
+		layout = newHistogramVec(opts, vector.labelNames)
+		vector.layouts[key] = layout
+	}
+
+	if current, known := vector.services[service]; known && current != key {
+		vector.layouts[current].DeletePartialMatch(prometheus.Labels{serviceLabel: service})
+	}
+
+	vector.services[service] = key
+	return layout
+}
+
+// evict removes series of every layout that haven't been updated for ttl
+func (vector *serviceHistogramVec) evict(ttl time.Duration) {
+	vector.mu.RLock()
+	defer vector.mu.RUnlock()
+
+	for _, layout := range vector.layouts {
+		layout.series.evict(ttl)
+	}
+}
+
+// Describe implements prometheus.Collector
+func (vector *serviceHistogramVec) Describe(descs chan<- *prometheus.Desc) {
+	descs <- vector.desc
+}
+
+// Collect implements prometheus.Collector
+func (vector *serviceHistogramVec) Collect(metrics chan<- prometheus.Metric) {
+	vector.mu.RLock()
+	defer vector.mu.RUnlock()
+
+	for _, layout := range vector.layouts {
+		layout.Collect(metrics)
+	}
+}
+
+// observe makes observation with exemplar, if there is one
+func observe(observer prometheus.Observer, value float64, exemplar map[string]string) {
+	if len(exemplar) == 0 || !validExemplar(exemplar) {
+		observer.Observe(value)
+		return
+	}
+
+	if exemplarObserver, ok := observer.(prometheus.ExemplarObserver); ok {
+		exemplarObserver.ObserveWithExemplar(value, exemplar)
+		return
+	}
+
+	observer.Observe(value)
+}
+
+func validExemplar(exemplar map[string]string) bool {
+	runes := 0
+
+	for name, value := range exemplar {
+		if !model.LabelName(name).IsValid() {
+			return false
+		}
+
+		runes += len([]rune(name)) + len([]rune(value))
+	}
+
+	// longer exemplars make client panic
+	return runes <= prometheus.ExemplarMaxRunes
+}
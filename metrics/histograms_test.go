@@ -0,0 +1,90 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestServiceHistogramVec(nativeBucketFactor float64) (*serviceHistogramVec, *prometheus.Registry) {
+	vector := newServiceHistogramVec(prometheus.HistogramOpts{
+		Name:                        "test_time",
+		Help:                        "Test time",
+		Buckets:                     []float64{1, 2},
+		NativeHistogramBucketFactor: nativeBucketFactor,
+	}, []string{"method", "service"})
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(vector)
+	return vector, registry
+}
+
+func TestServiceHistogramVecBuckets(t *testing.T) {
+	vector, registry := newTestServiceHistogramVec(0)
+
+	vector.with(nil, "GET", "default").Observe(1)
+	vector.with([]float64{0.1, 0.5, 5}, "GET", "custom").Observe(0.2)
+
+	families, err := registry.Gather()
+	assert.NoError(t, err)
+	assert.Len(t, families, 1)
+	assert.Len(t, families[0].Metric, 2)
+
+	for _, metric := range families[0].Metric {
+		switch metric.Label[1].GetValue() {
+		case "default":
+			assert.Len(t, metric.Histogram.Bucket, 2)
+		case "custom":
+			assert.Len(t, metric.Histogram.Bucket, 3)
+			assert.Equal(t, 0.1, metric.Histogram.Bucket[0].GetUpperBound())
+		}
+	}
+
+	// series of the previous layout are deleted when service buckets change
+	vector.with(nil, "GET", "custom").Observe(1)
+	assert.Equal(t, 2, testutil.CollectAndCount(vector))
+
+	families, err = registry.Gather()
+	assert.NoError(t, err)
+
+	for _, metric := range families[0].Metric {
+		assert.Len(t, metric.Histogram.Bucket, 2)
+	}
+}
+
+func TestServiceHistogramVecExemplar(t *testing.T) {
+	vector, registry := newTestServiceHistogramVec(0)
+
+	observe(vector.with(nil, "GET", "service"), 1.5, map[string]string{"request_id": "abc"})
+	observe(vector.with(nil, "GET", "service"), 0.5, map[string]string{"invalid-label": "abc"})
+
+	families, err := registry.Gather()
+	assert.NoError(t, err)
+
+	buckets := families[0].Metric[0].Histogram.Bucket
+	assert.Nil(t, buckets[0].Exemplar)
+	assert.NotNil(t, buckets[1].Exemplar)
+	assert.Equal(t, "request_id", buckets[1].Exemplar.Label[0].GetName())
+	assert.Equal(t, "abc", buckets[1].Exemplar.Label[0].GetValue())
+	assert.Equal(t, 1.5, buckets[1].Exemplar.GetValue())
+	assert.Equal(t, uint64(2), families[0].Metric[0].Histogram.GetSampleCount())
+}
+
+func TestServiceHistogramVecNative(t *testing.T) {
+	vector, registry := newTestServiceHistogramVec(nativeHistogramBucketFactor)
+	vector.with(nil, "GET", "service").Observe(0.3)
+
+	families, err := registry.Gather()
+	assert.NoError(t, err)
+
+	histogram := families[0].Metric[0].Histogram
+	assert.NotNil(t, histogram.Schema)
+	assert.Len(t, histogram.Bucket, 2)
+}
+
+func TestValidExemplar(t *testing.T) {
+	assert.True(t, validExemplar(map[string]string{"trace_id": "4bf92f3577b34da6a3ce929d0e0e4736"}))
+	assert.False(t, validExemplar(map[string]string{"trace-id": "abc"}))
+	assert.False(t, validExemplar(map[string]string{"trace_id": string(make([]byte, 130))}))
+}
@@ -0,0 +1,141 @@
+package metrics
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+/* Log metric types */
+const (
+	LogMetricTypeCounter   = "counter"
+	LogMetricTypeGauge     = "gauge"
+	LogMetricTypeHistogram = "histogram"
+)
+
+// LogMetricLabelOther replaces label values of series exceeding the series limit of the metric
+const LogMetricLabelOther = "__other__"
+
+// LogMetrics creates log derived metrics and deletes their series that haven't been updated for series TTL
+type LogMetrics struct {
+	mu          sync.Mutex
+	metrics     []*LogMetric
+	seriesLimit int
+	seriesTTL   time.Duration
+}
+
+// NewLogMetrics is a LogMetrics constructor; metrics keep no more than seriesLimit series, zero means no limit
+func NewLogMetrics(seriesLimit int, seriesTTL time.Duration) *LogMetrics {
+	return &LogMetrics{
+		seriesLimit: seriesLimit,
+		seriesTTL:   seriesTTL,
+	}
+}
+
+// NewLogMetric creates metric of the given type and registers it; default buckets are used for histogram
+// if buckets are not specified
+func (logMetrics *LogMetrics) NewLogMetric(
+	name, metricType, help string, labels []string, buckets []float64) (*LogMetric, error) {
+	metric, err := NewLogMetric(name, metricType, help, labels, buckets, logMetrics.seriesLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	logMetrics.mu.Lock()
+	logMetrics.metrics = append(logMetrics.metrics, metric)
+	logMetrics.mu.Unlock()
+	return metric, nil
+}
+
+// Retrieve deletes stale series of the metrics
+func (logMetrics *LogMetrics) Retrieve() error {
+	logMetrics.mu.Lock()
+	defer logMetrics.mu.Unlock()
+
+	for _, metric := range logMetrics.metrics {
+		metric.series.evict(logMetrics.seriesTTL)
+	}
+
+	return nil
+}
+
+// LogMetric is a user-defined metric derived from log entries, registered at runtime
+type LogMetric struct {
+	counter   *prometheus.CounterVec
+	gauge     *prometheus.GaugeVec
+	histogram *prometheus.HistogramVec
+
+	series      *seriesTracker
+	seriesLimit int
+	labelsOther []string
+}
+
+// NewLogMetric creates metric of the given type and registers it; default buckets are used for histogram
+// if buckets are not specified; observations of series exceeding seriesLimit are folded into the series with
+// LogMetricLabelOther label values, zero limit means no limit
+func NewLogMetric(
+	name, metricType, help string, labels []string, buckets []float64, seriesLimit int) (*LogMetric, error) {
+	var collector prometheus.Collector
+	var vector labelValuesDeleter
+
+	metric := &LogMetric{seriesLimit: seriesLimit, labelsOther: make([]string, len(labels))}
+
+	for i := range metric.labelsOther {
+		metric.labelsOther[i] = LogMetricLabelOther
+	}
+
+	if help == "" {
+		help = fmt.Sprintf("Log derived %s %s", metricType, name)
+	}
+
+	switch metricType {
+	case LogMetricTypeCounter:
+		metric.counter = prometheus.NewCounterVec(prometheus.CounterOpts{Name: name, Help: help}, labels)
+		collector, vector = metric.counter, metric.counter
+	case LogMetricTypeGauge:
+		metric.gauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: name, Help: help}, labels)
+		collector, vector = metric.gauge, metric.gauge
+	case LogMetricTypeHistogram:
+		if len(buckets) == 0 {
+			buckets = prometheus.DefBuckets
+		}
+
+		metric.histogram = prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{Name: name, Help: help, Buckets: buckets}, labels)
+		collector, vector = metric.histogram, metric.histogram
+	default:
+		return nil, fmt.Errorf("unsupported type '%s' of metric '%s'", metricType, name)
+	}
+
+	if err := prometheus.Register(collector); err != nil {
+		return nil, fmt.Errorf("unable to register metric '%s', %s", name, err)
+	}
+
+	metric.series = newSeriesTracker(vector)
+	return metric, nil
+}
+
+// Observe adds value to counter, sets gauge or makes histogram observation
+func (metric *LogMetric) Observe(labelValues []string, value float64) {
+	observe := func() { metric.observe(labelValues, value) }
+
+	if !metric.series.touchLimited(metric.seriesLimit, observe, labelValues...) {
+		metric.series.touch(func() { metric.observe(metric.labelsOther, value) }, metric.labelsOther...)
+	}
+}
+
+// observe makes observation of the series, it's called while the series is tracked
+func (metric *LogMetric) observe(labelValues []string, value float64) {
+	switch {
+	case metric.counter != nil:
+		if value >= 0 {
+			metric.counter.WithLabelValues(labelValues...).Add(value)
+		}
+	case metric.gauge != nil:
+		metric.gauge.WithLabelValues(labelValues...).Set(value)
+	case metric.histogram != nil:
+		metric.histogram.WithLabelValues(labelValues...).Observe(value)
+	}
+}
@@ -0,0 +1,67 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewLogMetric(t *testing.T) {
+	counter, err := NewLogMetric("test_log_counter", LogMetricTypeCounter, "", []string{"code"}, nil, 0)
+	assert.NoError(t, err)
+	counter.Observe([]string{"42"}, 1)
+	counter.Observe([]string{"42"}, 2)
+	counter.Observe([]string{"42"}, -1)
+	assert.Equal(t, 3.0, testutil.ToFloat64(counter.counter.WithLabelValues("42")))
+
+	gauge, err := NewLogMetric("test_log_gauge", LogMetricTypeGauge, "Gauge", []string{}, nil, 0)
+	assert.NoError(t, err)
+	gauge.Observe([]string{}, 5)
+	gauge.Observe([]string{}, 2)
+	assert.Equal(t, 2.0, testutil.ToFloat64(gauge.gauge.WithLabelValues()))
+
+	histogram, err := NewLogMetric("test_log_histogram", LogMetricTypeHistogram, "", []string{}, []float64{1, 10}, 0)
+	assert.NoError(t, err)
+	histogram.Observe([]string{}, 5)
+	assert.Equal(t, 1, testutil.CollectAndCount(histogram.histogram))
+
+	_, err = NewLogMetric("test_log_counter", LogMetricTypeCounter, "", []string{"code"}, nil, 0)
+	assert.Error(t, err)
+
+	_, err = NewLogMetric("test_log_summary", "summary", "", []string{}, nil, 0)
+	assert.Error(t, err)
+
+	_, err = NewLogMetric("invalid-name", LogMetricTypeCounter, "", []string{}, nil, 0)
+	assert.Error(t, err)
+}
+
+func TestLogMetricsSeriesLimit(t *testing.T) {
+	now := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	logMetrics := NewLogMetrics(2, time.Hour)
+	metric, err := logMetrics.NewLogMetric("test_log_limited", LogMetricTypeCounter, "", []string{"id", "code"}, nil)
+	assert.NoError(t, err)
+	metric.series.now = func() time.Time { return now }
+
+	metric.Observe([]string{"1", "200"}, 1)
+	metric.Observe([]string{"2", "200"}, 1)
+	// series exceeding the limit are folded
+	metric.Observe([]string{"3", "200"}, 1)
+	metric.Observe([]string{"4", "500"}, 1)
+	// known series are still observed
+	metric.Observe([]string{"1", "200"}, 1)
+
+	assert.Equal(t, 3, testutil.CollectAndCount(metric.counter))
+	assert.Equal(t, 2.0, testutil.ToFloat64(metric.counter.WithLabelValues("1", "200")))
+	assert.Equal(t, 2.0, testutil.ToFloat64(
+		metric.counter.WithLabelValues(LogMetricLabelOther, LogMetricLabelOther)))
+
+	// stale series are deleted, giving room to the new ones
+	now = now.Add(2 * time.Hour)
+	assert.NoError(t, logMetrics.Retrieve())
+	assert.Equal(t, 0, testutil.CollectAndCount(metric.counter))
+
+	metric.Observe([]string{"3", "200"}, 1)
+	assert.Equal(t, 1.0, testutil.ToFloat64(metric.counter.WithLabelValues("3", "200")))
+}
@@ -0,0 +1,134 @@
+package metrics
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// seriesKeySeparator can't be a part of valid label values
+const seriesKeySeparator = "\xff"
+
+type labelValuesDeleter interface {
+	DeleteLabelValues(labelValues ...string) bool
+}
+
+type trackedSeries struct {
+	labelValues []string
+	updated     time.Time
+}
+
+// seriesTracker keeps last update time of every series of the vector, so the stale ones can be deleted
+// instead of resetting the whole vector
+type seriesTracker struct {
+	mu     sync.Mutex
+	vector labelValuesDeleter
+	series map[string]*trackedSeries
+	now    func() time.Time
+}
+
+func newSeriesTracker(vector labelValuesDeleter) *seriesTracker {
+	return &seriesTracker{
+		vector: vector,
+		series: make(map[string]*trackedSeries),
+		now:    time.Now,
+	}
+}
+
+// touch marks series as updated now; create gets the series from the vector under the lock, so the series can't be
+// evicted between being created and tracked
+func (tracker *seriesTracker) touch(create func(), labelValues ...string) {
+	tracker.touchLimited(0, create, labelValues...)
+}
+
+// touchLimited marks series as updated now and calls create if the series is known or count of series is below limit;
+// zero limit means no limit
+func (tracker *seriesTracker) touchLimited(limit int, create func(), labelValues ...string) bool {
+	key := strings.Join(labelValues, seriesKeySeparator)
+
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+
+	if series, ok := tracker.series[key]; ok {
+		series.updated = tracker.now()
+		create()
+		return true
+	}
+
+	if limit > 0 && len(tracker.series) >= limit {
+		return false
+	}
+
+	tracker.series[key] = &trackedSeries{
+		labelValues: append([]string(nil), labelValues...),
+		updated:     tracker.now(),
+	}
+	create()
+	return true
+}
+
+// delete removes the series from the vector
+func (tracker *seriesTracker) delete(labelValues ...string) bool {
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+
+	delete(tracker.series, strings.Join(labelValues, seriesKeySeparator))
+	return tracker.vector.DeleteLabelValues(labelValues...)
+}
+
+// evict removes series that haven't been updated for ttl, returns count of removed series
+func (tracker *seriesTracker) evict(ttl time.Duration) int {
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+
+	deadline := tracker.now().Add(-ttl)
+	count := 0
+
+	for key, series := range tracker.series {
+		if series.updated.After(deadline) {
+			continue
+		}
+
+		tracker.vector.DeleteLabelValues(series.labelValues...)
+		delete(tracker.series, key)
+		count++
+	}
+
+	return count
+}
+
+// counterVec is a CounterVec with series tracking
+type counterVec struct {
+	*prometheus.CounterVec
+	series *seriesTracker
+}
+
+func newCounterVec(opts prometheus.CounterOpts, labelNames []string) counterVec {
+	vector := prometheus.NewCounterVec(opts, labelNames)
+	return counterVec{CounterVec: vector, series: newSeriesTracker(vector)}
+}
+
+func (vector counterVec) with(labelValues ...string) prometheus.Counter {
+	var counter prometheus.Counter
+	vector.series.touch(func() { counter = vector.WithLabelValues(labelValues...) }, labelValues...)
+	return counter
+}
+
+// histogramVec is a HistogramVec with series tracking
+type histogramVec struct {
+	*prometheus.HistogramVec
+	series *seriesTracker
+}
+
+func newHistogramVec(opts prometheus.HistogramOpts, labelNames []string) histogramVec {
+	vector := prometheus.NewHistogramVec(opts, labelNames)
+	return histogramVec{HistogramVec: vector, series: newSeriesTracker(vector)}
+}
+
+func (vector histogramVec) with(labelValues ...string) prometheus.Observer {
+	var observer prometheus.Observer
+	vector.series.touch(func() { observer = vector.WithLabelValues(labelValues...) }, labelValues...)
+	return observer
+}
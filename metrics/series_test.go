@@ -0,0 +1,85 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSeriesTrackerEvict(t *testing.T) {
+	now := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	vector := newCounterVec(prometheus.CounterOpts{Name: "test_count"}, []string{"namespace", "pod"})
+	vector.series.now = func() time.Time { return now }
+
+	vector.with("ns", "stale").Inc()
+	vector.with("ns", "active").Add(2)
+
+	now = now.Add(30 * time.Minute)
+	vector.with("ns", "active").Inc()
+	assert.Equal(t, 2, testutil.CollectAndCount(vector))
+
+	now = now.Add(40 * time.Minute)
+	assert.Equal(t, 1, vector.series.evict(time.Hour))
+	assert.Equal(t, 1, testutil.CollectAndCount(vector))
+	// counter of the active series isn't reset
+	assert.Equal(t, 3.0, testutil.ToFloat64(vector.WithLabelValues("ns", "active")))
+
+	now = now.Add(time.Hour)
+	assert.Equal(t, 1, vector.series.evict(time.Hour))
+	assert.Equal(t, 0, testutil.CollectAndCount(vector))
+	assert.Empty(t, vector.series.series)
+
+	// zero ttl deletes series updated just now, that's how metrics are reset
+	vector.with("ns", "active").Inc()
+	assert.Equal(t, 1, vector.series.evict(0))
+	assert.Equal(t, 0, testutil.CollectAndCount(vector))
+}
+
+func TestSeriesTrackerEvictWhileCreating(t *testing.T) {
+	vector := newCounterVec(prometheus.CounterOpts{Name: "test_count"}, []string{"namespace", "pod"})
+	evicted := make(chan int, 1)
+
+	vector.series.touch(func() {
+		go func() {
+			evicted <- vector.series.evict(0)
+		}()
+
+		// eviction waits for the series to be created
+		time.Sleep(10 * time.Millisecond)
+		vector.WithLabelValues("ns", "pod").Inc()
+	}, "ns", "pod")
+
+	// series isn't left in the vector untracked
+	assert.Equal(t, 1, <-evicted)
+	assert.Equal(t, 0, testutil.CollectAndCount(vector))
+	assert.Empty(t, vector.series.series)
+}
+
+func TestSeriesTrackerDelete(t *testing.T) {
+	vector := newHistogramVec(prometheus.HistogramOpts{Name: "test_time"}, []string{"container"})
+	vector.with("first").Observe(1)
+	vector.with("second").Observe(1)
+
+	assert.True(t, vector.series.delete("first"))
+	assert.False(t, vector.series.delete("first"))
+	assert.Equal(t, 1, testutil.CollectAndCount(vector))
+	assert.Len(t, vector.series.series, 1)
+}
+
+func TestSLOTrackerEvict(t *testing.T) {
+	now := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	tracker := NewSLOTracker()
+	tracker.now = func() time.Time { return now }
+	tracker.Observe("service", 0.9, []time.Duration{time.Minute}, true)
+
+	now = now.Add(30 * time.Minute)
+	tracker.evict(time.Hour)
+	assert.Len(t, tracker.services, 1)
+
+	now = now.Add(30 * time.Minute)
+	tracker.evict(time.Hour)
+	assert.Empty(t, tracker.services)
+}
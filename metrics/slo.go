@@ -0,0 +1,193 @@
+package metrics
+
+import (
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/model"
+)
+
+// sloWindowBuckets is a count of buckets each burn rate window is split to; events are expired by whole buckets,
+// so the window is approximated with precision of one bucket
+const sloWindowBuckets = 30
+
+// sloWindow counts good and bad events of the sliding window
+type sloWindow struct {
+	duration time.Duration
+	bucket   time.Duration
+	good     []float64
+	bad      []float64
+	index    int
+	start    time.Time
+}
+
+func newSLOWindow(duration time.Duration, now time.Time) *sloWindow {
+	bucket := duration / sloWindowBuckets
+
+	if bucket <= 0 {
+		bucket = duration
+	}
+
+	return &sloWindow{
+		duration: duration,
+		bucket:   bucket,
+		good:     make([]float64, sloWindowBuckets),
+		bad:      make([]float64, sloWindowBuckets),
+		start:    now.Truncate(bucket),
+	}
+}
+
+// advance moves current bucket to the one containing now, clearing expired buckets
+func (w *sloWindow) advance(now time.Time) {
+	steps := int(now.Sub(w.start) / w.bucket)
+
+	if steps <= 0 {
+		return
+	}
+
+	if steps > len(w.good) {
+		steps = len(w.good)
+	}
+
+	for i := 0; i < steps; i++ {
+		w.index = (w.index + 1) % len(w.good)
+		w.good[w.index], w.bad[w.index] = 0, 0
+	}
+
+	w.start = now.Truncate(w.bucket)
+}
+
+func (w *sloWindow) add(now time.Time, good bool) {
+	w.advance(now)
+
+	if good {
+		w.good[w.index]++
+		return
+	}
+
+	w.bad[w.index]++
+}
+
+// burnRate is a ratio of bad events rate to the error budget of the objective
+func (w *sloWindow) burnRate(now time.Time, objective float64) float64 {
+	w.advance(now)
+	good, bad := 0.0, 0.0
+
+	for i := range w.good {
+		good += w.good[i]
+		bad += w.bad[i]
+	}
+
+	if good+bad == 0 {
+		return 0
+	}
+
+	return bad / (good + bad) / (1 - objective)
+}
+
+type sloService struct {
+	objective float64
+	windows   []*sloWindow
+	updated   time.Time
+}
+
+// SLOTracker is a prometheus collector of per-service burn rates over sliding windows; values are computed on scrape
+type SLOTracker struct {
+	mu       sync.Mutex
+	services map[string]*sloService
+	now      func() time.Time
+
+	burnRate  *prometheus.Desc
+	objective *prometheus.Desc
+}
+
+// NewSLOTracker is a SLOTracker constructor
+func NewSLOTracker() *SLOTracker {
+	return &SLOTracker{
+		services: make(map[string]*sloService),
+		now:      time.Now,
+		burnRate: prometheus.NewDesc("slo_burn_rate",
+			"Ratio of bad requests rate within the window to the error budget of service objective",
+			[]string{"service", "window"}, nil),
+		objective: prometheus.NewDesc("slo_objective",
+			"Service level objective, target fraction of good requests", []string{"service"}, nil),
+	}
+}
+
+// Observe accounts good or bad event of the service; windows are recreated if service windows have changed
+func (tracker *SLOTracker) Observe(service string, objective float64, windows []time.Duration, good bool) {
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+
+	now := tracker.now()
+	state, ok := tracker.services[service]
+
+	if !ok || !sameWindows(state.windows, windows) {
+		state = &sloService{windows: make([]*sloWindow, 0, len(windows))}
+
+		for _, window := range windows {
+			state.windows = append(state.windows, newSLOWindow(window, now))
+		}
+
+		tracker.services[service] = state
+	}
+
+	state.objective = objective
+	state.updated = now
+
+	for _, window := range state.windows {
+		window.add(now, good)
+	}
+}
+
+// evict drops services that haven't been observed for ttl
+func (tracker *SLOTracker) evict(ttl time.Duration) {
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+
+	deadline := tracker.now().Add(-ttl)
+
+	for service, state := range tracker.services {
+		if !state.updated.After(deadline) {
+			delete(tracker.services, service)
+		}
+	}
+}
+
+// Describe implements prometheus.Collector
+func (tracker *SLOTracker) Describe(descs chan<- *prometheus.Desc) {
+	descs <- tracker.burnRate
+	descs <- tracker.objective
+}
+
+// Collect implements prometheus.Collector
+func (tracker *SLOTracker) Collect(metrics chan<- prometheus.Metric) {
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+
+	now := tracker.now()
+
+	for service, state := range tracker.services {
+		metrics <- prometheus.MustNewConstMetric(tracker.objective, prometheus.GaugeValue, state.objective, service)
+
+		for _, window := range state.windows {
+			metrics <- prometheus.MustNewConstMetric(tracker.burnRate, prometheus.GaugeValue,
+				window.burnRate(now, state.objective), service, model.Duration(window.duration).String())
+		}
+	}
+}
+
+func sameWindows(windows []*sloWindow, durations []time.Duration) bool {
+	if len(windows) != len(durations) {
+		return false
+	}
+
+	for i := range windows {
+		if windows[i].duration != durations[i] {
+			return false
+		}
+	}
+
+	return true
+}
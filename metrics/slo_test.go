@@ -0,0 +1,71 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSLOTracker(t *testing.T) {
+	now := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	tracker := NewSLOTracker()
+	tracker.now = func() time.Time { return now }
+	windows := []time.Duration{5 * time.Minute, time.Hour}
+
+	for i := 0; i < 6; i++ {
+		tracker.Observe("service", 0.75, windows, i%2 == 0)
+	}
+
+	expected := `
+# HELP slo_burn_rate Ratio of bad requests rate within the window to the error budget of service objective
+# TYPE slo_burn_rate gauge
+slo_burn_rate{service="service",window="1h"} 2
+slo_burn_rate{service="service",window="5m"} 2
+`
+	assert.NoError(t, testutil.CollectAndCompare(tracker, strings.NewReader(expected), "slo_burn_rate"))
+
+	// bad events are expired from short window only
+	now = now.Add(10 * time.Minute)
+
+	for i := 0; i < 6; i++ {
+		tracker.Observe("service", 0.75, windows, true)
+	}
+
+	expected = `
+# HELP slo_burn_rate Ratio of bad requests rate within the window to the error budget of service objective
+# TYPE slo_burn_rate gauge
+slo_burn_rate{service="service",window="1h"} 1
+slo_burn_rate{service="service",window="5m"} 0
+`
+	assert.NoError(t, testutil.CollectAndCompare(tracker, strings.NewReader(expected), "slo_burn_rate"))
+
+	// all events are expired
+	now = now.Add(2 * time.Hour)
+
+	expected = `
+# HELP slo_burn_rate Ratio of bad requests rate within the window to the error budget of service objective
+# TYPE slo_burn_rate gauge
+slo_burn_rate{service="service",window="1h"} 0
+slo_burn_rate{service="service",window="5m"} 0
+# HELP slo_objective Service level objective, target fraction of good requests
+# TYPE slo_objective gauge
+slo_objective{service="service"} 0.75
+`
+	assert.NoError(t, testutil.CollectAndCompare(tracker, strings.NewReader(expected)))
+}
+
+func TestSLOTrackerWindowsChange(t *testing.T) {
+	tracker := NewSLOTracker()
+	tracker.Observe("service", 0.9, []time.Duration{time.Hour}, false)
+	tracker.Observe("service", 0.9, []time.Duration{time.Minute}, true)
+
+	expected := `
+# HELP slo_burn_rate Ratio of bad requests rate within the window to the error budget of service objective
+# TYPE slo_burn_rate gauge
+slo_burn_rate{service="service",window="1m"} 0
+`
+	assert.NoError(t, testutil.CollectAndCompare(tracker, strings.NewReader(expected), "slo_burn_rate"))
+}
@@ -25,6 +25,8 @@ const (
 /* ContainerD related constants */
 const (
 	containerDLineGroupsCount = 4
+	containerDTagPartial      = "P"
+	containerDTagFull         = "F"
 	LogKeyTime                = "time"
 	LogKeyStream              = "stream"
 )
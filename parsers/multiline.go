@@ -0,0 +1,120 @@
+package parsers
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/2gis/loggo/common"
+)
+
+// LineContent returns user log content of the container engine line of the given format
+func LineContent(format string, line []byte) (string, error) {
+	switch format {
+	case common.CRITypeDocker:
+		var outer map[string]interface{}
+
+		if err := json.Unmarshal(line, &outer); err != nil {
+			return "", fmt.Errorf("outer json unmarshalling, %s", err)
+		}
+
+		content, ok := outer[LogKeyLog].(string)
+
+		if !ok {
+			return "", fmt.Errorf("%w: line '%s'", ErrLogFieldNotString, line)
+		}
+
+		return content, nil
+	case common.CRITypeContainerD, common.CRITypeCRIO:
+		output := r.FindSubmatch(line)
+
+		if len(output) != containerDLineGroupsCount {
+			return "", fmt.Errorf("unable to parse containerd line '%s'", line)
+		}
+
+		return string(output[3]), nil
+	}
+
+	return string(line), nil
+}
+
+// MergeLines joins user log contents of the lines into the single line of the given format;
+// container engine fields are taken from the first line
+func MergeLines(format string, lines [][]byte) ([]byte, error) {
+	if len(lines) == 1 {
+		return lines[0], nil
+	}
+
+	contents := make([]string, 0, len(lines))
+
+	for _, line := range lines {
+		content, err := LineContent(format, line)
+
+		if err != nil {
+			return nil, err
+		}
+
+		contents = append(contents, content)
+	}
+
+	switch format {
+	case common.CRITypeDocker:
+		var outer map[string]interface{}
+
+		if err := json.Unmarshal(lines[0], &outer); err != nil {
+			return nil, fmt.Errorf("outer json unmarshalling, %s", err)
+		}
+
+		// docker keeps line endings in log field
+		outer[LogKeyLog] = strings.Join(contents, "")
+		return json.Marshal(outer)
+	case common.CRITypeContainerD, common.CRITypeCRIO:
+		output := r.FindSubmatchIndex(lines[0])
+		merged := string(lines[0][:output[6]]) + strings.Join(contents, "\n")
+		return []byte(merged), nil
+	}
+
+	return []byte(strings.Join(contents, "\n")), nil
+}
+
+// PartialLine checks whether the line of the given format is the partial one; CRI runtimes split long lines
+// into partial lines followed by the full one
+func PartialLine(format string, line []byte) bool {
+	switch format {
+	case common.CRITypeContainerD, common.CRITypeCRIO:
+		output := rTag.FindSubmatch(line)
+		return output != nil && string(output[1]) == containerDTagPartial
+	}
+
+	return false
+}
+
+// JoinPartialLines joins partial lines along with the line completing them into the single full line;
+// container engine fields are taken from the first line
+func JoinPartialLines(format string, lines [][]byte) ([]byte, error) {
+	if len(lines) == 1 {
+		return lines[0], nil
+	}
+
+	contents := make([]string, 0, len(lines))
+
+	for _, line := range lines {
+		content, err := LineContent(format, line)
+
+		if err != nil {
+			return nil, err
+		}
+
+		contents = append(contents, content)
+	}
+
+	output := rTag.FindSubmatchIndex(lines[0])
+
+	if output == nil {
+		return nil, fmt.Errorf("unable to parse containerd line '%s'", lines[0])
+	}
+
+	joined := string(lines[0][:output[2]]) + containerDTagFull + string(lines[0][output[3]:output[1]]) +
+		strings.Join(contents, "")
+	return []byte(joined), nil
+}
@@ -0,0 +1,67 @@
+package parsers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/2gis/loggo/common"
+)
+
+func TestLineContent(t *testing.T) {
+	content, err := LineContent(common.CRITypeDocker, []byte(`{"log":"hello\n","stream":"stdout"}`))
+	assert.NoError(t, err)
+	assert.Equal(t, "hello\n", content)
+
+	content, err = LineContent(common.CRITypeContainerD, []byte(`2020-09-10T07:00:03.585507743Z stdout F hello`))
+	assert.NoError(t, err)
+	assert.Equal(t, "hello", content)
+
+	content, err = LineContent(common.CRITypeCRIO, []byte(`2020-09-10T10:00:03.585507743+03:00 stdout F hello`))
+	assert.NoError(t, err)
+	assert.Equal(t, "hello", content)
+
+	_, err = LineContent(common.CRITypeDocker, []byte(`hello`))
+	assert.Error(t, err)
+}
+
+func TestMergeLines(t *testing.T) {
+	merged, err := MergeLines(common.CRITypeDocker, [][]byte{
+		[]byte(`{"log":"panic: oops\n","stream":"stderr","time":"2020-09-10T07:00:03Z"}`),
+		[]byte(`{"log":"\tmain.go:10\n","stream":"stderr","time":"2020-09-10T07:00:04Z"}`),
+	})
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"log":"panic: oops\n\tmain.go:10\n","stream":"stderr","time":"2020-09-10T07:00:03Z"}`,
+		string(merged))
+
+	merged, err = MergeLines(common.CRITypeContainerD, [][]byte{
+		[]byte(`2020-09-10T07:00:03.585507743Z stderr F panic: oops`),
+		[]byte(`2020-09-10T07:00:03.585507744Z stderr F 	main.go:10`),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "2020-09-10T07:00:03.585507743Z stderr F panic: oops\n\tmain.go:10", string(merged))
+
+	_, err = MergeLines(common.CRITypeContainerD, [][]byte{[]byte(`first`), []byte(`second`)})
+	assert.Error(t, err)
+}
+
+func TestPartialLine(t *testing.T) {
+	assert.True(t, PartialLine(common.CRITypeContainerD, []byte(`2020-09-10T07:00:03.585507743Z stdout P hel`)))
+	assert.True(t, PartialLine(common.CRITypeCRIO, []byte(`2020-09-10T10:00:03.585507743+03:00 stdout P hel`)))
+	assert.False(t, PartialLine(common.CRITypeContainerD, []byte(`2020-09-10T07:00:03.585507743Z stdout F lo`)))
+	assert.False(t, PartialLine(common.CRITypeContainerD, []byte(`P hello`)))
+	assert.False(t, PartialLine(common.CRITypeDocker, []byte(`{"log":"hel","stream":"stdout"}`)))
+}
+
+func TestJoinPartialLines(t *testing.T) {
+	joined, err := JoinPartialLines(common.CRITypeContainerD, [][]byte{
+		[]byte(`2020-09-10T07:00:03.585507743Z stdout P {"msg":`),
+		[]byte(`2020-09-10T07:00:03.585507744Z stdout P "hello`),
+		[]byte(`2020-09-10T07:00:03.585507745Z stdout F  world"}`),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, `2020-09-10T07:00:03.585507743Z stdout F {"msg":"hello world"}`, string(joined))
+
+	_, err = JoinPartialLines(common.CRITypeContainerD, [][]byte{[]byte(`first`), []byte(`second`)})
+	assert.Error(t, err)
+}
@@ -10,11 +10,20 @@ import (
 
 var (
 	r = regexp.MustCompile("(?s)^(.+) (stdout|stderr) . (.*)$")
+	// rTag captures the tag telling whether the line is partial one
+	rTag = regexp.MustCompile("^\\S+ (?:stdout|stderr) ([PF]) ")
 )
 
 // CreateParserContainerDFormat returns containerd parser
-func CreateParserContainerDFormat(config configuration.ParserConfig) func(line []byte) (common.EntryMap, error) {
-	return func(line []byte) (common.EntryMap, error) {
+func CreateParserContainerDFormat(
+	config configuration.ParserConfig) func(line []byte, parseUserLog common.UserLogParser) (common.EntryMap, error) {
+	return createParserCRIFormat(config, func(time string) string { return time })
+}
+
+// createParserCRIFormat returns parser of CRI log lines, normalizeTime is applied to the line timestamp
+func createParserCRIFormat(config configuration.ParserConfig,
+	normalizeTime func(string) string) func(line []byte, parseUserLog common.UserLogParser) (common.EntryMap, error) {
+	return func(line []byte, parseUserLog common.UserLogParser) (common.EntryMap, error) {
 		lineString := string(line)
 		output := r.FindStringSubmatch(lineString)
 
@@ -24,10 +33,11 @@ func CreateParserContainerDFormat(config configuration.ParserConfig) func(line [
 
 		var outer = make(common.EntryMap)
 
-		setContainerDFields(outer, config.CRIFieldsKey, output[1], output[2])
+		setContainerDFields(outer, config.CRIFieldsKey, normalizeTime(output[1]), output[2])
 
 		if err := setLogFieldContent(
-			outer, config.UserLogFieldsKey, config.RawLogFieldKey, output[3], config.FlattenUserLog); err != nil {
+			outer, config.UserLogFieldsKey, config.RawLogFieldKey, output[3], config.FlattenUserLog,
+			parseUserLog); err != nil {
 			return nil, fmt.Errorf("error setting user log field: %w", err)
 		}
 
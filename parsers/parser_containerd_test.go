@@ -74,7 +74,7 @@ func TestParseContainerDFormat(t *testing.T) {
 
 		t.Run(testCase.name, func(t *testing.T) {
 			parser := CreateParserContainerDFormat(testCase.config)
-			out, err := parser([]byte(testCase.input))
+			out, err := parser([]byte(testCase.input), nil)
 			assert.Equal(t, testCase.entryMapExpected, out)
 
 			if testCase.errExpected != nil {
@@ -0,0 +1,26 @@
+package parsers
+
+import (
+	"time"
+
+	"github.com/2gis/loggo/common"
+	"github.com/2gis/loggo/configuration"
+)
+
+// CreateParserCRIOFormat returns CRI-O parser; CRI-O lines have the same format as containerd ones, but conmon writes
+// timestamps in node local time, they're converted to UTC to be consistent with containerd lines
+func CreateParserCRIOFormat(
+	config configuration.ParserConfig) func(line []byte, parseUserLog common.UserLogParser) (common.EntryMap, error) {
+	return createParserCRIFormat(config, timeUTC)
+}
+
+// timeUTC converts RFC3339 timestamp to UTC, unknown timestamps are kept as is
+func timeUTC(timestamp string) string {
+	value, err := time.Parse(time.RFC3339Nano, timestamp)
+
+	if err != nil {
+		return timestamp
+	}
+
+	return value.UTC().Format(time.RFC3339Nano)
+}
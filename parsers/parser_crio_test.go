@@ -0,0 +1,31 @@
+package parsers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/2gis/loggo/common"
+)
+
+func TestParseCRIOFormat(t *testing.T) {
+	parser := CreateParserCRIOFormat(configFlattenSubDict())
+
+	out, err := parser([]byte(`2020-09-10T10:00:03.585507743+03:00 stderr F {"hello":"world"}`), nil)
+	assert.NoError(t, err)
+	assert.Equal(t, common.EntryMap{
+		"log": common.EntryMap{"hello": "world"},
+		"cri": common.EntryMap{
+			"stream": "stderr",
+			"time":   "2020-09-10T07:00:03.585507743Z",
+		},
+	}, out)
+
+	out, err = parser([]byte(`{"log":"hello world"}`), nil)
+	assert.Error(t, err)
+	assert.Nil(t, out)
+
+	out, err = CreateParserCRIOFormat(configFlattenTopLevel())([]byte("2020-09-10T07:00:03.5Z stdout F message"), nil)
+	assert.NoError(t, err)
+	assert.Equal(t, common.EntryMap{"msg": "message", "stream": "stdout", "time": "2020-09-10T07:00:03.5Z"}, out)
+}
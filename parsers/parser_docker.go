@@ -14,8 +14,9 @@ var (
 	ErrLogFieldNotString = errors.New("user log field does not contain string")
 )
 
-func CreateParserDockerFormat(config configuration.ParserConfig) func(line []byte) (common.EntryMap, error) {
-	return func(line []byte) (common.EntryMap, error) {
+func CreateParserDockerFormat(
+	config configuration.ParserConfig) func(line []byte, parseUserLog common.UserLogParser) (common.EntryMap, error) {
+	return func(line []byte, parseUserLog common.UserLogParser) (common.EntryMap, error) {
 		var outer common.EntryMap
 
 		if err := json.Unmarshal(line, &outer); err != nil {
@@ -38,7 +39,8 @@ func CreateParserDockerFormat(config configuration.ParserConfig) func(line []byt
 		setDockerFields(outer, config.CRIFieldsKey)
 
 		if err := setLogFieldContent(
-			outer, config.UserLogFieldsKey, config.RawLogFieldKey, logFieldContentString, config.FlattenUserLog); err != nil {
+			outer, config.UserLogFieldsKey, config.RawLogFieldKey, logFieldContentString, config.FlattenUserLog,
+			parseUserLog); err != nil {
 			return nil, fmt.Errorf("error setting user log field: %w", err)
 		}
 
@@ -65,13 +67,15 @@ func setDockerFields(entryMap common.EntryMap, targetField string) {
 	entryMap[targetField] = dockerFields
 }
 
-func setLogFieldContent(entryMap common.EntryMap, userLogField, rawField, logFieldContent string, flatten bool) error {
-	var inner interface{}
+func setLogFieldContent(entryMap common.EntryMap, userLogField, rawField, logFieldContent string, flatten bool,
+	parseUserLog common.UserLogParser) error {
+	if parseUserLog == nil {
+		parseUserLog = ParseUserLogJSON
+	}
 
 	baseMap := selectBaseMap(entryMap, userLogField)
-	err := json.Unmarshal([]byte(logFieldContent), &inner)
-	innerMap, ok := inner.(map[string]interface{})
-	if err != nil || !ok {
+	innerMap, err := parseUserLog(logFieldContent)
+	if err != nil {
 		baseMap[rawField] = logFieldContent
 		return nil
 	}
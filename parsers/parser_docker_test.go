@@ -203,7 +203,7 @@ func TestParseLineBasic(t *testing.T) {
 		t.Run(testCase.name, func(t *testing.T) {
 			parser := CreateParserDockerFormat(configFlattenTopLevel())
 
-			out, err := parser([]byte(testCase.input))
+			out, err := parser([]byte(testCase.input), nil)
 			assert.Equal(t, testCase.entryMapExpected, out)
 
 			if testCase.errExpected != nil {
@@ -221,7 +221,7 @@ func TestParseLineNginxTransforms(t *testing.T) {
 		t.Run(testCase.name, func(t *testing.T) {
 			parser := CreateParserDockerFormat(configFlattenTopLevel())
 
-			out, err := parser([]byte(testCase.input))
+			out, err := parser([]byte(testCase.input), nil)
 			assert.Equal(t, testCase.entryMapExpected, out)
 
 			if testCase.errExpected != nil {
@@ -239,7 +239,7 @@ func TestSeparateDockerUserLogFieldsSet(t *testing.T) {
 		t.Run(testCase.name, func(t *testing.T) {
 			parser := CreateParserDockerFormat(testCase.config)
 
-			out, err := parser([]byte(testCase.input))
+			out, err := parser([]byte(testCase.input), nil)
 			assert.Equal(t, testCase.entryMapExpected, out)
 
 			if testCase.errExpected != nil {
@@ -257,7 +257,7 @@ func TestParserDockerUnpackingControl(t *testing.T) {
 		t.Run(testCase.name, func(t *testing.T) {
 			parser := CreateParserDockerFormat(testCase.config)
 
-			out, err := parser([]byte(testCase.input))
+			out, err := parser([]byte(testCase.input), nil)
 			assert.Equal(t, testCase.entryMapExpected, out)
 
 			if testCase.errExpected != nil {
@@ -0,0 +1,24 @@
+package parsers
+
+import (
+	"fmt"
+
+	"github.com/2gis/loggo/common"
+	"github.com/2gis/loggo/configuration"
+)
+
+// CreateParserFileFormat returns host log file parser; the whole line is user log
+func CreateParserFileFormat(
+	config configuration.ParserConfig) func(line []byte, parseUserLog common.UserLogParser) (common.EntryMap, error) {
+	return func(line []byte, parseUserLog common.UserLogParser) (common.EntryMap, error) {
+		var outer = make(common.EntryMap)
+
+		if err := setLogFieldContent(
+			outer, config.UserLogFieldsKey, config.RawLogFieldKey, string(line), config.FlattenUserLog,
+			parseUserLog); err != nil {
+			return nil, fmt.Errorf("error setting user log field: %w", err)
+		}
+
+		return outer, nil
+	}
+}
@@ -0,0 +1,31 @@
+package parsers
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/2gis/loggo/common"
+)
+
+func TestParseFileFormat(t *testing.T) {
+	parser := CreateParserFileFormat(configFlattenSubDict())
+
+	out, err := parser([]byte(`{"hello":"world","a": 1}`), nil)
+	assert.NoError(t, err)
+	assert.Equal(t, common.EntryMap{"log": common.EntryMap{"hello": "world", "a": float64(1)}}, out)
+
+	out, err = parser([]byte("type=USER_LOGIN msg=audit(1610000000.123:42): res=success"), nil)
+	assert.NoError(t, err)
+	assert.Equal(t, common.EntryMap{
+		"log": common.EntryMap{"msg": "type=USER_LOGIN msg=audit(1610000000.123:42): res=success"},
+	}, out)
+
+	out, err = parser(
+		[]byte("type=USER_LOGIN msg=audit(1610000000.123:42): res=success"),
+		CreateParserUserLogRegexp(regexp.MustCompile(`^type=(?P<audit_type>\S+) (.*)$`)),
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, "USER_LOGIN", out["log"].(common.EntryMap)["audit_type"])
+}
@@ -2,7 +2,9 @@ package parsers
 
 import (
 	"fmt"
+	"net"
 	"strconv"
+	"time"
 
 	"github.com/pkg/errors"
 
@@ -23,8 +25,12 @@ type ServiceProvider interface {
 type MetricsCollector interface {
 	IncrementHTTPRequestCount(podName, method, service, path string, status int)
 	IncrementHTTPRequestsTotalCount(service string)
-	ObserveHTTPRequestTime(podName, method, service, path string, value float64)
-	ObserveHTTPUpstreamResponseTimeTotal(podName, method, service, path string, value float64)
+	ObserveHTTPRequestTime(
+		podName, method, service, path string, value float64, buckets []float64, exemplar map[string]string)
+	ObserveHTTPUpstreamResponseTimeTotal(
+		podName, method, service, path string, value float64, buckets []float64, exemplar map[string]string)
+	ObserveSLOEvent(service string, objective float64, windows []time.Duration, good bool)
+	IncrementHTTPRequestSeriesOverflowCount(service string)
 }
 
 // SLIMessage is a structure for storage the parsed message from MQ
@@ -38,25 +44,33 @@ type SLIMessage struct {
 	Status                    int
 	RequestTime               float64
 	UpstreamResponseTimeTotal *float64
+
+	// Exemplar contains request or trace id label, if found
+	Exemplar map[string]string
 }
 
 // SLI parser modifies metrics according to data received from serviceProvider
 type SLI struct {
 	serviceProvider  ServiceProvider
 	metricsCollector MetricsCollector
+	mapping          SLIFieldMapping
+	cardinalityGuard *SLICardinalityGuard
 }
 
 // NewParserSLI is a constructor for ParserSLI
-func NewParserSLI(provider ServiceProvider, collector MetricsCollector) *SLI {
+func NewParserSLI(provider ServiceProvider, collector MetricsCollector, mapping SLIFieldMapping,
+	guard *SLICardinalityGuard) *SLI {
 	return &SLI{
 		serviceProvider:  provider,
 		metricsCollector: collector,
+		mapping:          mapping,
+		cardinalityGuard: guard,
 	}
 }
 
 // Parse is an interface function to process EntryMap by convention
 func (parser *SLI) Parse(entryMap common.EntryMap) {
-	slaMessage, err := newSLIMessage(entryMap)
+	slaMessage, err := newSLIMessage(entryMap, parser.mapping)
 
 	if err != nil {
 		return
@@ -76,6 +90,12 @@ func (parser *SLI) Parse(entryMap common.EntryMap) {
 		return
 	}
 
+	// status isn't guarded, as its values are bounded by the protocol
+	if !parser.cardinalityGuard.Allow(service.Name, slaMessage.Method, pathLabel, slaMessage.PodName) {
+		parser.metricsCollector.IncrementHTTPRequestSeriesOverflowCount(service.Name)
+		slaMessage.Method, slaMessage.PodName, pathLabel = SLILabelOther, SLILabelOther, SLILabelOther
+	}
+
 	parser.metricsCollector.IncrementHTTPRequestCount(
 		slaMessage.PodName,
 		slaMessage.Method,
@@ -89,8 +109,15 @@ func (parser *SLI) Parse(entryMap common.EntryMap) {
 		service.Name,
 		pathLabel,
 		slaMessage.RequestTime,
+		service.Buckets,
+		slaMessage.Exemplar,
 	)
 
+	if service.SLO != nil {
+		parser.metricsCollector.ObserveSLOEvent(service.Name, service.SLO.Objective, service.SLO.Windows,
+			service.SLO.Good(slaMessage.Status, slaMessage.RequestTime))
+	}
+
 	if slaMessage.UpstreamResponseTimeTotal == nil {
 		return
 	}
@@ -101,68 +128,120 @@ func (parser *SLI) Parse(entryMap common.EntryMap) {
 		service.Name,
 		pathLabel,
 		*slaMessage.UpstreamResponseTimeTotal,
+		service.Buckets,
+		slaMessage.Exemplar,
 	)
 }
 
-// newSLIMessage tries to check if entry map contains service level indicator fields
+// newSLIMessage tries to check if entry map contains service level indicator fields of the mapping
 // type assertions are not so clean and short as previous version's full conversions, but thrice as faster
-func newSLIMessage(entryMap common.EntryMap) (sliMessage SLIMessage, err error) {
+func newSLIMessage(entryMap common.EntryMap, mapping SLIFieldMapping) (sliMessage SLIMessage, err error) {
 	// the SLA flag is used to separate the sla messages from regular logs by convention
-	slaFlag, _ := entryMap[LogKeySLA].(bool)
+	if mapping.Flag != "" {
+		slaFlag, _ := entryMap[mapping.Flag].(bool)
 
-	if !slaFlag {
-		return SLIMessage{}, ErrConstructSLI
+		if !slaFlag {
+			return SLIMessage{}, ErrConstructSLI
+		}
 	}
 
-	host, _ := entryMap[LogKeyHost].(string)
+	host := lookupString(entryMap, mapping.Host)
 
 	if host == "" {
 		return SLIMessage{}, ErrConstructSLI
 	}
 
-	method, _ := entryMap[LogKeyRequestMethod].(string)
+	// authority of proxies access logs may contain port
+	if hostname, _, err := net.SplitHostPort(host); err == nil {
+		host = hostname
+	}
+
+	method := lookupString(entryMap, mapping.Method)
 
 	if method == "" {
 		return SLIMessage{}, ErrConstructSLI
 	}
 
-	uri, _ := entryMap[LogKeyRequestURI].(string)
+	uri := lookupString(entryMap, mapping.URI)
 
 	if uri == "" {
 		return SLIMessage{}, ErrConstructSLI
 	}
 
-	requestTime, err := strconv.ParseFloat(
-		fmt.Sprintf("%v", entryMap[LogKeyRequestTime]), 64,
-	)
+	requestTime, ok := lookupFloat(entryMap, mapping.RequestTime)
 
-	if err != nil {
+	if !ok {
 		return SLIMessage{}, ErrConstructSLI
 	}
 
+	statusValue, _ := entryMap.Lookup(mapping.Status)
 	status, err := strconv.ParseInt(
-		fmt.Sprintf("%v", entryMap[LogKeyStatus]), 10, 64,
+		fmt.Sprintf("%v", statusValue), 10, 64,
 	)
 
 	if err != nil {
 		return SLIMessage{}, ErrConstructSLI
 	}
 
-	podName, _ := entryMap[LogKeyUpstreamPodName].(string)
 	sliMessage = SLIMessage{
 		Host:        host,
-		PodName:     podName,
+		PodName:     lookupString(entryMap, mapping.UpstreamHost),
 		Method:      method,
 		URI:         uri,
 		Status:      int(status),
-		RequestTime: requestTime,
+		RequestTime: requestTime * mapping.RequestTimeMultiplier,
 	}
 
-	if value, ok := entryMap[LogKeyUpstreamResponseTimeTotal]; ok {
-		if upstreamResponseTimeTotal, ok := value.(float64); ok {
-			sliMessage.UpstreamResponseTimeTotal = &upstreamResponseTimeTotal
+	for _, field := range mapping.Exemplars {
+		if value := lookupString(entryMap, field); value != "" {
+			sliMessage.Exemplar = map[string]string{exemplarLabel(field): value}
+			break
 		}
 	}
 
+	if mapping.UpstreamResponseTime == "" {
+		return sliMessage, nil
+	}
+
+	// negative values are used by proxies for requests that haven't reached upstream
+	if upstreamResponseTimeTotal, ok := lookupFloat(entryMap, mapping.UpstreamResponseTime); ok &&
+		upstreamResponseTimeTotal >= 0 {
+		upstreamResponseTimeTotal *= mapping.UpstreamResponseTimeMultiplier
+		sliMessage.UpstreamResponseTimeTotal = &upstreamResponseTimeTotal
+	}
+
 	return sliMessage, nil
 }
+
+func lookupString(entryMap common.EntryMap, path string) string {
+	if path == "" {
+		return ""
+	}
+
+	value, _ := entryMap.Lookup(path)
+	result, _ := value.(string)
+	return result
+}
+
+// lookupFloat returns number of numeric or string field, as access logs of different proxies use both
+func lookupFloat(entryMap common.EntryMap, path string) (float64, bool) {
+	value, ok := entryMap.Lookup(path)
+
+	if !ok {
+		return 0, false
+	}
+
+	switch typed := value.(type) {
+	case float64:
+		return typed, true
+	case int:
+		return float64(typed), true
+	case int64:
+		return float64(typed), true
+	case string:
+		result, err := strconv.ParseFloat(typed, 64)
+		return result, err == nil
+	}
+
+	return 0, false
+}
@@ -20,7 +20,7 @@ func TestParseCorrectSLAMessage(t *testing.T) {
 		LogKeyUpstreamResponseTimeTotal:       10.2,
 	}
 
-	message, err := newSLIMessage(source)
+	message, err := newSLIMessage(source, sliPresets[SLIPresetNginx])
 	assert.NoError(t, err)
 	assert.Equal(t, getStubMessage("/hello", ""), message)
 }
@@ -37,7 +37,7 @@ func TestParseCorrectSLAMessageWithQueryParams(t *testing.T) {
 		LogKeyUpstreamResponseTimeTotal:       10.2,
 	}
 
-	message, err := newSLIMessage(source)
+	message, err := newSLIMessage(source, sliPresets[SLIPresetNginx])
 	assert.NoError(t, err)
 	assert.Equal(t, getStubMessage("/hello?filter=test", ""), message)
 }
@@ -55,7 +55,7 @@ func TestParseMessageWithUpstreamPodName(t *testing.T) {
 		LogKeyUpstreamResponseTimeTotal:       10.2,
 	}
 
-	message, err := newSLIMessage(source)
+	message, err := newSLIMessage(source, sliPresets[SLIPresetNginx])
 	assert.NoError(t, err)
 	assert.Equal(t, getStubMessage("/hello?filter=test", "podname_0"), message)
 }
@@ -70,7 +70,7 @@ func TestParseMessageWithIncorrectRequest(t *testing.T) {
 		LogKeyUpstreamResponseTimeReplacement: 10.2,
 		LogKeyUpstreamResponseTimeTotal:       10.2,
 	}
-	_, err := newSLIMessage(source)
+	_, err := newSLIMessage(source, sliPresets[SLIPresetNginx])
 	assert.Error(t, err)
 
 	source = common.EntryMap{
@@ -81,7 +81,7 @@ func TestParseMessageWithIncorrectRequest(t *testing.T) {
 		LogKeyUpstreamResponseTimeReplacement: 10.2,
 		LogKeyUpstreamResponseTimeTotal:       10.2,
 	}
-	_, err = newSLIMessage(source)
+	_, err = newSLIMessage(source, sliPresets[SLIPresetNginx])
 	assert.Error(t, err)
 
 	source = common.EntryMap{
@@ -92,7 +92,7 @@ func TestParseMessageWithIncorrectRequest(t *testing.T) {
 		LogKeyUpstreamResponseTimeReplacement: 10.2,
 		LogKeyUpstreamResponseTimeTotal:       10.2,
 	}
-	_, err = newSLIMessage(source)
+	_, err = newSLIMessage(source, sliPresets[SLIPresetNginx])
 	assert.Error(t, err)
 }
 
@@ -0,0 +1,106 @@
+package parsers
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/2gis/loggo/logging"
+)
+
+// SLILabelOther replaces high cardinality labels of series exceeding the limits
+const SLILabelOther = "__other__"
+
+// SLICardinalityGuard limits count of distinct SLI label sets per service and in total; label sets not seen for ttl
+// are forgotten on Retrieve, the same way stale metrics series are deleted
+type SLICardinalityGuard struct {
+	mu           sync.Mutex
+	serviceLimit int
+	globalLimit  int
+	ttl          time.Duration
+	now          func() time.Time
+	logger       logging.Logger
+
+	series     map[string]map[string]time.Time
+	total      int
+	overflowed map[string]bool
+}
+
+// NewSLICardinalityGuard is a SLICardinalityGuard constructor; zero limit means no limit
+func NewSLICardinalityGuard(
+	serviceLimit, globalLimit int, ttl time.Duration, logger logging.Logger) *SLICardinalityGuard {
+	return &SLICardinalityGuard{
+		serviceLimit: serviceLimit,
+		globalLimit:  globalLimit,
+		ttl:          ttl,
+		now:          time.Now,
+		logger:       logger,
+		series:       make(map[string]map[string]time.Time),
+		overflowed:   make(map[string]bool),
+	}
+}
+
+// Allow checks if label set of the service is known or fits into the limits
+func (guard *SLICardinalityGuard) Allow(service string, labelValues ...string) bool {
+	key := strings.Join(labelValues, "\xff")
+
+	guard.mu.Lock()
+	defer guard.mu.Unlock()
+
+	series, ok := guard.series[service]
+
+	if ok {
+		if _, ok := series[key]; ok {
+			series[key] = guard.now()
+			return true
+		}
+	}
+
+	if (guard.serviceLimit > 0 && len(series) >= guard.serviceLimit) ||
+		(guard.globalLimit > 0 && guard.total >= guard.globalLimit) {
+		if !guard.overflowed[service] {
+			guard.overflowed[service] = true
+			guard.logger.Warnf("SLI series limit (per service %d, total %d) exceeded by service '%s', "+
+				"label values '%s' are folded to '%s'", guard.serviceLimit, guard.globalLimit, service,
+				strings.Join(labelValues, "', '"), SLILabelOther)
+		}
+
+		return false
+	}
+
+	if !ok {
+		series = make(map[string]time.Time)
+		guard.series[service] = series
+	}
+
+	series[key] = guard.now()
+	guard.total++
+	return true
+}
+
+// Retrieve forgets label sets that haven't been seen for ttl; warnings are logged again for services still
+// exceeding the limits
+func (guard *SLICardinalityGuard) Retrieve() error {
+	guard.mu.Lock()
+	defer guard.mu.Unlock()
+
+	deadline := guard.now().Add(-guard.ttl)
+
+	for service, series := range guard.series {
+		for key, seen := range series {
+			if seen.After(deadline) {
+				continue
+			}
+
+			delete(series, key)
+			guard.total--
+		}
+
+		if len(series) == 0 {
+			delete(guard.series, service)
+		}
+	}
+
+	guard.overflowed = make(map[string]bool)
+	return nil
+}
@@ -0,0 +1,49 @@
+package parsers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/2gis/loggo/logging"
+)
+
+func TestSLICardinalityGuardServiceLimit(t *testing.T) {
+	guard := NewSLICardinalityGuard(2, 0, time.Hour, logging.NewLoggerDefault())
+
+	assert.True(t, guard.Allow("first", "GET", "/a", "pod-1"))
+	assert.True(t, guard.Allow("first", "GET", "/a", "pod-2"))
+	assert.False(t, guard.Allow("first", "GET", "/a", "pod-3"))
+	// known label sets are still allowed
+	assert.True(t, guard.Allow("first", "GET", "/a", "pod-1"))
+	assert.True(t, guard.Allow("second", "GET", "/a", "pod-3"))
+}
+
+func TestSLICardinalityGuardGlobalLimit(t *testing.T) {
+	guard := NewSLICardinalityGuard(0, 2, time.Hour, logging.NewLoggerDefault())
+
+	assert.True(t, guard.Allow("first", "GET", "/a", "pod-1"))
+	assert.True(t, guard.Allow("second", "GET", "/a", "pod-1"))
+	assert.False(t, guard.Allow("third", "GET", "/a", "pod-1"))
+}
+
+func TestSLICardinalityGuardRetrieve(t *testing.T) {
+	now := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	guard := NewSLICardinalityGuard(1, 1, time.Hour, logging.NewLoggerDefault())
+	guard.now = func() time.Time { return now }
+
+	assert.True(t, guard.Allow("first", "GET", "/a", "pod-1"))
+	assert.False(t, guard.Allow("first", "GET", "/a", "pod-2"))
+	assert.True(t, guard.overflowed["first"])
+
+	now = now.Add(30 * time.Minute)
+	assert.NoError(t, guard.Retrieve())
+	assert.False(t, guard.Allow("second", "GET", "/a", "pod-1"))
+
+	now = now.Add(time.Hour)
+	assert.NoError(t, guard.Retrieve())
+	assert.Equal(t, 0, guard.total)
+	assert.Empty(t, guard.series)
+	assert.True(t, guard.Allow("second", "GET", "/a", "pod-1"))
+}
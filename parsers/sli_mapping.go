@@ -0,0 +1,208 @@
+package parsers
+
+import (
+	"fmt"
+	"strings"
+)
+
+/* SLI field mapping presets */
+const (
+	SLIPresetNginx   = "nginx"
+	SLIPresetEnvoy   = "envoy"
+	SLIPresetTraefik = "traefik"
+	SLIPresetHAProxy = "haproxy"
+)
+
+/* SLI field mapping keys, used in overrides */
+const (
+	sliMappingFlag                     = "flag"
+	sliMappingHost                     = "host"
+	sliMappingMethod                   = "method"
+	sliMappingURI                      = "uri"
+	sliMappingStatus                   = "status"
+	sliMappingRequestTime              = "request_time"
+	sliMappingRequestTimeUnit          = "request_time_unit"
+	sliMappingUpstreamHost             = "upstream_host"
+	sliMappingUpstreamResponseTime     = "upstream_response_time"
+	sliMappingUpstreamResponseTimeUnit = "upstream_response_time_unit"
+	sliMappingExemplar                 = "exemplar"
+)
+
+// durationUnits are multipliers converting durations to seconds
+var durationUnits = map[string]float64{
+	"s":  1,
+	"ms": 1e-3,
+	"us": 1e-6,
+	"ns": 1e-9,
+}
+
+// SLIFieldMapping describes which log fields contain service level indicator values; durations are converted to
+// seconds with the multipliers
+type SLIFieldMapping struct {
+	// Flag is a bool field marking SLI entries; when empty, every entry having required fields is an SLI entry
+	Flag string
+
+	Host        string
+	Method      string
+	URI         string
+	Status      string
+	RequestTime string
+
+	UpstreamHost         string
+	UpstreamResponseTime string
+
+	RequestTimeMultiplier          float64
+	UpstreamResponseTimeMultiplier float64
+
+	// Exemplars are fields with request or trace id to attach to latency observations, the first present one is used
+	Exemplars []string
+}
+
+var sliPresets = map[string]SLIFieldMapping{
+	SLIPresetNginx: {
+		Flag:                           LogKeySLA,
+		Host:                           LogKeyHost,
+		Method:                         LogKeyRequestMethod,
+		URI:                            LogKeyRequestURI,
+		Status:                         LogKeyStatus,
+		RequestTime:                    LogKeyRequestTime,
+		UpstreamHost:                   LogKeyUpstreamPodName,
+		UpstreamResponseTime:           LogKeyUpstreamResponseTimeTotal,
+		RequestTimeMultiplier:          durationUnits["s"],
+		UpstreamResponseTimeMultiplier: durationUnits["s"],
+		Exemplars:                      []string{"trace_id", "request_id"},
+	},
+	// envoy json access log with field names of the default format
+	SLIPresetEnvoy: {
+		Host:                           "authority",
+		Method:                         "method",
+		URI:                            "path",
+		Status:                         "response_code",
+		RequestTime:                    "duration",
+		UpstreamHost:                   "upstream_host",
+		UpstreamResponseTime:           "upstream_service_time",
+		RequestTimeMultiplier:          durationUnits["ms"],
+		UpstreamResponseTimeMultiplier: durationUnits["ms"],
+		Exemplars:                      []string{"trace_id", "request_id"},
+	},
+	// traefik json access log, durations are in nanoseconds
+	SLIPresetTraefik: {
+		Host:                           "RequestHost",
+		Method:                         "RequestMethod",
+		URI:                            "RequestPath",
+		Status:                         "DownstreamStatus",
+		RequestTime:                    "Duration",
+		UpstreamHost:                   "ServiceAddr",
+		UpstreamResponseTime:           "OriginDuration",
+		RequestTimeMultiplier:          durationUnits["ns"],
+		UpstreamResponseTimeMultiplier: durationUnits["ns"],
+		Exemplars:                      []string{"trace_id", "request_X-Request-Id"},
+	},
+	// haproxy json log-format with fields named after log variables: %Ta, %Tr, %s, %HM, %HU, %ST
+	// and captured host header
+	SLIPresetHAProxy: {
+		Host:                           "host",
+		Method:                         "http_method",
+		URI:                            "http_uri",
+		Status:                         "status_code",
+		RequestTime:                    "active_time",
+		UpstreamHost:                   "server_name",
+		UpstreamResponseTime:           "response_time",
+		RequestTimeMultiplier:          durationUnits["ms"],
+		UpstreamResponseTimeMultiplier: durationUnits["ms"],
+		Exemplars:                      []string{"trace_id", "unique_id"},
+	},
+}
+
+// NewSLIFieldMapping returns mapping of the preset with overrides applied; overrides is a comma-separated list of
+// key=field pairs, where key is one of flag, host, method, uri, status, request_time, upstream_host,
+// upstream_response_time, exemplar, or key=unit pairs for request_time_unit and upstream_response_time_unit
+// (s, ms, us, ns)
+func NewSLIFieldMapping(preset, overrides string) (SLIFieldMapping, error) {
+	mapping, ok := sliPresets[preset]
+
+	if !ok {
+		return SLIFieldMapping{}, fmt.Errorf("unknown SLI preset '%s'", preset)
+	}
+
+	for _, override := range strings.Split(overrides, ",") {
+		override = strings.TrimSpace(override)
+
+		if override == "" {
+			continue
+		}
+
+		parts := strings.SplitN(override, "=", 2)
+
+		if len(parts) != 2 {
+			return SLIFieldMapping{}, fmt.Errorf("SLI field override '%s' must be in key=value form", override)
+		}
+
+		key, value := strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1])
+
+		if err := mapping.set(key, value); err != nil {
+			return SLIFieldMapping{}, err
+		}
+	}
+
+	if mapping.Host == "" || mapping.Method == "" || mapping.URI == "" || mapping.Status == "" ||
+		mapping.RequestTime == "" {
+		return SLIFieldMapping{}, fmt.Errorf("SLI fields host, method, uri, status and request_time must be mapped")
+	}
+
+	return mapping, nil
+}
+
+// exemplarLabel makes valid label name of the field
+func exemplarLabel(field string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '_' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			return r
+		}
+
+		return '_'
+	}, field)
+}
+
+func (mapping *SLIFieldMapping) set(key, value string) error {
+	switch key {
+	case sliMappingFlag:
+		mapping.Flag = value
+	case sliMappingHost:
+		mapping.Host = value
+	case sliMappingMethod:
+		mapping.Method = value
+	case sliMappingURI:
+		mapping.URI = value
+	case sliMappingStatus:
+		mapping.Status = value
+	case sliMappingRequestTime:
+		mapping.RequestTime = value
+	case sliMappingUpstreamHost:
+		mapping.UpstreamHost = value
+	case sliMappingUpstreamResponseTime:
+		mapping.UpstreamResponseTime = value
+	case sliMappingExemplar:
+		mapping.Exemplars = nil
+
+		if value != "" {
+			mapping.Exemplars = []string{value}
+		}
+	case sliMappingRequestTimeUnit, sliMappingUpstreamResponseTimeUnit:
+		multiplier, ok := durationUnits[value]
+
+		if !ok {
+			return fmt.Errorf("unknown duration unit '%s' for SLI field '%s'", value, key)
+		}
+
+		if key == sliMappingRequestTimeUnit {
+			mapping.RequestTimeMultiplier = multiplier
+		} else {
+			mapping.UpstreamResponseTimeMultiplier = multiplier
+		}
+	default:
+		return fmt.Errorf("unknown SLI field key '%s'", key)
+	}
+
+	return nil
+}
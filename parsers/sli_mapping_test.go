@@ -0,0 +1,142 @@
+package parsers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/2gis/loggo/common"
+)
+
+func TestNewSLIFieldMapping(t *testing.T) {
+	mapping, err := NewSLIFieldMapping(SLIPresetNginx, "")
+	assert.NoError(t, err)
+	assert.Equal(t, sliPresets[SLIPresetNginx], mapping)
+
+	mapping, err = NewSLIFieldMapping(SLIPresetEnvoy, "host=x-forwarded-host, request_time_unit=s, flag=access")
+	assert.NoError(t, err)
+	assert.Equal(t, "x-forwarded-host", mapping.Host)
+	assert.Equal(t, "access", mapping.Flag)
+	assert.Equal(t, 1.0, mapping.RequestTimeMultiplier)
+	assert.Equal(t, 1e-3, mapping.UpstreamResponseTimeMultiplier)
+
+	for _, testCase := range []struct {
+		preset    string
+		overrides string
+	}{
+		{"apache", ""},
+		{SLIPresetEnvoy, "host"},
+		{SLIPresetEnvoy, "unknown=field"},
+		{SLIPresetEnvoy, "request_time_unit=m"},
+		{SLIPresetEnvoy, "uri="},
+	} {
+		_, err = NewSLIFieldMapping(testCase.preset, testCase.overrides)
+		assert.Error(t, err, testCase)
+	}
+}
+
+func TestNewSLIMessagePresets(t *testing.T) {
+	upstreamResponseTime := 0.012
+
+	for _, testCase := range []struct {
+		preset   string
+		source   common.EntryMap
+		expected SLIMessage
+	}{
+		{
+			SLIPresetEnvoy,
+			common.EntryMap{
+				"authority": "test.local:8080", "method": "GET", "path": "/hello?a=b", "response_code": 200.0,
+				"duration": 25.0, "upstream_host": "10.0.0.1:8080", "upstream_service_time": "12",
+			},
+			SLIMessage{
+				Host: "test.local", PodName: "10.0.0.1:8080", Method: "GET", URI: "/hello?a=b", Status: 200,
+				RequestTime: 0.025, UpstreamResponseTimeTotal: &upstreamResponseTime,
+			},
+		},
+		{
+			SLIPresetTraefik,
+			common.EntryMap{
+				"RequestHost": "test.local", "RequestMethod": "POST", "RequestPath": "/hello",
+				"DownstreamStatus": 502.0, "Duration": 25000000.0, "ServiceAddr": "10.0.0.1:80",
+				"OriginDuration": 12000000.0,
+			},
+			SLIMessage{
+				Host: "test.local", PodName: "10.0.0.1:80", Method: "POST", URI: "/hello", Status: 502,
+				RequestTime: 0.025, UpstreamResponseTimeTotal: &upstreamResponseTime,
+			},
+		},
+		{
+			SLIPresetHAProxy,
+			common.EntryMap{
+				"host": "test.local", "http_method": "GET", "http_uri": "/hello", "status_code": "404",
+				"active_time": "25", "server_name": "backend-1", "response_time": "-1",
+			},
+			SLIMessage{
+				Host: "test.local", PodName: "backend-1", Method: "GET", URI: "/hello", Status: 404,
+				RequestTime: 0.025, UpstreamResponseTimeTotal: nil,
+			},
+		},
+	} {
+		mapping, err := NewSLIFieldMapping(testCase.preset, "")
+		assert.NoError(t, err)
+
+		message, err := newSLIMessage(testCase.source, mapping)
+		assert.NoError(t, err, testCase.preset)
+		assert.Equal(t, testCase.expected.Host, message.Host, testCase.preset)
+		assert.Equal(t, testCase.expected.PodName, message.PodName, testCase.preset)
+		assert.Equal(t, testCase.expected.Method, message.Method, testCase.preset)
+		assert.Equal(t, testCase.expected.URI, message.URI, testCase.preset)
+		assert.Equal(t, testCase.expected.Status, message.Status, testCase.preset)
+		assert.InDelta(t, testCase.expected.RequestTime, message.RequestTime, 1e-9, testCase.preset)
+
+		if testCase.expected.UpstreamResponseTimeTotal == nil {
+			assert.Nil(t, message.UpstreamResponseTimeTotal, testCase.preset)
+			continue
+		}
+
+		if assert.NotNil(t, message.UpstreamResponseTimeTotal, testCase.preset) {
+			assert.InDelta(t, *testCase.expected.UpstreamResponseTimeTotal, *message.UpstreamResponseTimeTotal, 1e-9)
+		}
+	}
+}
+
+func TestNewSLIMessagePresetsMissingFields(t *testing.T) {
+	mapping, _ := NewSLIFieldMapping(SLIPresetEnvoy, "")
+	_, err := newSLIMessage(common.EntryMap{"authority": "test.local", "method": "GET", "path": "/"}, mapping)
+	assert.Error(t, err)
+}
+
+func TestNewSLIMessageExemplar(t *testing.T) {
+	source := common.EntryMap{
+		LogKeySLA:           true,
+		LogKeyRequestMethod: "GET",
+		LogKeyRequestURI:    "/hello",
+		LogKeyHost:          "test.local",
+		LogKeyStatus:        200,
+		LogKeyRequestTime:   0.1,
+		"request_id":        "d010f6631c81407596a7ff19aaf6312b",
+	}
+
+	message, err := newSLIMessage(source, sliPresets[SLIPresetNginx])
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"request_id": "d010f6631c81407596a7ff19aaf6312b"}, message.Exemplar)
+
+	source["trace_id"] = "4bf92f3577b34da6a3ce929d0e0e4736"
+	message, err = newSLIMessage(source, sliPresets[SLIPresetNginx])
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"trace_id": "4bf92f3577b34da6a3ce929d0e0e4736"}, message.Exemplar)
+
+	mapping, err := NewSLIFieldMapping(SLIPresetNginx, "exemplar=x-request-id")
+	assert.NoError(t, err)
+	source["x-request-id"] = "abc"
+	message, err = newSLIMessage(source, mapping)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"x_request_id": "abc"}, message.Exemplar)
+
+	mapping, err = NewSLIFieldMapping(SLIPresetNginx, "exemplar=")
+	assert.NoError(t, err)
+	message, err = newSLIMessage(source, mapping)
+	assert.NoError(t, err)
+	assert.Nil(t, message.Exemplar)
+}
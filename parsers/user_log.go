@@ -0,0 +1,167 @@
+package parsers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/2gis/loggo/common"
+)
+
+var (
+	ErrUserLogNotMap         = errors.New("user log is not a json map")
+	ErrUserLogNoPairs        = errors.New("user log does not contain key=value pairs")
+	ErrUserLogNotMatched     = errors.New("user log does not match regular expression")
+	ErrUserLogParserUnknown  = errors.New("unknown user log parser")
+	ErrUserLogRegexpRequired = errors.New("regular expression is required for regex parser")
+)
+
+// NewUserLogParser returns user log parser by its name; empty name stands for json parser
+func NewUserLogParser(name string, pattern *regexp.Regexp) (common.UserLogParser, error) {
+	switch name {
+	case "", common.UserLogParserJSON:
+		return ParseUserLogJSON, nil
+	case common.UserLogParserLogfmt:
+		return ParseUserLogLogfmt, nil
+	case common.UserLogParserRegexp:
+		if pattern == nil {
+			return nil, ErrUserLogRegexpRequired
+		}
+
+		return CreateParserUserLogRegexp(pattern), nil
+	}
+
+	return nil, fmt.Errorf("%w: '%s'", ErrUserLogParserUnknown, name)
+}
+
+// ParseUserLogJSON parses user log as json map
+func ParseUserLogJSON(content string) (map[string]interface{}, error) {
+	var inner interface{}
+
+	if err := json.Unmarshal([]byte(content), &inner); err != nil {
+		return nil, err
+	}
+
+	innerMap, ok := inner.(map[string]interface{})
+
+	if !ok {
+		return nil, ErrUserLogNotMap
+	}
+
+	return innerMap, nil
+}
+
+// ParseUserLogLogfmt parses user log as logfmt string: key=value pairs, values may be double-quoted,
+// keys without values are considered to be true; at least one key=value pair is expected
+func ParseUserLogLogfmt(content string) (map[string]interface{}, error) {
+	result := make(map[string]interface{})
+	pairsFlag := false
+	position := 0
+
+	for position < len(content) {
+		for position < len(content) && content[position] == ' ' {
+			position++
+		}
+
+		start := position
+
+		for position < len(content) && content[position] != '=' && content[position] != ' ' {
+			position++
+		}
+
+		key := content[start:position]
+
+		if position >= len(content) || content[position] == ' ' {
+			if key != "" {
+				result[key] = true
+			}
+
+			continue
+		}
+
+		// skip '='
+		position++
+
+		if position < len(content) && content[position] == '"' {
+			end := quotedValueEnd(content, position)
+			value, err := strconv.Unquote(content[position:end])
+
+			if err != nil {
+				value = strings.Trim(content[position:end], `"`)
+			}
+
+			position = end
+
+			if key != "" {
+				result[key] = value
+				pairsFlag = true
+			}
+
+			continue
+		}
+
+		start = position
+
+		for position < len(content) && content[position] != ' ' {
+			position++
+		}
+
+		if key != "" {
+			result[key] = content[start:position]
+			pairsFlag = true
+		}
+	}
+
+	if !pairsFlag {
+		return nil, ErrUserLogNoPairs
+	}
+
+	return result, nil
+}
+
+func quotedValueEnd(content string, position int) int {
+	// skip opening quote
+	position++
+
+	for position < len(content) {
+		switch content[position] {
+		case '\\':
+			position += 2
+			continue
+		case '"':
+			return position + 1
+		}
+
+		position++
+	}
+
+	return len(content)
+}
+
+// CreateParserUserLogRegexp returns parser that takes values of named groups of the regular expression
+func CreateParserUserLogRegexp(pattern *regexp.Regexp) common.UserLogParser {
+	names := pattern.SubexpNames()
+
+	return func(content string) (map[string]interface{}, error) {
+		match := pattern.FindStringSubmatch(content)
+
+		if match == nil {
+			return nil, ErrUserLogNotMatched
+		}
+
+		result := make(map[string]interface{}, len(names))
+
+		for i, name := range names {
+			if i == 0 || name == "" {
+				continue
+			}
+
+			result[name] = match[i]
+		}
+
+		return result, nil
+	}
+}
@@ -0,0 +1,86 @@
+package parsers
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/2gis/loggo/common"
+)
+
+func TestNewUserLogParser(t *testing.T) {
+	for _, name := range []string{"", common.UserLogParserJSON, common.UserLogParserLogfmt} {
+		parser, err := NewUserLogParser(name, nil)
+		assert.NoError(t, err)
+		assert.NotNil(t, parser)
+	}
+
+	_, err := NewUserLogParser(common.UserLogParserRegexp, nil)
+	assert.ErrorIs(t, err, ErrUserLogRegexpRequired)
+
+	_, err = NewUserLogParser("yaml", nil)
+	assert.ErrorIs(t, err, ErrUserLogParserUnknown)
+}
+
+func TestParseUserLogJSON(t *testing.T) {
+	result, err := ParseUserLogJSON(`{"a":"b","c":1}`)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"a": "b", "c": float64(1)}, result)
+
+	_, err = ParseUserLogJSON(`[1, 2]`)
+	assert.ErrorIs(t, err, ErrUserLogNotMap)
+
+	_, err = ParseUserLogJSON(`plain`)
+	assert.Error(t, err)
+}
+
+func TestParseUserLogLogfmt(t *testing.T) {
+	testCases := []struct {
+		name     string
+		input    string
+		expected map[string]interface{}
+		err      error
+	}{
+		{
+			name:  "Plain and quoted values",
+			input: `level=info msg="request done" status=200 duration=1.5ms`,
+			expected: map[string]interface{}{
+				"level": "info", "msg": "request done", "status": "200", "duration": "1.5ms",
+			},
+		},
+		{
+			name:     "Escaped quotes and bare keys",
+			input:    `msg="say \"hi\"" debug  empty=`,
+			expected: map[string]interface{}{"msg": `say "hi"`, "debug": true, "empty": ""},
+		},
+		{
+			name:  "No pairs",
+			input: `just some words`,
+			err:   ErrUserLogNoPairs,
+		},
+	}
+
+	for _, testCase := range testCases {
+		result, err := ParseUserLogLogfmt(testCase.input)
+
+		if testCase.err != nil {
+			assert.ErrorIs(t, err, testCase.err, testCase.name)
+			continue
+		}
+
+		assert.NoError(t, err, testCase.name)
+		assert.Equal(t, testCase.expected, result, testCase.name)
+	}
+}
+
+func TestCreateParserUserLogRegexp(t *testing.T) {
+	parser := CreateParserUserLogRegexp(regexp.MustCompile(`^(?P<level>\w+) \[(?P<module>[^]]+)\] (.*)$`))
+
+	result, err := parser("WARN [db] connection lost")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"level": "WARN", "module": "db"}, result)
+
+	_, err = parser("connection lost")
+	assert.ErrorIs(t, err, ErrUserLogNotMatched)
+}
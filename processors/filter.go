@@ -0,0 +1,26 @@
+package processors
+
+import (
+	"github.com/2gis/loggo/common"
+	"github.com/2gis/loggo/expression"
+)
+
+// Filter drops entries not matching the expression
+type Filter struct {
+	expression *expression.Expression
+}
+
+// NewFilter is a Filter constructor
+func NewFilter(source string) (*Filter, error) {
+	compiled, err := expression.Compile(source)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Filter{expression: compiled}, nil
+}
+
+// Process returns false if entry doesn't match the expression
+func (f *Filter) Process(entryMap common.EntryMap) bool {
+	return f.expression.Evaluate(entryMap)
+}
@@ -0,0 +1,32 @@
+package processors
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/2gis/loggo/common"
+)
+
+func TestFilter(t *testing.T) {
+	filter, err := NewFilter(`kubernetes.namespace_name != "kube-system" || level == "error"`)
+	assert.NoError(t, err)
+
+	assert.True(t, filter.Process(common.EntryMap{common.KubernetesNamespaceName: "prod"}))
+	assert.True(t, filter.Process(common.EntryMap{common.KubernetesNamespaceName: "kube-system", "level": "error"}))
+	assert.False(t, filter.Process(common.EntryMap{common.KubernetesNamespaceName: "kube-system"}))
+
+	_, err = NewFilter(`level ==`)
+	assert.Error(t, err)
+}
+
+func BenchmarkFilter_Process(b *testing.B) {
+	filter, _ := NewFilter(`kubernetes.namespace_name == "prod" && status >= 500`)
+	entryMap := common.EntryMap{common.KubernetesNamespaceName: "prod", "status": float64(200), "message": "ok"}
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		filter.Process(entryMap)
+	}
+}
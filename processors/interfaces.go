@@ -0,0 +1,13 @@
+package processors
+
+// SamplingRules provides severity sampling ratios for pods, see rates.Rater
+type SamplingRules interface {
+	Sampling(namespace, pod string) map[string]float64
+}
+
+// MetricsCollector is a metrics counter object interface for processors
+type MetricsCollector interface {
+	IncrementSampledOutCount(namespace, severity string)
+	IncrementRedactionHitsCount(rule string, count int)
+	IncrementTraceContextCount(namespace string, present bool)
+}
@@ -0,0 +1,165 @@
+package processors
+
+import (
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strconv"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/2gis/loggo/common"
+	"github.com/2gis/loggo/expression"
+)
+
+// logMetricTypeCounter is the only metric type not requiring value field, see metrics.LogMetricTypeCounter
+const logMetricTypeCounter = "counter"
+
+// LogMetric is a metric entries values are observed with
+type LogMetric interface {
+	Observe(labelValues []string, value float64)
+}
+
+// LogMetricFactory creates and registers metric of the given type
+type LogMetricFactory func(name, metricType, help string, labels []string, buckets []float64) (LogMetric, error)
+
+// LogMetricRecord is the struct to unmarshal yaml list item of log metrics file to
+type LogMetricRecord struct {
+	Name    string
+	Type    string
+	Help    string
+	Match   string
+	Value   string
+	Labels  map[string]string
+	Buckets []float64
+}
+
+type logMetricRule struct {
+	match       *expression.Expression
+	valueField  string
+	labelFields []string
+	metric      LogMetric
+}
+
+// LogMetricsDeriver observes user-defined metrics for entries matching their expressions; metric value is taken from
+// the value field (counters are incremented by one if there is no value field), label values are taken from fields
+type LogMetricsDeriver struct {
+	rules []*logMetricRule
+}
+
+// LoadLogMetricRecords reads log metric records from yaml file
+func LoadLogMetricRecords(filePath string) ([]LogMetricRecord, error) {
+	yamlData, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]LogMetricRecord, 0)
+
+	if err = yaml.Unmarshal(yamlData, &result); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// NewLogMetricsDeriver is a LogMetricsDeriver constructor, metrics are created with factory
+func NewLogMetricsDeriver(records []LogMetricRecord, factory LogMetricFactory) (*LogMetricsDeriver, error) {
+	rules := make([]*logMetricRule, 0, len(records))
+
+	for _, record := range records {
+		rule := &logMetricRule{valueField: record.Value}
+
+		if record.Match != "" {
+			match, err := expression.Compile(record.Match)
+			if err != nil {
+				return nil, fmt.Errorf("metric '%s': %s", record.Name, err)
+			}
+
+			rule.match = match
+		}
+
+		if record.Value == "" && record.Type != logMetricTypeCounter {
+			return nil, fmt.Errorf("metric '%s': value field is required for type '%s'", record.Name, record.Type)
+		}
+
+		labels := make([]string, 0, len(record.Labels))
+
+		for label := range record.Labels {
+			labels = append(labels, label)
+		}
+
+		sort.Strings(labels)
+
+		for _, label := range labels {
+			rule.labelFields = append(rule.labelFields, record.Labels[label])
+		}
+
+		metric, err := factory(record.Name, record.Type, record.Help, labels, record.Buckets)
+		if err != nil {
+			return nil, err
+		}
+
+		rule.metric = metric
+		rules = append(rules, rule)
+	}
+
+	return &LogMetricsDeriver{rules: rules}, nil
+}
+
+// Process observes metrics, entries are never dropped
+func (d *LogMetricsDeriver) Process(entryMap common.EntryMap) bool {
+	for _, rule := range d.rules {
+		if rule.match != nil && !rule.match.Evaluate(entryMap) {
+			continue
+		}
+
+		value, ok := rule.value(entryMap)
+
+		if !ok {
+			continue
+		}
+
+		labelValues := make([]string, len(rule.labelFields))
+
+		for i, field := range rule.labelFields {
+			labelValues[i] = labelValue(entryMap, field)
+		}
+
+		rule.metric.Observe(labelValues, value)
+	}
+
+	return true
+}
+
+func (rule *logMetricRule) value(entryMap common.EntryMap) (float64, bool) {
+	if rule.valueField == "" {
+		return 1, true
+	}
+
+	value, ok := entryMap.Lookup(rule.valueField)
+
+	if !ok {
+		return 0, false
+	}
+
+	result, err := toFloat(value)
+	return result, err == nil
+}
+
+func labelValue(entryMap common.EntryMap, field string) string {
+	value, ok := entryMap.Lookup(field)
+
+	if !ok || value == nil {
+		return ""
+	}
+
+	switch typed := value.(type) {
+	case string:
+		return typed
+	case float64:
+		return strconv.FormatFloat(typed, 'f', -1, 64)
+	}
+
+	return fmt.Sprint(value)
+}
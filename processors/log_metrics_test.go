@@ -0,0 +1,116 @@
+package processors
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/2gis/loggo/common"
+)
+
+const filePathLogMetrics = "/tmp/test_log_metrics.yaml"
+
+type logMetricObservation struct {
+	labelValues []string
+	value       float64
+}
+
+type logMetricMock struct {
+	observations []logMetricObservation
+}
+
+func (m *logMetricMock) Observe(labelValues []string, value float64) {
+	m.observations = append(m.observations, logMetricObservation{labelValues: labelValues, value: value})
+}
+
+type logMetricFactoryMock struct {
+	metrics map[string]*logMetricMock
+	labels  map[string][]string
+}
+
+func (f *logMetricFactoryMock) create(name, _, _ string, labels []string, _ []float64) (LogMetric, error) {
+	if name == "invalid" {
+		return nil, errors.New("invalid metric")
+	}
+
+	f.metrics[name] = &logMetricMock{}
+	f.labels[name] = labels
+	return f.metrics[name], nil
+}
+
+func TestLogMetricsDeriver(t *testing.T) {
+	factory := &logMetricFactoryMock{metrics: map[string]*logMetricMock{}, labels: map[string][]string{}}
+	deriver, err := NewLogMetricsDeriver([]LogMetricRecord{
+		{
+			Name:   "app_errors_total",
+			Type:   "counter",
+			Match:  `level == "error"`,
+			Labels: map[string]string{"code": "error_code", "namespace": common.KubernetesNamespaceName},
+		},
+		{
+			Name:  "db_duration_ms",
+			Type:  "histogram",
+			Value: "db.duration_ms",
+		},
+	}, factory.create)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"code", "namespace"}, factory.labels["app_errors_total"])
+
+	entries := []common.EntryMap{
+		{common.KubernetesNamespaceName: "prod", "level": "error", "error_code": float64(42)},
+		{common.KubernetesNamespaceName: "prod", "level": "error"},
+		{"level": "info", "db": map[string]interface{}{"duration_ms": "12.5"}},
+		{"level": "info", "db": map[string]interface{}{"duration_ms": "slow"}},
+	}
+
+	for _, entryMap := range entries {
+		assert.True(t, deriver.Process(entryMap))
+	}
+
+	assert.Equal(t, []logMetricObservation{
+		{labelValues: []string{"42", "prod"}, value: 1},
+		{labelValues: []string{"", "prod"}, value: 1},
+	}, factory.metrics["app_errors_total"].observations)
+	assert.Equal(t, []logMetricObservation{
+		{labelValues: []string{}, value: 12.5},
+	}, factory.metrics["db_duration_ms"].observations)
+}
+
+func TestNewLogMetricsDeriverInvalid(t *testing.T) {
+	factory := &logMetricFactoryMock{metrics: map[string]*logMetricMock{}, labels: map[string][]string{}}
+
+	for _, record := range []LogMetricRecord{
+		{Name: "metric", Type: "counter", Match: "level =="},
+		{Name: "metric", Type: "gauge"},
+		{Name: "invalid", Type: "counter"},
+	} {
+		_, err := NewLogMetricsDeriver([]LogMetricRecord{record}, factory.create)
+		assert.Error(t, err, record)
+	}
+}
+
+func TestLoadLogMetricRecords(t *testing.T) {
+	payload := `---
+- name: app_errors_total
+  type: counter
+  match: level == "error"
+  labels:
+    code: error_code
+- name: db_duration_ms
+  type: histogram
+  value: db_duration_ms
+  buckets: [1, 10, 100]
+`
+	assert.NoError(t, os.WriteFile(filePathLogMetrics, []byte(payload), 0644))
+	defer os.Remove(filePathLogMetrics)
+
+	records, err := LoadLogMetricRecords(filePathLogMetrics)
+	assert.NoError(t, err)
+	assert.Equal(t, []LogMetricRecord{
+		{Name: "app_errors_total", Type: "counter", Match: `level == "error"`,
+			Labels: map[string]string{"code": "error_code"}},
+		{Name: "db_duration_ms", Type: "histogram", Value: "db_duration_ms", Buckets: []float64{1, 10, 100}},
+	}, records)
+}
@@ -0,0 +1,29 @@
+package processors
+
+import (
+	"strings"
+
+	"github.com/2gis/loggo/common"
+)
+
+// subMap returns nested map by the key; empty key stands for the entry itself, as configured keys of parsers do
+func subMap(entryMap common.EntryMap, key string) (common.EntryMap, bool) {
+	if key == "" {
+		return entryMap, true
+	}
+
+	nested, ok := entryMap[key].(common.EntryMap)
+	return nested, ok
+}
+
+func splitList(list string) []string {
+	result := make([]string, 0)
+
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			result = append(result, item)
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,155 @@
+package processors
+
+import (
+	"fmt"
+
+	"github.com/2gis/loggo/common"
+	"github.com/2gis/loggo/configuration"
+)
+
+// Redactor applies redaction rules to all the string values of the entry, including raw user log field;
+// rules are scoped by pod namespace
+type Redactor struct {
+	rules        []*RedactionRule
+	collector    MetricsCollector
+	extendsField string
+}
+
+// NewRedactor is a Redactor constructor
+func NewRedactor(records []RedactionRecord, hmacKey []byte, collector MetricsCollector,
+	parserConfig configuration.ParserConfig) (*Redactor, error) {
+	rules := make([]*RedactionRule, 0, len(records))
+
+	for _, record := range records {
+		rule, err := NewRedactionRule(record, hmacKey)
+		if err != nil {
+			return nil, err
+		}
+
+		rules = append(rules, rule)
+	}
+
+	return &Redactor{
+		rules:        rules,
+		collector:    collector,
+		extendsField: parserConfig.ExtendsFieldsKey,
+	}, nil
+}
+
+// Process redacts entry in place, entries are never dropped
+func (r *Redactor) Process(entryMap common.EntryMap) bool {
+	namespace := ""
+
+	if extends, ok := subMap(entryMap, r.extendsField); ok {
+		namespace = extends.NamespaceName()
+	}
+
+	for _, rule := range r.rules {
+		if !rule.Match(namespace, entryMap) {
+			continue
+		}
+
+		if hits := redactMap(rule, entryMap, ""); hits > 0 {
+			r.collector.IncrementRedactionHitsCount(rule.Name, hits)
+		}
+	}
+
+	return true
+}
+
+func redactMap(rule *RedactionRule, values map[string]interface{}, prefix string) int {
+	hits := 0
+
+	for key, value := range values {
+		path := key
+
+		if prefix != "" {
+			path = prefix + "." + key
+		}
+
+		// rules without pattern redact whole values of the fields, whatever the type is
+		if rule.pattern == nil {
+			if rule.matchField(key, path) {
+				redactField(rule, values, key, value)
+				hits++
+				continue
+			}
+		}
+
+		switch typed := value.(type) {
+		case common.EntryMap:
+			hits += redactMap(rule, typed, path)
+		case map[string]interface{}:
+			hits += redactMap(rule, typed, path)
+		case []interface{}:
+			count, dropFlag := redactSlice(rule, typed, key, path)
+			hits += count
+
+			if dropFlag {
+				delete(values, key)
+			}
+		case string:
+			if rule.pattern == nil || !rule.matchField(key, path) {
+				continue
+			}
+
+			redacted, count := rule.redactString(typed)
+
+			if count == 0 {
+				continue
+			}
+
+			hits += count
+
+			if rule.action == RedactionActionDrop {
+				delete(values, key)
+				continue
+			}
+
+			values[key] = redacted
+		}
+	}
+
+	return hits
+}
+
+// redactSlice redacts slice items in place; returns true if the whole slice should be dropped
+func redactSlice(rule *RedactionRule, values []interface{}, key, path string) (int, bool) {
+	hits, dropFlag := 0, false
+
+	for i, value := range values {
+		switch typed := value.(type) {
+		case common.EntryMap:
+			hits += redactMap(rule, typed, path)
+		case map[string]interface{}:
+			hits += redactMap(rule, typed, path)
+		case string:
+			if rule.pattern == nil || !rule.matchField(key, path) {
+				continue
+			}
+
+			redacted, count := rule.redactString(typed)
+
+			if count == 0 {
+				continue
+			}
+
+			hits += count
+			dropFlag = dropFlag || rule.action == RedactionActionDrop
+			values[i] = redacted
+		}
+	}
+
+	return hits, dropFlag
+}
+
+func redactField(rule *RedactionRule, values map[string]interface{}, key string, value interface{}) {
+	switch rule.action {
+	case RedactionActionDrop:
+		delete(values, key)
+	case RedactionActionHash:
+		values[key] = rule.replacement(fmt.Sprint(value))
+	default:
+		values[key] = rule.mask
+	}
+}
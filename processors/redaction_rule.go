@@ -0,0 +1,271 @@
+package processors
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/2gis/loggo/common"
+	"github.com/2gis/loggo/expression"
+)
+
+/* Redaction actions */
+const (
+	RedactionActionMask = "mask"
+	RedactionActionHash = "hash"
+	RedactionActionDrop = "drop"
+)
+
+/* Named detectors */
+const (
+	DetectorEmail       = "email"
+	DetectorPhone       = "phone"
+	DetectorCard        = "card"
+	DetectorBearerToken = "bearer_token"
+)
+
+// redactionGroupValue is a name of regular expression group to redact instead of the whole match
+const redactionGroupValue = "value"
+
+const redactionMaskDefault = "***"
+
+var (
+	ErrRedactionRuleEmpty = errors.New("redaction rule must have detector, pattern or fields")
+	ErrRedactionAction    = errors.New("unsupported redaction action")
+	ErrRedactionDetector  = errors.New("unknown redaction detector")
+	ErrRedactionHMACKey   = errors.New("hmac key is required for hash action")
+)
+
+var detectors = map[string]*regexp.Regexp{
+	DetectorEmail: regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`),
+	// international numbers with leading plus or numbers with parenthesized area code, to avoid matching dates
+	DetectorPhone: regexp.MustCompile(
+		`\+\d{1,3}[\s.\-]?\(?\d{1,4}\)?[\s.\-]?\d{2,4}[\s.\-]?\d{2,4}(?:[\s.\-]?\d{2,4})?|\(\d{3}\)\s?\d{3}[\s.\-]\d{4}`),
+	// candidates are validated with Luhn algorithm
+	DetectorCard: regexp.MustCompile(`\b(?:\d[ \-]?){12,18}\d\b`),
+	DetectorBearerToken: regexp.MustCompile(
+		`(?i)\bbearer\s+(?P<value>[A-Za-z0-9\-._~+/]+=*)`),
+}
+
+// RedactionRecord is the struct to unmarshal yaml list item of redaction rules file to
+type RedactionRecord struct {
+	Name      string
+	Namespace string
+	Detector  string
+	Pattern   string
+	Fields    []string
+	Condition string
+	Action    string
+	Mask      string
+}
+
+// RedactionRule is a compiled redaction rule; when there is a detector or pattern, matched parts of string values
+// are redacted (the fields the rule is limited to, if any); otherwise the whole values of the fields are redacted
+type RedactionRule struct {
+	Name string
+
+	namespace *regexp.Regexp
+	condition *expression.Expression
+	pattern   *regexp.Regexp
+	validate  func(match string) bool
+	fields    map[string]bool
+
+	action  string
+	mask    string
+	hmacKey []byte
+}
+
+// LoadRedactionRecords reads redaction records from yaml file
+func LoadRedactionRecords(filePath string) ([]RedactionRecord, error) {
+	yamlData, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]RedactionRecord, 0)
+
+	if err = yaml.Unmarshal(yamlData, &result); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// NewRedactionRule is a RedactionRule constructor; hmacKey is required for hash action only
+func NewRedactionRule(record RedactionRecord, hmacKey []byte) (*RedactionRule, error) {
+	rule := &RedactionRule{
+		Name:    record.Name,
+		action:  record.Action,
+		mask:    record.Mask,
+		hmacKey: hmacKey,
+	}
+
+	if rule.Name == "" {
+		rule.Name = record.Detector + record.Pattern
+	}
+
+	if rule.Name == "" {
+		rule.Name = strings.Join(record.Fields, ",")
+	}
+
+	switch rule.action {
+	case "":
+		rule.action = RedactionActionMask
+	case RedactionActionMask, RedactionActionDrop:
+	case RedactionActionHash:
+		if len(hmacKey) == 0 {
+			return nil, fmt.Errorf("rule '%s': %w", rule.Name, ErrRedactionHMACKey)
+		}
+	default:
+		return nil, fmt.Errorf("rule '%s': %w '%s'", rule.Name, ErrRedactionAction, rule.action)
+	}
+
+	if rule.mask == "" {
+		rule.mask = redactionMaskDefault
+	}
+
+	if record.Namespace != "" {
+		namespace, err := regexp.Compile(record.Namespace)
+		if err != nil {
+			return nil, fmt.Errorf("rule '%s': %s", rule.Name, err)
+		}
+
+		rule.namespace = namespace
+	}
+
+	if record.Condition != "" {
+		condition, err := expression.Compile(record.Condition)
+		if err != nil {
+			return nil, fmt.Errorf("rule '%s': %s", rule.Name, err)
+		}
+
+		rule.condition = condition
+	}
+
+	switch {
+	case record.Detector != "" && record.Pattern != "":
+		return nil, fmt.Errorf("rule '%s': either detector or pattern must be specified", rule.Name)
+	case record.Detector != "":
+		pattern, ok := detectors[record.Detector]
+
+		if !ok {
+			return nil, fmt.Errorf("rule '%s': %w '%s'", rule.Name, ErrRedactionDetector, record.Detector)
+		}
+
+		rule.pattern = pattern
+
+		if record.Detector == DetectorCard {
+			rule.validate = luhnValid
+		}
+	case record.Pattern != "":
+		pattern, err := regexp.Compile(record.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("rule '%s': %s", rule.Name, err)
+		}
+
+		rule.pattern = pattern
+	case len(record.Fields) == 0:
+		return nil, fmt.Errorf("rule '%s': %w", rule.Name, ErrRedactionRuleEmpty)
+	}
+
+	if len(record.Fields) > 0 {
+		rule.fields = make(map[string]bool, len(record.Fields))
+
+		for _, field := range record.Fields {
+			rule.fields[field] = true
+		}
+	}
+
+	return rule, nil
+}
+
+// Match checks if the rule is applicable to the entry of namespace
+func (rule *RedactionRule) Match(namespace string, entryMap common.EntryMap) bool {
+	if rule.namespace != nil && !rule.namespace.MatchString(namespace) {
+		return false
+	}
+
+	return rule.condition == nil || rule.condition.Evaluate(entryMap)
+}
+
+// matchField checks if the rule is applicable to the field given by its key and full dotted path
+func (rule *RedactionRule) matchField(key, path string) bool {
+	return rule.fields == nil || rule.fields[key] || rule.fields[path]
+}
+
+// redactString returns value with redacted matches and count of matches; for drop action the value is not modified
+func (rule *RedactionRule) redactString(value string) (string, int) {
+	matches := rule.pattern.FindAllStringSubmatchIndex(value, -1)
+
+	if len(matches) == 0 {
+		return value, 0
+	}
+
+	group := rule.pattern.SubexpIndex(redactionGroupValue)
+	builder := strings.Builder{}
+	position, count := 0, 0
+
+	for _, match := range matches {
+		start, end := match[0], match[1]
+
+		if group > 0 && match[2*group] >= 0 {
+			start, end = match[2*group], match[2*group+1]
+		}
+
+		if rule.validate != nil && !rule.validate(value[start:end]) {
+			continue
+		}
+
+		count++
+		builder.WriteString(value[position:start])
+		builder.WriteString(rule.replacement(value[start:end]))
+		position = end
+	}
+
+	builder.WriteString(value[position:])
+	return builder.String(), count
+}
+
+// replacement returns masked or hashed value
+func (rule *RedactionRule) replacement(value string) string {
+	if rule.action != RedactionActionHash {
+		return rule.mask
+	}
+
+	mac := hmac.New(sha256.New, rule.hmacKey)
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// luhnValid checks card number candidate with Luhn algorithm, ignoring separators
+func luhnValid(candidate string) bool {
+	sum, digits := 0, 0
+
+	for i := len(candidate) - 1; i >= 0; i-- {
+		if candidate[i] < '0' || candidate[i] > '9' {
+			continue
+		}
+
+		digit := int(candidate[i] - '0')
+
+		if digits%2 == 1 {
+			digit *= 2
+
+			if digit > 9 {
+				digit -= 9
+			}
+		}
+
+		sum += digit
+		digits++
+	}
+
+	return digits >= 13 && sum%10 == 0
+}
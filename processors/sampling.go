@@ -0,0 +1,57 @@
+package processors
+
+import (
+	"math/rand"
+
+	"github.com/2gis/loggo/common"
+	"github.com/2gis/loggo/configuration"
+)
+
+// Sampler drops part of entries according to sampling ratio of their canonical severity; ratios are taken from
+// the rule matching pod namespace and name, entries of severities not mentioned in the rule are kept
+type Sampler struct {
+	rules     SamplingRules
+	collector MetricsCollector
+
+	severityField string
+	extendsField  string
+
+	random func() float64
+}
+
+// NewSampler is a Sampler constructor; severityField is an output field of SeverityNormalizer
+func NewSampler(rules SamplingRules, collector MetricsCollector, severityField string,
+	parserConfig configuration.ParserConfig) *Sampler {
+	return &Sampler{
+		rules:         rules,
+		collector:     collector,
+		severityField: severityField,
+		extendsField:  parserConfig.ExtendsFieldsKey,
+		random:        rand.Float64,
+	}
+}
+
+// Process returns false if entry is sampled out
+func (s *Sampler) Process(entryMap common.EntryMap) bool {
+	severity, ok := entryMap[s.severityField].(string)
+
+	if !ok {
+		return true
+	}
+
+	extends, ok := subMap(entryMap, s.extendsField)
+
+	if !ok {
+		return true
+	}
+
+	namespace := extends.NamespaceName()
+	ratio, ok := s.rules.Sampling(namespace, extends.PodName())[severity]
+
+	if !ok || s.random() < ratio {
+		return true
+	}
+
+	s.collector.IncrementSampledOutCount(namespace, severity)
+	return false
+}
@@ -0,0 +1,45 @@
+package processors
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/2gis/loggo/common"
+	"github.com/2gis/loggo/components/rates"
+	"github.com/2gis/loggo/configuration"
+	"github.com/2gis/loggo/tests/mocks"
+)
+
+type samplingRecordsProvider struct{}
+
+func (p *samplingRecordsProvider) RateRecords() ([]rates.RateRecord, error) {
+	return []rates.RateRecord{{Namespace: "prod", Sampling: map[string]float64{common.SeverityDebug: 0.1}}}, nil
+}
+
+func TestSampler(t *testing.T) {
+	rater, err := rates.NewRater(&samplingRecordsProvider{}, 1000)
+	assert.NoError(t, err)
+	assert.NoError(t, rater.Retrieve())
+
+	sampler := NewSampler(
+		rater, mocks.NewCollectorMock(), "severity", configuration.ParserConfig{ExtendsFieldsKey: "extends"})
+	random := 0.0
+	sampler.random = func() float64 { return random }
+
+	entryMap := func(namespace, severity string) common.EntryMap {
+		return common.EntryMap{
+			"severity": severity,
+			"extends":  common.EntryMap{common.KubernetesNamespaceName: namespace},
+		}
+	}
+
+	random = 0.05
+	assert.True(t, sampler.Process(entryMap("prod", common.SeverityDebug)))
+
+	random = 0.5
+	assert.False(t, sampler.Process(entryMap("prod", common.SeverityDebug)))
+	assert.True(t, sampler.Process(entryMap("prod", common.SeverityWarn)))
+	assert.True(t, sampler.Process(entryMap("stage", common.SeverityDebug)))
+	assert.True(t, sampler.Process(common.EntryMap{"extends": common.EntryMap{common.KubernetesNamespaceName: "prod"}}))
+}
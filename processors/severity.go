@@ -0,0 +1,122 @@
+package processors
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/2gis/loggo/common"
+	"github.com/2gis/loggo/configuration"
+)
+
+var severityAliases = map[string]string{
+	"trace": common.SeverityTrace, "trc": common.SeverityTrace, "t": common.SeverityTrace,
+	"finest": common.SeverityTrace,
+	"debug":  common.SeverityDebug, "dbg": common.SeverityDebug, "d": common.SeverityDebug,
+	"fine": common.SeverityDebug,
+	"info": common.SeverityInfo, "inf": common.SeverityInfo, "i": common.SeverityInfo,
+	"information": common.SeverityInfo, "informational": common.SeverityInfo, "notice": common.SeverityInfo,
+	"warn": common.SeverityWarn, "warning": common.SeverityWarn, "wrn": common.SeverityWarn, "w": common.SeverityWarn,
+	"error": common.SeverityError, "err": common.SeverityError, "e": common.SeverityError,
+	"severe": common.SeverityError,
+	"fatal":  common.SeverityFatal, "f": common.SeverityFatal, "critical": common.SeverityFatal,
+	"crit": common.SeverityFatal, "panic": common.SeverityFatal, "alert": common.SeverityFatal,
+	"emerg": common.SeverityFatal, "emergency": common.SeverityFatal,
+}
+
+// syslogSeverities maps syslog numeric severities (RFC 5424) to canonical ones
+var syslogSeverities = []string{
+	common.SeverityFatal, common.SeverityFatal, common.SeverityFatal, common.SeverityError,
+	common.SeverityWarn, common.SeverityInfo, common.SeverityInfo, common.SeverityDebug,
+}
+
+// SeverityNormalizer looks for severity in user log fields and puts its canonical name to the output field
+type SeverityNormalizer struct {
+	fields       []string
+	outputField  string
+	userLogField string
+}
+
+// NewSeverityNormalizer is a SeverityNormalizer constructor
+func NewSeverityNormalizer(
+	config configuration.SeverityConfig, parserConfig configuration.ParserConfig) (*SeverityNormalizer, error) {
+	if config.OutputField == "" {
+		return nil, fmt.Errorf("severity output field must not be empty")
+	}
+
+	return &SeverityNormalizer{
+		fields:       splitList(config.Fields),
+		outputField:  config.OutputField,
+		userLogField: parserConfig.UserLogFieldsKey,
+	}, nil
+}
+
+// Process sets canonical severity field if severity is found, entries are never dropped
+func (n *SeverityNormalizer) Process(entryMap common.EntryMap) bool {
+	base, ok := subMap(entryMap, n.userLogField)
+
+	if !ok {
+		return true
+	}
+
+	for _, field := range n.fields {
+		value, ok := base.Lookup(field)
+
+		if !ok {
+			continue
+		}
+
+		if severity, ok := NormalizeSeverity(value); ok {
+			entryMap[n.outputField] = severity
+			return true
+		}
+	}
+
+	return true
+}
+
+// NormalizeSeverity converts severity name or number to canonical severity; numbers below 10 are considered to be
+// syslog severities, others are bunyan/pino-like levels (10 is trace, 20 is debug, ..., 60 is fatal)
+func NormalizeSeverity(value interface{}) (string, bool) {
+	switch typed := value.(type) {
+	case string:
+		if severity, ok := severityAliases[strings.ToLower(strings.TrimSpace(typed))]; ok {
+			return severity, true
+		}
+
+		number, err := toFloat(strings.TrimSpace(typed))
+
+		if err != nil {
+			return "", false
+		}
+
+		return numericSeverity(number)
+	case float64, int, int64:
+		number, _ := toFloat(typed)
+		return numericSeverity(number)
+	}
+
+	return "", false
+}
+
+func numericSeverity(number float64) (string, bool) {
+	switch {
+	case number < 0:
+		return "", false
+	case number < float64(len(syslogSeverities)):
+		return syslogSeverities[int(number)], true
+	case number < 10:
+		return "", false
+	case number < 20:
+		return common.SeverityTrace, true
+	case number < 30:
+		return common.SeverityDebug, true
+	case number < 40:
+		return common.SeverityInfo, true
+	case number < 50:
+		return common.SeverityWarn, true
+	case number < 60:
+		return common.SeverityError, true
+	}
+
+	return common.SeverityFatal, true
+}
@@ -0,0 +1,67 @@
+package processors
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/2gis/loggo/common"
+	"github.com/2gis/loggo/configuration"
+)
+
+func TestNormalizeSeverity(t *testing.T) {
+	testCases := []struct {
+		value    interface{}
+		severity string
+		ok       bool
+	}{
+		{"WARN", common.SeverityWarn, true},
+		{"warning", common.SeverityWarn, true},
+		{" Err ", common.SeverityError, true},
+		{"I", common.SeverityInfo, true},
+		{"notice", common.SeverityInfo, true},
+		{float64(40), common.SeverityWarn, true},
+		{"30", common.SeverityInfo, true},
+		{10, common.SeverityTrace, true},
+		{int64(60), common.SeverityFatal, true},
+		{float64(3), common.SeverityError, true},
+		{"7", common.SeverityDebug, true},
+		{float64(8), "", false},
+		{float64(-1), "", false},
+		{"verbose", "", false},
+		{true, "", false},
+	}
+
+	for _, testCase := range testCases {
+		severity, ok := NormalizeSeverity(testCase.value)
+		assert.Equal(t, testCase.ok, ok, testCase.value)
+		assert.Equal(t, testCase.severity, severity, testCase.value)
+	}
+}
+
+func TestSeverityNormalizer(t *testing.T) {
+	normalizer, err := NewSeverityNormalizer(
+		configuration.SeverityConfig{Fields: "level,severity,log.level", OutputField: "severity_normalized"},
+		configuration.ParserConfig{},
+	)
+	assert.NoError(t, err)
+
+	testCases := []struct {
+		entryMap common.EntryMap
+		severity interface{}
+	}{
+		{common.EntryMap{"level": "WARNING"}, common.SeverityWarn},
+		{common.EntryMap{"level": "unknown", "severity": float64(50)}, common.SeverityError},
+		{common.EntryMap{"log": map[string]interface{}{"level": "debug"}}, common.SeverityDebug},
+		{common.EntryMap{"log.level": "dbg"}, common.SeverityDebug},
+		{common.EntryMap{"message": "hello"}, nil},
+	}
+
+	for _, testCase := range testCases {
+		assert.True(t, normalizer.Process(testCase.entryMap))
+		assert.Equal(t, testCase.severity, testCase.entryMap["severity_normalized"], testCase.entryMap)
+	}
+
+	_, err = NewSeverityNormalizer(configuration.SeverityConfig{}, configuration.ParserConfig{})
+	assert.Error(t, err)
+}
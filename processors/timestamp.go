@@ -0,0 +1,271 @@
+package processors
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/2gis/loggo/common"
+	"github.com/2gis/loggo/configuration"
+	"github.com/2gis/loggo/parsers"
+)
+
+/* Timestamp formats, any other format is considered to be Go time layout */
+const (
+	TimestampFormatRFC3339     = "rfc3339"
+	TimestampFormatRFC3339Nano = "rfc3339nano"
+	TimestampFormatEpoch       = "epoch"
+	TimestampFormatEpochS      = "epoch_s"
+	TimestampFormatEpochMS     = "epoch_ms"
+	TimestampFormatEpochUS     = "epoch_us"
+	TimestampFormatEpochNS     = "epoch_ns"
+)
+
+var (
+	ErrTimestampType   = errors.New("unsupported timestamp value type")
+	ErrTimestampFormat = errors.New("timestamp does not match format")
+)
+
+// TimestampNormalizer looks for event time in user log fields, then puts it to the output field in a single format;
+// container engine time is considered to be collection time and is put to the collection field;
+// if there is no event time in user log, collection time is used instead
+type TimestampNormalizer struct {
+	fields          []string
+	formats         []string
+	outputField     string
+	outputFormat    string
+	collectionField string
+
+	userLogField string
+	criField     string
+
+	now func() time.Time
+}
+
+// NewTimestampNormalizer is a TimestampNormalizer constructor
+func NewTimestampNormalizer(
+	config configuration.TimestampConfig, parserConfig configuration.ParserConfig) (*TimestampNormalizer, error) {
+	if config.OutputField == "" {
+		return nil, fmt.Errorf("timestamp output field must not be empty")
+	}
+
+	formats := splitList(config.Formats)
+
+	if len(formats) == 0 {
+		return nil, fmt.Errorf("at least one timestamp format must be specified")
+	}
+
+	if config.OutputFormat == TimestampFormatEpoch {
+		return nil, fmt.Errorf("timestamp output format '%s' is ambiguous, specify the unit", config.OutputFormat)
+	}
+
+	for _, format := range append(formats, config.OutputFormat) {
+		if !validFormat(format) {
+			return nil, fmt.Errorf("unknown timestamp format '%s', neither format name nor time layout", format)
+		}
+	}
+
+	return &TimestampNormalizer{
+		fields:          splitList(config.Fields),
+		formats:         formats,
+		outputField:     config.OutputField,
+		outputFormat:    config.OutputFormat,
+		collectionField: config.CollectionField,
+
+		userLogField: parserConfig.UserLogFieldsKey,
+		criField:     parserConfig.CRIFieldsKey,
+
+		now: time.Now,
+	}, nil
+}
+
+// Process sets normalized event and collection time fields, entries are never dropped
+func (n *TimestampNormalizer) Process(entryMap common.EntryMap) bool {
+	collectionTime := n.collectionTime(entryMap)
+	eventTime, ok := n.eventTime(entryMap)
+
+	if !ok {
+		eventTime = collectionTime
+	}
+
+	entryMap[n.outputField] = FormatTime(eventTime, n.outputFormat)
+
+	if n.collectionField != "" {
+		entryMap[n.collectionField] = FormatTime(collectionTime, n.outputFormat)
+	}
+
+	return true
+}
+
+func (n *TimestampNormalizer) eventTime(entryMap common.EntryMap) (time.Time, bool) {
+	base, ok := subMap(entryMap, n.userLogField)
+
+	if !ok {
+		return time.Time{}, false
+	}
+
+	for _, field := range n.fields {
+		value, ok := base.Lookup(field)
+
+		if !ok {
+			continue
+		}
+
+		for _, format := range n.formats {
+			if result, err := ParseTime(value, format); err == nil {
+				return result, true
+			}
+		}
+	}
+
+	return time.Time{}, false
+}
+
+func (n *TimestampNormalizer) collectionTime(entryMap common.EntryMap) time.Time {
+	base, ok := subMap(entryMap, n.criField)
+
+	// entries produced by Loggo itself (K8S events) have no container engine fields, their time is at the top level
+	if !ok {
+		base = entryMap
+	}
+
+	if value, ok := base[parsers.LogKeyTime].(string); ok {
+		if result, err := time.Parse(time.RFC3339Nano, value); err == nil {
+			return result
+		}
+	}
+
+	return n.now()
+}
+
+// ParseTime converts value of string or numeric type to time according to format
+func ParseTime(value interface{}, format string) (time.Time, error) {
+	switch format {
+	case TimestampFormatEpoch, TimestampFormatEpochS, TimestampFormatEpochMS, TimestampFormatEpochUS,
+		TimestampFormatEpochNS:
+		number, err := toFloat(value)
+
+		if err != nil {
+			return time.Time{}, err
+		}
+
+		return parseEpoch(number, format), nil
+	}
+
+	valueString, ok := value.(string)
+
+	if !ok {
+		return time.Time{}, fmt.Errorf("%w: %T for format '%s'", ErrTimestampType, value, format)
+	}
+
+	layout := format
+
+	if format == TimestampFormatRFC3339 || format == TimestampFormatRFC3339Nano {
+		// fractional seconds are optional in RFC3339Nano layout
+		layout = time.RFC3339Nano
+	}
+
+	result, err := time.Parse(layout, valueString)
+
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: '%s', %s", ErrTimestampFormat, format, err)
+	}
+
+	return result, nil
+}
+
+// FormatTime converts time to UTC string or, in case of epoch format, to number
+func FormatTime(t time.Time, format string) interface{} {
+	t = t.UTC()
+
+	switch format {
+	case TimestampFormatRFC3339:
+		return t.Format(time.RFC3339)
+	case TimestampFormatRFC3339Nano:
+		return t.Format(time.RFC3339Nano)
+	case TimestampFormatEpochS:
+		return float64(t.UnixNano()) / float64(time.Second)
+	case TimestampFormatEpochMS:
+		return t.UnixNano() / int64(time.Millisecond)
+	case TimestampFormatEpochUS:
+		return t.UnixNano() / int64(time.Microsecond)
+	case TimestampFormatEpochNS:
+		return t.UnixNano()
+	}
+
+	return t.Format(format)
+}
+
+// validFormat checks whether the format is known format name or time layout keeping the date at least
+func validFormat(format string) bool {
+	switch format {
+	case TimestampFormatRFC3339, TimestampFormatRFC3339Nano, TimestampFormatEpoch, TimestampFormatEpochS,
+		TimestampFormatEpochMS, TimestampFormatEpochUS, TimestampFormatEpochNS:
+		return true
+	}
+
+	reference := time.Date(2021, time.March, 4, 5, 6, 7, 0, time.UTC)
+	result, err := time.Parse(format, reference.Format(format))
+
+	if err != nil {
+		return false
+	}
+
+	year, month, day := result.Date()
+	return year == 2021 && month == time.March && day == 4
+}
+
+func parseEpoch(number float64, format string) time.Time {
+	if format == TimestampFormatEpoch {
+		format = epochUnit(number)
+	}
+
+	switch format {
+	case TimestampFormatEpochMS:
+		return time.Unix(0, int64(number*float64(time.Millisecond)))
+	case TimestampFormatEpochUS:
+		return time.Unix(0, int64(number*float64(time.Microsecond)))
+	case TimestampFormatEpochNS:
+		return time.Unix(0, int64(number))
+	}
+
+	seconds, fraction := math.Modf(number)
+	return time.Unix(int64(seconds), int64(fraction*float64(time.Second)))
+}
+
+// epochUnit guesses the unit of epoch time by its magnitude, assuming the time is between 1973 and 5138
+func epochUnit(number float64) string {
+	switch absolute := math.Abs(number); {
+	case absolute >= 1e17:
+		return TimestampFormatEpochNS
+	case absolute >= 1e14:
+		return TimestampFormatEpochUS
+	case absolute >= 1e11:
+		return TimestampFormatEpochMS
+	}
+
+	return TimestampFormatEpochS
+}
+
+func toFloat(value interface{}) (float64, error) {
+	switch typed := value.(type) {
+	case float64:
+		return typed, nil
+	case int64:
+		return float64(typed), nil
+	case int:
+		return float64(typed), nil
+	case string:
+		number, err := strconv.ParseFloat(typed, 64)
+
+		if err != nil {
+			return 0, fmt.Errorf("%w: '%s' is not a number", ErrTimestampFormat, typed)
+		}
+
+		return number, nil
+	}
+
+	return 0, fmt.Errorf("%w: %T", ErrTimestampType, value)
+}
@@ -0,0 +1,169 @@
+package processors
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/2gis/loggo/common"
+	"github.com/2gis/loggo/configuration"
+)
+
+func TestParseTime(t *testing.T) {
+	expected := time.Date(2020, 9, 10, 7, 0, 3, 585000000, time.UTC)
+
+	testCases := []struct {
+		value  interface{}
+		format string
+	}{
+		{"2020-09-10T07:00:03.585Z", TimestampFormatRFC3339},
+		{"2020-09-10T10:00:03.585+03:00", TimestampFormatRFC3339Nano},
+		{float64(1599721203.585), TimestampFormatEpochS},
+		{"1599721203585", TimestampFormatEpochMS},
+		{float64(1599721203585000), TimestampFormatEpochUS},
+		{float64(1599721203585000000), TimestampFormatEpochNS},
+		{float64(1599721203.585), TimestampFormatEpoch},
+		{float64(1599721203585), TimestampFormatEpoch},
+		{float64(1599721203585000000), TimestampFormatEpoch},
+		{"10/09/2020 07:00:03.585", "02/01/2006 15:04:05.000"},
+	}
+
+	for _, testCase := range testCases {
+		result, err := ParseTime(testCase.value, testCase.format)
+		assert.NoError(t, err, testCase.format)
+		assert.WithinDuration(t, expected, result, time.Microsecond, testCase.format)
+	}
+
+	_, err := ParseTime(float64(1599721203), TimestampFormatRFC3339)
+	assert.ErrorIs(t, err, ErrTimestampType)
+
+	_, err = ParseTime("yesterday", TimestampFormatRFC3339)
+	assert.ErrorIs(t, err, ErrTimestampFormat)
+
+	_, err = ParseTime("yesterday", TimestampFormatEpoch)
+	assert.ErrorIs(t, err, ErrTimestampFormat)
+}
+
+func TestFormatTime(t *testing.T) {
+	value := time.Date(2020, 9, 10, 10, 0, 3, 585000000, time.FixedZone("MSK", 3*60*60))
+
+	assert.Equal(t, "2020-09-10T07:00:03Z", FormatTime(value, TimestampFormatRFC3339))
+	assert.Equal(t, "2020-09-10T07:00:03.585Z", FormatTime(value, TimestampFormatRFC3339Nano))
+	assert.Equal(t, int64(1599721203585), FormatTime(value, TimestampFormatEpochMS))
+	assert.Equal(t, int64(1599721203585000000), FormatTime(value, TimestampFormatEpochNS))
+	assert.Equal(t, "2020-09-10", FormatTime(value, "2006-01-02"))
+}
+
+func TestTimestampNormalizer(t *testing.T) {
+	config := configuration.TimestampConfig{
+		Fields:          "ts, request.time",
+		Formats:         "rfc3339, epoch",
+		OutputField:     "@timestamp",
+		OutputFormat:    TimestampFormatRFC3339Nano,
+		CollectionField: "collection_time",
+	}
+	parserConfig := configuration.ParserConfig{UserLogFieldsKey: "log", CRIFieldsKey: "cri"}
+	normalizer, err := NewTimestampNormalizer(config, parserConfig)
+	assert.NoError(t, err)
+	normalizer.now = func() time.Time { return time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC) }
+
+	testCases := []struct {
+		name           string
+		entryMap       common.EntryMap
+		eventTime      string
+		collectionTime string
+	}{
+		{
+			name: "Event time in user log",
+			entryMap: common.EntryMap{
+				"cri": common.EntryMap{"time": "2020-09-10T07:00:04.000000001Z"},
+				"log": common.EntryMap{"ts": float64(1599721203)},
+			},
+			eventTime:      "2020-09-10T07:00:03Z",
+			collectionTime: "2020-09-10T07:00:04.000000001Z",
+		},
+		{
+			name: "Event time in nested field, first matching field is taken",
+			entryMap: common.EntryMap{
+				"cri": common.EntryMap{"time": "2020-09-10T07:00:04Z"},
+				"log": common.EntryMap{
+					"ts":      "not a time",
+					"request": map[string]interface{}{"time": "2020-09-10T10:00:03+03:00"},
+				},
+			},
+			eventTime:      "2020-09-10T07:00:03Z",
+			collectionTime: "2020-09-10T07:00:04Z",
+		},
+		{
+			name: "No event time",
+			entryMap: common.EntryMap{
+				"cri": common.EntryMap{"time": "2020-09-10T07:00:04Z"},
+				"log": common.EntryMap{"message": "hello"},
+			},
+			eventTime:      "2020-09-10T07:00:04Z",
+			collectionTime: "2020-09-10T07:00:04Z",
+		},
+		{
+			name: "K8S event",
+			entryMap: common.EntryMap{
+				"time": "2020-09-10T07:00:05Z",
+				"log":  common.EntryMap{"reason": "BackOff"},
+			},
+			eventTime:      "2020-09-10T07:00:05Z",
+			collectionTime: "2020-09-10T07:00:05Z",
+		},
+		{
+			name:           "No time at all",
+			entryMap:       common.EntryMap{"raw": "hello"},
+			eventTime:      "2021-01-01T00:00:00Z",
+			collectionTime: "2021-01-01T00:00:00Z",
+		},
+	}
+
+	for _, testCase := range testCases {
+		assert.True(t, normalizer.Process(testCase.entryMap), testCase.name)
+		assert.Equal(t, testCase.eventTime, testCase.entryMap["@timestamp"], testCase.name)
+		assert.Equal(t, testCase.collectionTime, testCase.entryMap["collection_time"], testCase.name)
+	}
+}
+
+func TestTimestampNormalizerJournald(t *testing.T) {
+	config := configuration.TimestampConfig{
+		Fields:       "ts",
+		Formats:      "rfc3339",
+		OutputField:  "@timestamp",
+		OutputFormat: TimestampFormatEpochUS,
+	}
+	// journald time is at the top level of the entry
+	normalizer, err := NewTimestampNormalizer(config, configuration.ParserConfig{UserLogFieldsKey: "log"})
+	assert.NoError(t, err)
+
+	entryMap := common.EntryMap{
+		"time": "2020-09-10T07:00:03.585507Z",
+		"log":  common.EntryMap{"MESSAGE": "Started Session 1 of user root."},
+	}
+	assert.True(t, normalizer.Process(entryMap))
+	assert.Equal(t, int64(1599721203585507), entryMap["@timestamp"])
+}
+
+func TestNewTimestampNormalizerInvalid(t *testing.T) {
+	for _, config := range []configuration.TimestampConfig{
+		{Formats: "rfc3339", OutputFormat: TimestampFormatRFC3339},
+		{OutputField: "@timestamp", Formats: " , ", OutputFormat: TimestampFormatRFC3339},
+		{OutputField: "@timestamp", Formats: "rfc3339", OutputFormat: TimestampFormatEpoch},
+		{OutputField: "@timestamp", Formats: "rfc3339,rfc3339nanos", OutputFormat: TimestampFormatRFC3339},
+		{OutputField: "@timestamp", Formats: "rfc3339", OutputFormat: "epoch_sec"},
+		{OutputField: "@timestamp", Formats: "15:04:05", OutputFormat: TimestampFormatRFC3339},
+	} {
+		_, err := NewTimestampNormalizer(config, configuration.ParserConfig{})
+		assert.Error(t, err)
+	}
+
+	_, err := NewTimestampNormalizer(configuration.TimestampConfig{
+		OutputField:  "@timestamp",
+		Formats:      "epoch_ms,2006-01-02 15:04:05",
+		OutputFormat: "2006-01-02T15:04:05.000Z07:00",
+	}, configuration.ParserConfig{})
+	assert.NoError(t, err)
+}
@@ -0,0 +1,182 @@
+package processors
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/2gis/loggo/common"
+	"github.com/2gis/loggo/configuration"
+)
+
+/* Canonical trace context fields */
+const (
+	FieldTraceID = "trace_id"
+	FieldSpanID  = "span_id"
+)
+
+/* Trace context headers, see W3C Trace Context and B3 propagation */
+const (
+	traceContextTraceparent = "traceparent"
+	traceContextB3          = "b3"
+)
+
+// traceIDFields and spanIDFields are user log fields with separate ids, compared case-insensitively
+var (
+	traceIDFields = []string{"trace_id", "traceid", "x-b3-traceid", "trace.id"}
+	spanIDFields  = []string{"span_id", "spanid", "x-b3-spanid", "span.id"}
+)
+
+var (
+	patternTraceparent = regexp.MustCompile(`^[0-9a-f]{2}-([0-9a-f]{32})-([0-9a-f]{16})-[0-9a-f]{2}`)
+	patternB3          = regexp.MustCompile(`^([0-9a-f]{32}|[0-9a-f]{16})-([0-9a-f]{16})(-|$)`)
+	patternHexID       = regexp.MustCompile(`^([0-9a-f]{32}|[0-9a-f]{16})$`)
+
+	// raw log patterns, ids are looked for in traceparent values and key=value or "key":"value" pairs
+	patternRawTraceparent = regexp.MustCompile(`(?i)\b[0-9a-f]{2}-([0-9a-f]{32})-([0-9a-f]{16})-[0-9a-f]{2}\b`)
+	patternRawTraceID     = regexp.MustCompile(
+		`(?i)\b(?:trace_?id|x-b3-traceid)["']?\s*[=:]\s*["']?([0-9a-f]{32}|[0-9a-f]{16})\b`)
+	patternRawSpanID = regexp.MustCompile(`(?i)\b(?:span_?id|x-b3-spanid)["']?\s*[=:]\s*["']?([0-9a-f]{16})\b`)
+)
+
+// TraceContextNormalizer looks for trace context in user log fields (W3C traceparent, B3 single or multi header,
+// separate trace and span id fields) or in the raw log, then puts lowercase hex ids to the top-level trace_id and
+// span_id fields; 64-bit trace ids are left-padded to 128 bits. Entries with and without trace context are counted
+// per namespace
+type TraceContextNormalizer struct {
+	collector MetricsCollector
+
+	userLogField string
+	rawLogField  string
+	extendsField string
+}
+
+// NewTraceContextNormalizer is a TraceContextNormalizer constructor
+func NewTraceContextNormalizer(
+	collector MetricsCollector, parserConfig configuration.ParserConfig) *TraceContextNormalizer {
+	return &TraceContextNormalizer{
+		collector:    collector,
+		userLogField: parserConfig.UserLogFieldsKey,
+		rawLogField:  parserConfig.RawLogFieldKey,
+		extendsField: parserConfig.ExtendsFieldsKey,
+	}
+}
+
+// Process sets canonical trace context fields if trace context is found, entries are never dropped
+func (n *TraceContextNormalizer) Process(entryMap common.EntryMap) bool {
+	traceID, spanID := "", ""
+
+	if base, ok := subMap(entryMap, n.userLogField); ok {
+		traceID, spanID = n.lookup(base)
+	}
+
+	if traceID != "" {
+		entryMap[FieldTraceID] = traceID
+
+		if spanID != "" {
+			entryMap[FieldSpanID] = spanID
+		}
+	}
+
+	namespace := ""
+
+	if extends, ok := subMap(entryMap, n.extendsField); ok {
+		namespace = extends.NamespaceName()
+	}
+
+	n.collector.IncrementTraceContextCount(namespace, traceID != "")
+	return true
+}
+
+// lookup returns trace and span ids of user log, span id may be empty
+func (n *TraceContextNormalizer) lookup(base common.EntryMap) (string, string) {
+	fields := make(map[string]string, len(base))
+
+	for key, value := range base {
+		if value, ok := value.(string); ok {
+			fields[strings.ToLower(key)] = strings.ToLower(strings.TrimSpace(value))
+		}
+	}
+
+	if output := patternTraceparent.FindStringSubmatch(fields[traceContextTraceparent]); output != nil {
+		if traceID, spanID := validTraceID(output[1]), validSpanID(output[2]); traceID != "" {
+			return traceID, spanID
+		}
+	}
+
+	if output := patternB3.FindStringSubmatch(fields[traceContextB3]); output != nil {
+		if traceID, spanID := validTraceID(output[1]), validSpanID(output[2]); traceID != "" {
+			return traceID, spanID
+		}
+	}
+
+	for _, field := range traceIDFields {
+		traceID := validTraceID(fields[field])
+
+		if traceID == "" {
+			continue
+		}
+
+		for _, field := range spanIDFields {
+			if spanID := validSpanID(fields[field]); spanID != "" {
+				return traceID, spanID
+			}
+		}
+
+		return traceID, ""
+	}
+
+	raw, ok := base[n.rawLogField].(string)
+
+	if !ok || n.rawLogField == "" {
+		return "", ""
+	}
+
+	return lookupRaw(raw)
+}
+
+// lookupRaw looks for trace context in unparsed log line
+func lookupRaw(raw string) (string, string) {
+	if output := patternRawTraceparent.FindStringSubmatch(raw); output != nil {
+		traceID, spanID := validTraceID(strings.ToLower(output[1])), validSpanID(strings.ToLower(output[2]))
+
+		if traceID != "" {
+			return traceID, spanID
+		}
+	}
+
+	output := patternRawTraceID.FindStringSubmatch(raw)
+
+	if output == nil {
+		return "", ""
+	}
+
+	traceID, spanID := validTraceID(strings.ToLower(output[1])), ""
+
+	if output := patternRawSpanID.FindStringSubmatch(raw); output != nil && traceID != "" {
+		spanID = validSpanID(strings.ToLower(output[1]))
+	}
+
+	return traceID, spanID
+}
+
+// validTraceID returns 128-bit trace id of lowercase hex, or empty string for invalid and all-zero ids
+func validTraceID(id string) string {
+	if !patternHexID.MatchString(id) || strings.Trim(id, "0") == "" {
+		return ""
+	}
+
+	if len(id) == 16 {
+		return strings.Repeat("0", 16) + id
+	}
+
+	return id
+}
+
+// validSpanID returns span id of lowercase hex, or empty string for invalid and all-zero ids
+func validSpanID(id string) string {
+	if len(id) != 16 || !patternHexID.MatchString(id) || strings.Trim(id, "0") == "" {
+		return ""
+	}
+
+	return id
+}
@@ -0,0 +1,88 @@
+package processors
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/2gis/loggo/common"
+	"github.com/2gis/loggo/configuration"
+	"github.com/2gis/loggo/tests/mocks"
+)
+
+type traceContextCollector struct {
+	mocks.CollectorMock
+	counts map[string]map[bool]int
+}
+
+func (c *traceContextCollector) IncrementTraceContextCount(namespace string, present bool) {
+	if c.counts[namespace] == nil {
+		c.counts[namespace] = make(map[bool]int)
+	}
+
+	c.counts[namespace][present]++
+}
+
+func TestTraceContextNormalizer(t *testing.T) {
+	const (
+		traceID   = "4bf92f3577b34da6a3ce929d0e0e4736"
+		spanID    = "00f067aa0ba902b7"
+		traceID64 = "a3ce929d0e0e4736"
+	)
+
+	collector := &traceContextCollector{counts: make(map[string]map[bool]int)}
+	normalizer := NewTraceContextNormalizer(collector, configuration.ParserConfig{
+		UserLogFieldsKey: "log",
+		RawLogFieldKey:   "raw",
+		ExtendsFieldsKey: "extends",
+	})
+
+	tests := []struct {
+		userLog common.EntryMap
+		traceID string
+		spanID  string
+	}{
+		{common.EntryMap{"traceparent": "00-" + traceID + "-" + spanID + "-01"}, traceID, spanID},
+		{common.EntryMap{"traceparent": "00-" + traceID + "-0000000000000000-01"}, traceID, ""},
+		{common.EntryMap{"b3": traceID + "-" + spanID + "-1"}, traceID, spanID},
+		{common.EntryMap{"b3": traceID64 + "-" + spanID}, "0000000000000000" + traceID64, spanID},
+		{common.EntryMap{"X-B3-TraceId": traceID64, "X-B3-SpanId": spanID}, "0000000000000000" + traceID64, spanID},
+		{common.EntryMap{"traceId": "4BF92F3577B34DA6A3CE929D0E0E4736", "spanId": spanID}, traceID, spanID},
+		{common.EntryMap{"trace_id": traceID}, traceID, ""},
+		{common.EntryMap{"raw": "GET / 200 traceparent=00-" + traceID + "-" + spanID + "-01"}, traceID, spanID},
+		{common.EntryMap{"raw": `request done trace_id=` + traceID + ` span_id=` + spanID}, traceID, spanID},
+		{common.EntryMap{"raw": `{"traceId": "` + traceID64 + `"`}, "0000000000000000" + traceID64, ""},
+		{common.EntryMap{"trace_id": "00000000000000000000000000000000"}, "", ""},
+		{common.EntryMap{"trace_id": "not an id", "b3": "1"}, "", ""},
+		{common.EntryMap{"raw": "nothing to see here"}, "", ""},
+	}
+
+	for _, test := range tests {
+		entryMap := common.EntryMap{
+			"log":     test.userLog,
+			"extends": common.EntryMap{common.KubernetesNamespaceName: "prod"},
+		}
+
+		assert.True(t, normalizer.Process(entryMap))
+
+		traceID, ok := entryMap[FieldTraceID]
+		assert.Equal(t, test.traceID != "", ok, test.userLog)
+
+		if ok {
+			assert.Equal(t, test.traceID, traceID, test.userLog)
+		}
+
+		spanID, ok := entryMap[FieldSpanID]
+		assert.Equal(t, test.spanID != "", ok, test.userLog)
+
+		if ok {
+			assert.Equal(t, test.spanID, spanID, test.userLog)
+		}
+	}
+
+	assert.True(t, normalizer.Process(common.EntryMap{"log": "not a map"}))
+	assert.Equal(t, map[string]map[bool]int{
+		"prod": {true: 10, false: 3},
+		"":     {false: 1},
+	}, collector.counts)
+}
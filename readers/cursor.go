@@ -2,27 +2,39 @@ package readers
 
 import (
 	"fmt"
+	"hash/crc32"
+	"io"
 	"strconv"
 	"strings"
 )
 
+// fingerprintSize is the length of the file beginning the cursor fingerprint is computed of
+const fingerprintSize = 1024
+
 // Cursor position in file
 type Cursor struct {
 	Inode  uint64
 	Device uint64
 	Value  int64
+	// Fingerprint is a checksum of the first min(fingerprintSize, Value) bytes of the file, it tells the file from
+	// a new one with the reused inode; empty for cursors stored by older versions and cursors of compressed files
+	Fingerprint string
 }
 
-// String Cursor representation
+// String Cursor representation; cursors without fingerprint keep the older format
 func (cursor *Cursor) String() string {
-	return fmt.Sprintf("%d;%d;%d", cursor.Inode, cursor.Device, cursor.Value)
+	if cursor.Fingerprint == "" {
+		return fmt.Sprintf("%d;%d;%d", cursor.Inode, cursor.Device, cursor.Value)
+	}
+
+	return fmt.Sprintf("%d;%d;%d;%s", cursor.Inode, cursor.Device, cursor.Value, cursor.Fingerprint)
 }
 
 // NewCursorFromString is a constructor
 func NewCursorFromString(cursorString string) (*Cursor, error) {
 	statInfo := strings.Split(cursorString, ";")
 
-	if len(statInfo) != 3 {
+	if len(statInfo) != 3 && len(statInfo) != 4 {
 		return &Cursor{}, fmt.Errorf("cannot construct cursor from string %s", cursorString)
 	}
 
@@ -44,9 +56,54 @@ func NewCursorFromString(cursorString string) (*Cursor, error) {
 		return &Cursor{}, fmt.Errorf("cannot construct cursor from string %s", cursorString)
 	}
 
-	return &Cursor{
+	cursor := &Cursor{
 		Inode:  inode,
 		Device: dev,
 		Value:  int64(value),
-	}, nil
+	}
+
+	if len(statInfo) == 4 {
+		if _, err = strconv.ParseUint(statInfo[3], 16, 32); err != nil {
+			return &Cursor{}, fmt.Errorf("cannot construct cursor from string %s", cursorString)
+		}
+
+		cursor.Fingerprint = statInfo[3]
+	}
+
+	return cursor, nil
+}
+
+// fingerprintLength returns the length of the file beginning fingerprint is computed of for the offset
+func fingerprintLength(value int64) int64 {
+	if value < fingerprintSize {
+		return value
+	}
+
+	return fingerprintSize
+}
+
+// fingerprint computes checksum of the first length bytes of the source; empty string is returned for zero length
+func fingerprint(source io.Reader, length int64) (string, error) {
+	if length == 0 {
+		return "", nil
+	}
+
+	hash := crc32.NewIEEE()
+
+	if _, err := io.CopyN(hash, source, length); err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%08x", hash.Sum32()), nil
+}
+
+// fingerprintMatches checks that the source begins with the content cursor fingerprint is computed of; cursors without
+// fingerprint match anything
+func fingerprintMatches(source io.Reader, cursor *Cursor) bool {
+	if cursor.Fingerprint == "" {
+		return true
+	}
+
+	checksum, err := fingerprint(source, fingerprintLength(cursor.Value))
+	return err == nil && checksum == cursor.Fingerprint
 }
@@ -1,6 +1,7 @@
 package readers
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -9,8 +10,13 @@ import (
 func TestNewCursorFromString(t *testing.T) {
 	cursor, err := NewCursorFromString("0;1;2")
 	assert.NoError(t, err)
-	assert.Equal(t, &Cursor{0, 1, 2}, cursor)
+	assert.Equal(t, &Cursor{Inode: 0, Device: 1, Value: 2}, cursor)
 	assert.Equal(t, "0;1;2", cursor.String())
+
+	cursor, err = NewCursorFromString("0;1;2;0a1b2c3d")
+	assert.NoError(t, err)
+	assert.Equal(t, &Cursor{Inode: 0, Device: 1, Value: 2, Fingerprint: "0a1b2c3d"}, cursor)
+	assert.Equal(t, "0;1;2;0a1b2c3d", cursor.String())
 }
 
 func TestNewCursorFromStringNegative(t *testing.T) {
@@ -27,4 +33,27 @@ func TestNewCursorFromStringNegative(t *testing.T) {
 	assert.Error(t, err)
 	_, err = NewCursorFromString("abc;zxc;1")
 	assert.Error(t, err)
+
+	_, err = NewCursorFromString("1;1;2;xyz")
+	assert.Error(t, err)
+	_, err = NewCursorFromString("1;1;2;0a1b2c3d;1")
+	assert.Error(t, err)
+}
+
+func TestFingerprint(t *testing.T) {
+	checksum, err := fingerprint(strings.NewReader("value1\n"), 7)
+	assert.NoError(t, err)
+	assert.Len(t, checksum, 8)
+
+	assert.True(t, fingerprintMatches(strings.NewReader("value1\nvalue2\n"), &Cursor{Value: 7, Fingerprint: checksum}))
+	assert.False(t, fingerprintMatches(strings.NewReader("other1\nother2\n"), &Cursor{Value: 7, Fingerprint: checksum}))
+	assert.False(t, fingerprintMatches(strings.NewReader("value"), &Cursor{Value: 7, Fingerprint: checksum}))
+	assert.True(t, fingerprintMatches(strings.NewReader("other1\n"), &Cursor{Value: 7}))
+
+	// cursor values beyond fingerprintSize share the fingerprint of the file beginning
+	assert.Equal(t, int64(fingerprintSize), fingerprintLength(fingerprintSize*2))
+
+	checksum, err = fingerprint(strings.NewReader(""), 0)
+	assert.NoError(t, err)
+	assert.Empty(t, checksum)
 }
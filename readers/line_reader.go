@@ -3,32 +3,94 @@ package readers
 import (
 	"bufio"
 	"bytes"
+	"compress/gzip"
+	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
 	"syscall"
+	"time"
 )
 
+// ErrTruncated is returned by EntryRead when the file has been truncated in place (copytruncate rotation, for instance)
+// and reading is restarted from the beginning
+var ErrTruncated = errors.New("file has been truncated")
+
+// ErrRotatedPositionUnknown is returned by EntryRead when the position stored can't be attributed to one of rotated
+// files for sure, the file supposed to be unfinished is read from the beginning
+var ErrRotatedPositionUnknown = errors.New("position in rotated files is unknown")
+
+// rotationDrainTimeout is how long the descriptor of rotated or removed file is read after that, unless the new file
+// gets content earlier; the writer may append to the old file until it reopens the path
+const rotationDrainTimeout = 5 * time.Second
+
+const suffixCompressed = ".gz"
+
+// patternRotatedSuffix matches suffixes of container logs rotated by kubelet: rotation timestamp, all but the latest
+// rotated files are compressed
+var patternRotatedSuffix = regexp.MustCompile(`^\.\d{8}-\d{6}(\.gz)?$`)
+
 // LineReader is used for reading lines from file. Signals about rotations (fs notify rename)
 type LineReader struct {
 	filePath     string
 	fileHandler  *os.File
 	buffer       *bufio.Reader
+	counter      *countingReader
+	offset       int64
+	compressed   bool
 	bufferSize   int
 	cursor       *Cursor
 	fromTailFlag bool
+
+	// rotated siblings left to read before the file itself
+	rotated        []string
+	readingRotated bool
+
+	rotatedAt    time.Time
+	switchFlag   bool
+	drainTimeout time.Duration
+
+	// fingerprint of fingerprinted bytes of the file beginning
+	fingerprint   string
+	fingerprinted int64
+
+	// truncation or unknown position detected on start, it's reported by the first EntryRead
+	startErr error
+	// EOF of the file has been reached, it's checked for truncation before reading goes on
+	eofFlag bool
+}
+
+// countingReader counts bytes read; positions in compressed files can't be obtained by seeking
+type countingReader struct {
+	reader io.Reader
+	count  int64
 }
 
-// NewLineReader is a constructor for LineReader
-func NewLineReader(filePath string, bufferSize int, initialCursor *Cursor, fromTailFlag bool) (*LineReader, error) {
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	r.count += int64(n)
+	return n, err
+}
+
+// NewLineReader is a constructor for LineReader; cursor is used if it points to the same file: inode, device and
+// fingerprint match. If readRotatedFlag is set and the cursor points to the file rotated since, unfinished rotated
+// siblings are read before the file itself
+func NewLineReader(filePath string, bufferSize int, initialCursor *Cursor, fromTailFlag,
+	readRotatedFlag bool) (*LineReader, error) {
 	reader := LineReader{
 		filePath:     filePath,
 		bufferSize:   bufferSize,
 		fromTailFlag: fromTailFlag,
 		cursor:       &Cursor{},
+		drainTimeout: rotationDrainTimeout,
 	}
 
-	inode, device, err := reader.getStatInfo()
+	inode, device, size, err := statFile(filePath)
 
 	if err != nil {
 		return nil, err
@@ -41,19 +103,53 @@ func NewLineReader(filePath string, bufferSize int, initialCursor *Cursor, fromT
 		},
 	)
 
-	// check whether we should use storage cursor
-	cursorValidFlag := inode == initialCursor.Inode && device == initialCursor.Device
+	// check whether we should use storage cursor; the inode may have been reused by a new file, or the file may have
+	// been truncated since the cursor was stored
+	inodeMatchFlag := inode == initialCursor.Inode && device == initialCursor.Device
+	sameFileFlag := inodeMatchFlag && fileFingerprintMatches(filePath, initialCursor)
+	cursorValidFlag := sameFileFlag && size >= initialCursor.Value
 
 	if cursorValidFlag {
 		reader.setCursor(initialCursor)
 	}
 
-	err = reader.acquireSource(!cursorValidFlag)
+	// the file has been truncated while the cursor was stored, it's read from the beginning; a new file that got the
+	// inode doesn't begin with the content read, it isn't a truncation
+	if sameFileFlag && size < initialCursor.Value {
+		reader.startErr = ErrTruncated
+	}
+
+	offsetKnownFlag := true
+
+	if !sameFileFlag && readRotatedFlag && initialCursor.Inode != 0 {
+		reader.rotated, offsetKnownFlag = unfinishedRotated(filePath, initialCursor)
+	}
+
+	if len(reader.rotated) > 0 {
+		reader.readingRotated = true
+		reader.startErr = nil
+		offset := initialCursor.Value
+
+		if !offsetKnownFlag {
+			reader.startErr = ErrRotatedPositionUnknown
+			offset = 0
+		}
+
+		err = reader.acquireRotated(offset)
+	} else {
+		// files with the same inode are new to the reader, they're read from the beginning
+		err = reader.acquireSource(!inodeMatchFlag)
+	}
 
 	if err != nil {
 		return nil, err
 	}
 
+	if cursorValidFlag && initialCursor.Fingerprint != "" {
+		reader.fingerprint = initialCursor.Fingerprint
+		reader.fingerprinted = fingerprintLength(initialCursor.Value)
+	}
+
 	return &reader, nil
 }
 
@@ -63,59 +159,151 @@ func (reader *LineReader) EntryRead() ([]byte, bool, error) {
 		return nil, false, fmt.Errorf("journal from specified path '%s' isn't acquired", reader.filePath)
 	}
 
-	buffer, prefixFlag, err := reader.buffer.ReadLine()
+	if reader.startErr != nil {
+		err := reader.startErr
+		reader.startErr = nil
+		return nil, false, err
+	}
+
+	if reader.eofFlag {
+		reader.eofFlag = false
+
+		if err := reader.checkResumed(); err != nil {
+			return nil, false, err
+		}
+	}
+
+	for {
+		buffer, prefixFlag, err := reader.buffer.ReadLine()
+
+		if len(buffer) != 0 {
+			reader.setCursor(
+				&Cursor{
+					Inode:  reader.cursor.Inode,
+					Device: reader.cursor.Device,
+					Value:  reader.offset + reader.counter.count - int64(reader.buffer.Buffered()),
+				},
+			)
+			reader.updateFingerprint()
+			result := make([]byte, len(buffer))
+			copy(result, buffer)
+
+			return result, prefixFlag, nil
+		}
+
+		// empty line
+		if err == nil {
+			continue
+		}
+
+		if err != io.EOF {
+			return nil, false, err
+		}
+
+		switched, err := reader.next()
+
+		if err != nil || !switched {
+			return nil, false, err
+		}
+	}
+}
+
+// next is called at EOF of the current source; it checks if file has been rotated, truncated or deleted and switches
+// to the next source, the flag returned means reading should go on
+func (reader *LineReader) next() (bool, error) {
+	if reader.readingRotated {
+		return true, reader.acquireRotated(0)
+	}
+
+	inode, device, size, err := statFile(reader.filePath)
+
+	if err != nil && !os.IsNotExist(err) {
+		reader.setCursor(&Cursor{})
+		return false, err
+	}
 
-	if int64(len(buffer)) != 0 {
-		position, errSeek := reader.fileHandler.Seek(0, 1)
+	if err == nil && inode == reader.cursor.Inode && device == reader.cursor.Device {
+		reader.eofFlag = true
+		return false, reader.checkTruncation(size)
+	}
 
-		// should not happen normally
-		if errSeek != nil {
-			return nil, false, errSeek
+	// the old descriptor is read once more after the new file has got content, the writer has switched to it then
+	if reader.switchFlag {
+		reader.switchFlag = false
+		reader.rotatedAt = time.Time{}
+
+		if err != nil {
+			reader.setCursor(&Cursor{})
+			return false, err
 		}
 
 		reader.setCursor(
 			&Cursor{
-				Inode:  reader.cursor.Inode,
-				Device: reader.cursor.Device,
-				Value:  position - int64(reader.buffer.Buffered()),
+				Inode:  inode,
+				Device: device,
+				Value:  0,
 			},
 		)
-		result := make([]byte, len(buffer))
-		copy(result, buffer)
-
-		return result, prefixFlag, nil
+		return true, reader.acquireSource(false)
 	}
 
-	if err != io.EOF {
-		return nil, false, err
+	if reader.rotatedAt.IsZero() {
+		reader.rotatedAt = time.Now()
 	}
 
-	// check if file has been rotated or deleted
-	inode, device, err := reader.getStatInfo()
-
-	if err != nil {
-		reader.setCursor(&Cursor{})
-		return nil, false, err
+	if (err == nil && size > 0) || time.Since(reader.rotatedAt) >= reader.drainTimeout {
+		reader.switchFlag = true
+		return true, nil
 	}
 
-	if inode == reader.cursor.Inode && device == reader.cursor.Device {
-		return nil, false, nil
+	return false, nil
+}
+
+// checkTruncation starts reading from the beginning if the file is shorter than the offset read or its beginning
+// differs from the one read: the file has been truncated and written up to the offset again since
+func (reader *LineReader) checkTruncation(size int64) error {
+	if size >= reader.cursor.Value && reader.beginningMatches() {
+		return nil
 	}
 
 	reader.setCursor(
 		&Cursor{
-			Inode:  inode,
-			Device: device,
+			Inode:  reader.cursor.Inode,
+			Device: reader.cursor.Device,
 			Value:  0,
 		},
 	)
-	err = reader.acquireSource(false)
 
-	if err != nil {
-		return nil, false, err
+	if err := reader.acquireSource(false); err != nil {
+		return err
 	}
 
-	return nil, false, nil
+	return ErrTruncated
+}
+
+// checkResumed checks the file for truncation before reading goes on after EOF; the file may have been truncated and
+// written past the offset meanwhile
+func (reader *LineReader) checkResumed() error {
+	inode, device, size, err := statFile(reader.filePath)
+
+	if err != nil || inode != reader.cursor.Inode || device != reader.cursor.Device {
+		return nil
+	}
+
+	return reader.checkTruncation(size)
+}
+
+// beginningMatches checks that the file still begins with the content fingerprint has been computed of; the file is
+// considered unchanged if it can't be read
+func (reader *LineReader) beginningMatches() bool {
+	if reader.fingerprint == "" {
+		return true
+	}
+
+	checksum, err := fingerprint(
+		io.NewSectionReader(reader.fileHandler, 0, reader.fingerprinted), reader.fingerprinted,
+	)
+	return err != nil || checksum == reader.fingerprint
 }
 
 func (reader *LineReader) acquireSource(considerTailFlag bool) error {
@@ -137,26 +325,216 @@ func (reader *LineReader) acquireSource(considerTailFlag bool) error {
 	}
 
 	reader.cursor.Value = offset
-	buffer := bufio.NewReaderSize(fileHandler, reader.bufferSize)
-	reader.fileHandler = fileHandler
-	reader.buffer = buffer
+	reader.setSource(fileHandler, fileHandler, offset)
+	return nil
+}
+
+// acquireRotated opens the next rotated sibling from the offset, the file itself is opened after the last one;
+// siblings that can't be read (compressed and removed by kubelet meanwhile, for instance) are skipped
+func (reader *LineReader) acquireRotated(offset int64) error {
+	for len(reader.rotated) > 0 {
+		path := reader.rotated[0]
+		reader.rotated = reader.rotated[1:]
+
+		if err := reader.openRotated(path, offset); err == nil {
+			return nil
+		}
+
+		offset = 0
+	}
+
+	reader.readingRotated = false
+	inode, device, _, err := statFile(reader.filePath)
+
+	if err != nil {
+		reader.Close()
+		return err
+	}
+
+	reader.setCursor(
+		&Cursor{
+			Inode:  inode,
+			Device: device,
+			Value:  0,
+		},
+	)
+	return reader.acquireSource(false)
+}
+
+// openRotated opens rotated sibling, skipping offset bytes of its content
+func (reader *LineReader) openRotated(path string, offset int64) error {
+	reader.Close()
+	inode, device, _, err := statFile(path)
+
+	if err != nil {
+		return err
+	}
+
+	fileHandler, err := os.Open(path)
+
+	if err != nil {
+		return err
+	}
+
+	source, err := decompressed(path, fileHandler)
+
+	if err != nil {
+		fileHandler.Close()
+		return err
+	}
+
+	// sibling shorter than offset has been read to the end
+	if _, err = io.CopyN(ioutil.Discard, source, offset); err != nil {
+		fileHandler.Close()
+		return err
+	}
+
+	reader.setCursor(
+		&Cursor{
+			Inode:  inode,
+			Device: device,
+			Value:  offset,
+		},
+	)
+	reader.setSource(fileHandler, source, offset)
+	reader.compressed = source != io.Reader(fileHandler)
 	return nil
 }
 
-func (reader *LineReader) getStatInfo() (uint64, uint64, error) {
-	stat, err := os.Stat(reader.filePath)
+func (reader *LineReader) setSource(fileHandler *os.File, source io.Reader, offset int64) {
+	reader.fileHandler = fileHandler
+	reader.counter = &countingReader{reader: source}
+	reader.buffer = bufio.NewReaderSize(reader.counter, reader.bufferSize)
+	reader.offset = offset
+	reader.compressed = false
+	reader.fingerprint = ""
+	reader.fingerprinted = 0
+}
+
+// updateFingerprint sets fingerprint of the file beginning read so far to the cursor; it's recomputed until
+// fingerprintSize bytes are read, compressed files aren't fingerprinted
+func (reader *LineReader) updateFingerprint() {
+	if reader.compressed {
+		return
+	}
+
+	length := fingerprintLength(reader.cursor.Value)
+
+	if length != reader.fingerprinted {
+		checksum, err := fingerprint(io.NewSectionReader(reader.fileHandler, 0, length), length)
+
+		if err != nil {
+			return
+		}
+
+		reader.fingerprint = checksum
+		reader.fingerprinted = length
+	}
+
+	reader.cursor.Fingerprint = reader.fingerprint
+}
+
+// decompressed returns reader of the file content, compressed files are decompressed
+func decompressed(path string, fileHandler *os.File) (io.Reader, error) {
+	if !strings.HasSuffix(path, suffixCompressed) {
+		return fileHandler, nil
+	}
+
+	return gzip.NewReader(fileHandler)
+}
+
+// fileFingerprintMatches checks that the file content begins with the content cursor fingerprint is computed of
+func fileFingerprintMatches(path string, cursor *Cursor) bool {
+	if cursor.Fingerprint == "" {
+		return true
+	}
+
+	fileHandler, err := os.Open(path)
+
+	if err != nil {
+		return false
+	}
+
+	defer fileHandler.Close()
+	source, err := decompressed(path, fileHandler)
+
+	return err == nil && fingerprintMatches(source, cursor)
+}
+
+// unfinishedRotated returns rotated siblings starting from the one the cursor points to, oldest first; the flag
+// returned is unset if the cursor offset can't be applied to the first of them
+func unfinishedRotated(filePath string, cursor *Cursor) ([]string, bool) {
+	rotated := rotatedSiblings(filePath)
+
+	for i, path := range rotated {
+		inode, device, _, err := statFile(path)
+
+		if err == nil && inode == cursor.Inode && device == cursor.Device && fileFingerprintMatches(path, cursor) {
+			return rotated[i:], true
+		}
+	}
+
+	// the file has been compressed since, compressed files get new inodes, so their content is compared
+	if cursor.Fingerprint != "" {
+		for i := len(rotated) - 1; i >= 0; i-- {
+			if strings.HasSuffix(rotated[i], suffixCompressed) && fileFingerprintMatches(rotated[i], cursor) {
+				return rotated[i:], true
+			}
+		}
+
+		return nil, true
+	}
+
+	// cursors stored by older versions have no fingerprint; kubelet keeps the latest rotated file uncompressed, so
+	// it's the latest compressed one. That's certain only if there's the only compressed one, the file might have
+	// been rotated more times otherwise, and the offset isn't applied
+	latest, compressed := -1, 0
+
+	for i, path := range rotated {
+		if strings.HasSuffix(path, suffixCompressed) {
+			latest = i
+			compressed++
+		}
+	}
+
+	if latest < 0 {
+		return nil, true
+	}
+
+	return rotated[latest:], compressed == 1
+}
+
+// rotatedSiblings returns files rotated by kubelet, oldest first
+func rotatedSiblings(filePath string) []string {
+	paths, _ := filepath.Glob(filePath + ".*")
+	rotated := make([]string, 0, len(paths))
+
+	for _, path := range paths {
+		if patternRotatedSuffix.MatchString(strings.TrimPrefix(path, filePath)) {
+			rotated = append(rotated, path)
+		}
+	}
+
+	// timestamps are sortable
+	sort.Strings(rotated)
+	return rotated
+}
+
+// statFile returns inode, device and size of the file
+func statFile(path string) (uint64, uint64, int64, error) {
+	stat, err := os.Stat(path)
 
 	if err != nil {
-		return 0, 0, err
+		return 0, 0, 0, err
 	}
 
 	statInfo, ok := stat.Sys().(*syscall.Stat_t)
 
 	if !ok {
-		return 0, 0, err
+		return 0, 0, 0, err
 	}
 
-	return statInfo.Ino, statInfo.Dev, nil
+	return statInfo.Ino, statInfo.Dev, stat.Size(), nil
 }
 
 func (reader *LineReader) setCursor(cursor *Cursor) {
@@ -183,6 +561,7 @@ func (reader *LineReader) Close() error {
 
 	reader.fileHandler = nil
 	reader.buffer = nil
+	reader.counter = nil
 	return err
 }
 
@@ -1,7 +1,11 @@
 package readers
 
 import (
+	"compress/gzip"
+	"io/ioutil"
 	"os"
+	"path/filepath"
+	"strings"
 	"syscall"
 	"testing"
 
@@ -19,7 +23,7 @@ const (
 func TestLineReader_EntryRead_Positive(t *testing.T) {
 	createTestFile([]byte("value1\nvalue2"))
 
-	reader, err := NewLineReader(FilePathTemp, ReaderBufferSizeNormal, &Cursor{}, false)
+	reader, err := NewLineReader(FilePathTemp, ReaderBufferSizeNormal, &Cursor{}, false, false)
 	assert.NoError(t, err)
 
 	// read entry; second entry is not ended with \n
@@ -57,7 +61,7 @@ func TestLineReader_EntryRead_Positive(t *testing.T) {
 func TestSmallBufferBehavior(t *testing.T) {
 	_ = createTestFile([]byte("value1value2value3value4\nvalue5"))
 
-	reader, err := NewLineReader(FilePathTemp, ReaderBufferSizeSmall, &Cursor{}, false)
+	reader, err := NewLineReader(FilePathTemp, ReaderBufferSizeSmall, &Cursor{}, false, false)
 	assert.NoError(t, err)
 
 	byteString, prefixFlag, err := reader.EntryRead()
@@ -91,7 +95,7 @@ func TestSmallBufferBehavior(t *testing.T) {
 func TestLineReader_EntryRead_Positive_Rotation(t *testing.T) {
 	_ = createTestFile([]byte("value1\nvalue2"))
 
-	reader, err := NewLineReader(FilePathTemp, ReaderBufferSizeNormal, &Cursor{}, false)
+	reader, err := NewLineReader(FilePathTemp, ReaderBufferSizeNormal, &Cursor{}, false, false)
 	assert.NoError(t, err)
 	rotateTestFile([]byte("value3\nvalue4\n"))
 
@@ -102,12 +106,7 @@ func TestLineReader_EntryRead_Positive_Rotation(t *testing.T) {
 	assert.Equal(t, []byte("value2"), byteString)
 	assert.Equal(t, len("value1\nvalue2"), int(reader.GetCursor().Value))
 
-	// move counter is 0 here, so it invalidates but returns nil on this iteration
-	byteString, _, err = reader.EntryRead()
-	assert.NoError(t, err)
-	assert.Nil(t, byteString)
-	assert.Equal(t, 0, int(reader.GetCursor().Value))
-
+	// the old file is read to the end, the new one has content, so reading goes on with it
 	byteString, _, err = reader.EntryRead()
 	assert.NoError(t, err)
 	assert.Equal(t, []byte("value3"), byteString)
@@ -124,13 +123,202 @@ func TestLineReader_EntryRead_Positive_Rotation(t *testing.T) {
 	clean()
 }
 
+func TestLineReader_EntryRead_RotationDrain(t *testing.T) {
+	createTestFile([]byte("value1\n"))
+
+	reader, err := NewLineReader(FilePathTemp, ReaderBufferSizeNormal, &Cursor{}, false, false)
+	assert.NoError(t, err)
+
+	byteString, _, err := reader.EntryRead()
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("value1"), byteString)
+
+	// the writer appends to the old file until it reopens the path
+	rotateTestFile([]byte{})
+	appendFile(t, FilePathTempRotated, "value2\n")
+	inodeRotated := reader.GetCursor().Inode
+
+	byteString, _, err = reader.EntryRead()
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("value2"), byteString)
+
+	byteString, _, err = reader.EntryRead()
+	assert.NoError(t, err)
+	assert.Nil(t, byteString)
+	assert.Equal(t, inodeRotated, reader.GetCursor().Inode)
+
+	// lines written to the old file before the writer has switched aren't lost
+	appendFile(t, FilePathTempRotated, "value3\n")
+	appendFile(t, FilePathTemp, "value4\n")
+
+	for _, expected := range []string{"value3", "value4"} {
+		byteString, _, err = reader.EntryRead()
+		assert.NoError(t, err)
+		assert.Equal(t, []byte(expected), byteString)
+	}
+
+	inode, device, _ := getStatInfo()
+	assert.Equal(
+		t,
+		&Cursor{Inode: inode, Device: device, Value: 7, Fingerprint: testFingerprint(t, "value4\n")},
+		reader.GetCursor(),
+	)
+	clean()
+}
+
+func TestLineReader_EntryRead_RotationDrainTimeout(t *testing.T) {
+	createTestFile([]byte("value1\n"))
+
+	reader, err := NewLineReader(FilePathTemp, ReaderBufferSizeNormal, &Cursor{}, false, false)
+	assert.NoError(t, err)
+	reader.drainTimeout = 0
+	rotateTestFile([]byte{})
+
+	byteString, _, err := reader.EntryRead()
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("value1"), byteString)
+
+	// the new file is empty, it's switched to after timeout
+	byteString, _, err = reader.EntryRead()
+	assert.NoError(t, err)
+	assert.Nil(t, byteString)
+
+	inode, device, _ := getStatInfo()
+	assert.Equal(t, &Cursor{Inode: inode, Device: device}, reader.GetCursor())
+	clean()
+}
+
+func TestLineReader_RotatedSiblings(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "0.log")
+	compressed := path + ".20210101-100000.gz"
+	rotated := path + ".20210101-110000"
+
+	file, err := os.Create(compressed)
+	assert.NoError(t, err)
+	writer := gzip.NewWriter(file)
+	_, err = writer.Write([]byte("a1\na2\n"))
+	assert.NoError(t, err)
+	assert.NoError(t, writer.Close())
+	assert.NoError(t, file.Close())
+
+	assert.NoError(t, ioutil.WriteFile(rotated, []byte("b1\nb2\n"), 0644))
+	assert.NoError(t, ioutil.WriteFile(path, []byte("c1\n"), 0644))
+	assert.NoError(t, ioutil.WriteFile(path+".tmp", []byte("x\n"), 0644))
+
+	cursor := func(path string, value int64, content string) *Cursor {
+		inode, device, _, err := statFile(path)
+		assert.NoError(t, err)
+		return &Cursor{Inode: inode, Device: device, Value: value, Fingerprint: testFingerprint(t, content)}
+	}
+	readAll := func(reader *LineReader) []string {
+		lines := make([]string, 0)
+
+		for {
+			byteString, _, err := reader.EntryRead()
+			assert.NoError(t, err)
+
+			if byteString == nil {
+				return lines
+			}
+
+			lines = append(lines, string(byteString))
+		}
+	}
+
+	assert.Equal(t, []string{compressed, rotated}, rotatedSiblings(path))
+
+	for _, testCase := range []struct {
+		name     string
+		cursor   *Cursor
+		flag     bool
+		expected []string
+	}{
+		{"rotated", cursor(rotated, 3, "b1\n"), true, []string{"b2", "c1"}},
+		{"compressed", cursor(compressed, 3, ""), true, []string{"a2", "b1", "b2", "c1"}},
+		{"compressed since", &Cursor{Inode: 1, Device: 1, Value: 3}, true, []string{"a2", "b1", "b2", "c1"}},
+		{
+			"compressed since, fingerprint",
+			&Cursor{Inode: 1, Device: 1, Value: 3, Fingerprint: testFingerprint(t, "a1\n")},
+			true,
+			[]string{"a2", "b1", "b2", "c1"},
+		},
+		{
+			"fingerprint mismatch",
+			&Cursor{Inode: 1, Device: 1, Value: 3, Fingerprint: testFingerprint(t, "x1\n")},
+			true,
+			[]string{"c1"},
+		},
+		{"valid cursor", cursor(path, 0, ""), true, []string{"c1"}},
+		{"no cursor", &Cursor{}, true, []string{"c1"}},
+		{"disabled", cursor(rotated, 3, "b1\n"), false, []string{"c1"}},
+	} {
+		t.Run(testCase.name, func(t *testing.T) {
+			reader, err := NewLineReader(path, ReaderBufferSizeNormal, testCase.cursor, false, testCase.flag)
+			assert.NoError(t, err)
+			assert.Equal(t, testCase.expected, readAll(reader))
+			assert.Equal(t, cursor(path, 3, "c1\n"), reader.GetCursor())
+			assert.NoError(t, reader.Close())
+		})
+	}
+
+	// cursor points to the rotated file while it's read
+	reader, err := NewLineReader(path, ReaderBufferSizeNormal, cursor(compressed, 0, ""), false, true)
+	assert.NoError(t, err)
+
+	byteString, _, err := reader.EntryRead()
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("a1"), byteString)
+	// compressed files aren't fingerprinted
+	assert.Equal(t, cursor(compressed, 3, ""), reader.GetCursor())
+}
+
+func TestLineReader_RotatedSiblingsUnknownPosition(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "0.log")
+
+	for name, content := range map[string]string{
+		".20210101-100000.gz": "a1\na2\n",
+		".20210101-110000.gz": "b1\nb2\n",
+	} {
+		file, err := os.Create(path + name)
+		assert.NoError(t, err)
+		writer := gzip.NewWriter(file)
+		_, err = writer.Write([]byte(content))
+		assert.NoError(t, err)
+		assert.NoError(t, writer.Close())
+		assert.NoError(t, file.Close())
+	}
+
+	assert.NoError(t, ioutil.WriteFile(path+".20210101-120000", []byte("c1\n"), 0644))
+	assert.NoError(t, ioutil.WriteFile(path, []byte("d1\n"), 0644))
+
+	// cursor without fingerprint may belong to any of compressed files, the latest one is read from the beginning
+	reader, err := NewLineReader(path, ReaderBufferSizeNormal, &Cursor{Inode: 1, Device: 1, Value: 3}, false, true)
+	assert.NoError(t, err)
+
+	byteString, _, err := reader.EntryRead()
+	assert.Equal(t, ErrRotatedPositionUnknown, err)
+	assert.Nil(t, byteString)
+
+	for _, expected := range []string{"b1", "b2", "c1", "d1"} {
+		byteString, _, err := reader.EntryRead()
+		assert.NoError(t, err)
+		assert.Equal(t, []byte(expected), byteString)
+	}
+
+	assert.NoError(t, reader.Close())
+}
+
 func TestLineReader_EntryRead_Negative_Removal(t *testing.T) {
 	createTestFile([]byte("value1\nvalue2\n"))
 
-	reader, err := NewLineReader(FilePathTemp, ReaderBufferSizeNormal, &Cursor{}, false)
+	reader, err := NewLineReader(FilePathTemp, ReaderBufferSizeNormal, &Cursor{}, false, false)
 	assert.NoError(t, err)
 
 	os.Remove(FilePathTemp)
+	// the file isn't expected to be created again
+	reader.drainTimeout = 0
 
 	reader.EntryRead()
 	byteString, _, err := reader.EntryRead()
@@ -146,13 +334,13 @@ func TestLineReader_EntryRead_Negative_Removal(t *testing.T) {
 
 func TestNewLineReader_EntryRead_Negative_NotAcquired(t *testing.T) {
 	clean()
-	_, err := NewLineReader(FilePathTemp, ReaderBufferSizeNormal, &Cursor{}, false)
+	_, err := NewLineReader(FilePathTemp, ReaderBufferSizeNormal, &Cursor{}, false, false)
 	assert.Error(t, err)
 }
 
 func TestLineReader_TailFlag(t *testing.T) {
 	createTestFile([]byte("value1\nvalue2"))
-	reader, err := NewLineReader(FilePathTemp, ReaderBufferSizeNormal, &Cursor{}, true)
+	reader, err := NewLineReader(FilePathTemp, ReaderBufferSizeNormal, &Cursor{}, true, false)
 	assert.NoError(t, err)
 	extendTestFile([]byte("value3\nvalue4"))
 
@@ -171,14 +359,157 @@ func TestLineReader_TailFlag_CursorValid(t *testing.T) {
 	inode, device, _ := getStatInfo()
 
 	// must not consider tail flag if the cursor is valid -- i.e. stat info is the same as in storage record
-	reader, err := NewLineReader(FilePathTemp, ReaderBufferSizeNormal, &Cursor{Inode: inode, Device: device, Value: 2}, true)
+	reader, err := NewLineReader(FilePathTemp, ReaderBufferSizeNormal, &Cursor{Inode: inode, Device: device, Value: 2}, true, false)
 	assert.NoError(t, err)
 	assert.Equal(t, 2, int(reader.GetCursor().Value))
 }
 
+func TestLineReader_EntryRead_Truncation(t *testing.T) {
+	createTestFile([]byte("value1\nvalue2\n"))
+	inode, device, _ := getStatInfo()
+
+	reader, err := NewLineReader(FilePathTemp, ReaderBufferSizeNormal, &Cursor{}, false, false)
+	assert.NoError(t, err)
+
+	for _, expected := range []string{"value1", "value2"} {
+		byteString, _, err := reader.EntryRead()
+		assert.NoError(t, err)
+		assert.Equal(t, []byte(expected), byteString)
+	}
+
+	byteString, _, err := reader.EntryRead()
+	assert.NoError(t, err)
+	assert.Nil(t, byteString)
+
+	// copytruncate keeps the inode, the file is read from the beginning
+	truncateTestFile([]byte("value3\n"))
+	byteString, _, err = reader.EntryRead()
+	assert.Equal(t, ErrTruncated, err)
+	assert.Nil(t, byteString)
+	assert.Equal(t, &Cursor{Inode: inode, Device: device, Value: 0}, reader.GetCursor())
+
+	byteString, _, err = reader.EntryRead()
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("value3"), byteString)
+
+	byteString, _, err = reader.EntryRead()
+	assert.NoError(t, err)
+	assert.Nil(t, byteString)
+	clean()
+}
+
+func TestLineReader_EntryRead_TruncationRewritten(t *testing.T) {
+	createTestFile([]byte("value1\nvalue2\n"))
+	inode, device, _ := getStatInfo()
+
+	reader, err := NewLineReader(FilePathTemp, ReaderBufferSizeNormal, &Cursor{}, false, false)
+	assert.NoError(t, err)
+
+	for _, expected := range []string{"value1", "value2"} {
+		byteString, _, err := reader.EntryRead()
+		assert.NoError(t, err)
+		assert.Equal(t, []byte(expected), byteString)
+	}
+
+	byteString, _, err := reader.EntryRead()
+	assert.NoError(t, err)
+	assert.Nil(t, byteString)
+
+	// the file is truncated and written past the offset read while the reader waits
+	truncateTestFile([]byte("other1\nother2\nother3\n"))
+	byteString, _, err = reader.EntryRead()
+	assert.Equal(t, ErrTruncated, err)
+	assert.Nil(t, byteString)
+	assert.Equal(t, &Cursor{Inode: inode, Device: device, Value: 0}, reader.GetCursor())
+
+	for _, expected := range []string{"other1", "other2", "other3"} {
+		byteString, _, err := reader.EntryRead()
+		assert.NoError(t, err)
+		assert.Equal(t, []byte(expected), byteString)
+	}
+
+	byteString, _, err = reader.EntryRead()
+	assert.NoError(t, err)
+	assert.Nil(t, byteString)
+	clean()
+}
+
+func TestNewLineReader_CursorPastEOF(t *testing.T) {
+	createTestFile([]byte("value1\n"))
+	inode, device, _ := getStatInfo()
+
+	// the file has been truncated while the cursor was stored
+	reader, err := NewLineReader(
+		FilePathTemp, ReaderBufferSizeNormal, &Cursor{Inode: inode, Device: device, Value: 100}, false, false,
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), reader.GetCursor().Value)
+
+	// truncation is reported once
+	byteString, _, err := reader.EntryRead()
+	assert.Equal(t, ErrTruncated, err)
+	assert.Nil(t, byteString)
+
+	byteString, _, err = reader.EntryRead()
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("value1"), byteString)
+	clean()
+}
+
+func TestNewLineReader_InodeReuseSmallerFile(t *testing.T) {
+	createTestFile([]byte("other1\n"))
+	inode, device, _ := getStatInfo()
+
+	// the cursor of the removed file points beyond the end of the new one
+	stored := &Cursor{Inode: inode, Device: device, Value: 14, Fingerprint: testFingerprint(t, "value1\nvalue2\n")}
+	reader, err := NewLineReader(FilePathTemp, ReaderBufferSizeNormal, stored, false, false)
+	assert.NoError(t, err)
+
+	byteString, _, err := reader.EntryRead()
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("other1"), byteString)
+	assert.NoError(t, reader.Close())
+	clean()
+}
+
+func TestNewLineReader_Fingerprint(t *testing.T) {
+	createTestFile([]byte("value1\nvalue2\n"))
+	inode, device, _ := getStatInfo()
+	stored := &Cursor{Inode: inode, Device: device, Value: 7, Fingerprint: testFingerprint(t, "value1\n")}
+
+	reader, err := NewLineReader(FilePathTemp, ReaderBufferSizeNormal, stored, false, false)
+	assert.NoError(t, err)
+	assert.Equal(t, stored, reader.GetCursor())
+	assert.NoError(t, reader.Close())
+
+	// new file has got the inode of the removed one
+	truncateTestFile([]byte("other1\nother2\n"))
+
+	for _, testCase := range []struct {
+		name     string
+		cursor   *Cursor
+		expected string
+	}{
+		{"inode reuse", stored, "other1"},
+		{"no fingerprint", &Cursor{Inode: inode, Device: device, Value: 7}, "other2"},
+	} {
+		t.Run(testCase.name, func(t *testing.T) {
+			reader, err := NewLineReader(FilePathTemp, ReaderBufferSizeNormal, testCase.cursor, true, false)
+			assert.NoError(t, err)
+
+			byteString, _, err := reader.EntryRead()
+			assert.NoError(t, err)
+			assert.Equal(t, []byte(testCase.expected), byteString)
+			assert.NoError(t, reader.Close())
+		})
+	}
+
+	clean()
+}
+
 func TestLineReader_Close(t *testing.T) {
 	createTestFile([]byte("value1\nvalue2"))
-	reader, err := NewLineReader(FilePathTemp, ReaderBufferSizeNormal, &Cursor{}, true)
+	reader, err := NewLineReader(FilePathTemp, ReaderBufferSizeNormal, &Cursor{}, true, false)
 	assert.NoError(t, err)
 	reader.Close()
 
@@ -188,6 +519,12 @@ func TestLineReader_Close(t *testing.T) {
 	clean()
 }
 
+func testFingerprint(t *testing.T, content string) string {
+	checksum, err := fingerprint(strings.NewReader(content), int64(len(content)))
+	assert.NoError(t, err)
+	return checksum
+}
+
 func TestGetLastSeparatorPosition(t *testing.T) {
 	createTestFile([]byte("value1\nvalue2"))
 	fileHandler, _ := os.Open(FilePathTemp)
@@ -266,6 +603,37 @@ func rotateTestFile(payload []byte) error {
 	return createTestFile(payload)
 }
 
+// truncateTestFile imitates copytruncate rotation: the file is copied, then truncated in place and written again
+func truncateTestFile(payload []byte) error {
+	content, err := ioutil.ReadFile(FilePathTemp)
+
+	if err != nil {
+		return err
+	}
+
+	if err = ioutil.WriteFile(FilePathTempRotated, content, 0666); err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(FilePathTemp, os.O_TRUNC|os.O_RDWR, 0666)
+
+	if err != nil {
+		return err
+	}
+
+	file.Write(payload)
+	file.Close()
+	return nil
+}
+
+func appendFile(t *testing.T, path, payload string) {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0666)
+	assert.NoError(t, err)
+	_, err = file.WriteString(payload)
+	assert.NoError(t, err)
+	assert.NoError(t, file.Close())
+}
+
 func clean() {
 	os.Remove(FilePathTemp)
 	os.Remove(FilePathTempRotated)
@@ -7,12 +7,19 @@ type ParserSLI interface {
 	Parse(entryMap common.EntryMap)
 }
 
+// Processor modifies parsed entry in place; false result means entry should be dropped
+type Processor interface {
+	Process(entryMap common.EntryMap) bool
+}
+
 // MetricsCollector is a metrics counter object interface for parsing sli stage
 type MetricsCollector interface {
 	IncrementHTTPRequestCount(podName, method, service, path string, status int)
 	IncrementHTTPRequestsTotalCount(service string)
-	ObserveHTTPRequestTime(podName, method, service, path string, value float64)
-	ObserveHTTPUpstreamResponseTimeTotal(podName, method, service, path string, value float64)
+	ObserveHTTPRequestTime(
+		podName, method, service, path string, value float64, buckets []float64, exemplar map[string]string)
+	ObserveHTTPUpstreamResponseTimeTotal(
+		podName, method, service, path string, value float64, buckets []float64, exemplar map[string]string)
 }
 
 // TransportClient is a transport interface for transport stage
@@ -8,7 +8,7 @@ import (
 	"github.com/2gis/loggo/logging"
 )
 
-type ParserFunction func([]byte) (common.EntryMap, error)
+type ParserFunction func([]byte, common.UserLogParser) (common.EntryMap, error)
 type ParserFunctionDefault func([]byte) common.EntryMap
 
 var ErrUnknownMessageFormat = errors.New("unknown message log format")
@@ -19,6 +19,8 @@ type StageParsingEntry struct {
 
 	parseDockerFormat     ParserFunction
 	parseContainerDFormat ParserFunction
+	parseCRIOFormat       ParserFunction
+	parseFileFormat       ParserFunction
 	parseDefault          ParserFunctionDefault
 
 	extendsField string
@@ -39,13 +41,15 @@ func (s *StageParsingEntry) Close() {
 }
 
 // NewStageParsingEntry is a StageParsingEntry constructor
-func NewStageParsingEntry(input <-chan *common.Entry, parseDocker, parseContainerD ParserFunction,
+func NewStageParsingEntry(input <-chan *common.Entry, parseDocker, parseContainerD, parseCRIO, parseFile ParserFunction,
 	parserDefault ParserFunctionDefault, extendsField string, logger logging.Logger) *StageParsingEntry {
 	stage := &StageParsingEntry{
 		stage: stage{wg: &sync.WaitGroup{}, logger: logger},
 
 		parseDockerFormat:     parseDocker,
 		parseContainerDFormat: parseContainerD,
+		parseCRIOFormat:       parseCRIO,
+		parseFileFormat:       parseFile,
 		parseDefault:          parserDefault,
 
 		extendsField: extendsField,
@@ -64,9 +68,13 @@ func (s *StageParsingEntry) proceed() {
 
 		switch message.Format {
 		case common.CRITypeDocker:
-			entryMap, err = s.parseDockerFormat(message.Origin)
+			entryMap, err = s.parseDockerFormat(message.Origin, message.UserLogParser)
 		case common.CRITypeContainerD:
-			entryMap, err = s.parseContainerDFormat(message.Origin)
+			entryMap, err = s.parseContainerDFormat(message.Origin, message.UserLogParser)
+		case common.CRITypeCRIO:
+			entryMap, err = s.parseCRIOFormat(message.Origin, message.UserLogParser)
+		case common.FormatFile:
+			entryMap, err = s.parseFileFormat(message.Origin, message.UserLogParser)
 		default:
 			err = ErrUnknownMessageFormat
 		}
@@ -11,7 +11,7 @@ import (
 	"github.com/2gis/loggo/logging"
 )
 
-func parserFunctionTest(line []byte) (common.EntryMap, error) {
+func parserFunctionTest(line []byte, _ common.UserLogParser) (common.EntryMap, error) {
 	if len(line) == 0 {
 		return common.EntryMap{}, errors.New("line is empty")
 	}
@@ -19,6 +19,10 @@ func parserFunctionTest(line []byte) (common.EntryMap, error) {
 	return common.EntryMap{"line": string(line)}, nil
 }
 
+func parserFunctionCRIOTest(line []byte, _ common.UserLogParser) (common.EntryMap, error) {
+	return common.EntryMap{"crio": string(line)}, nil
+}
+
 func parserFunctionDefaultTest(_ []byte) common.EntryMap {
 	return common.EntryMap{
 		"default": true,
@@ -42,10 +46,16 @@ func TestStageParsingEntryTest(t *testing.T) {
 			entry:    common.Entry{Origin: []byte(nil), Format: common.CRITypeDocker},
 			entryMap: common.EntryMap{"default": true},
 		},
+		{
+			entry:    common.Entry{Origin: []byte("value"), Format: common.CRITypeCRIO},
+			entryMap: common.EntryMap{"crio": "value"},
+		},
 	}
 
 	input := make(chan *common.Entry, len(expectations))
-	stage := NewStageParsingEntry(input, parserFunctionTest, nil, parserFunctionDefaultTest, "", logging.NewLoggerDefault())
+	stage := NewStageParsingEntry(
+		input, parserFunctionTest, nil, parserFunctionCRIOTest, nil, parserFunctionDefaultTest, "", logging.NewLoggerDefault(),
+	)
 	wg := &sync.WaitGroup{}
 	wg.Add(1)
 
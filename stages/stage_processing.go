@@ -0,0 +1,59 @@
+package stages
+
+import (
+	"sync"
+
+	"github.com/2gis/loggo/common"
+	"github.com/2gis/loggo/logging"
+)
+
+// StageProcessing applies processors to messages from input one by one, drops message once any processor rejects it
+type StageProcessing struct {
+	stage
+
+	processors []Processor
+
+	input  <-chan common.EntryMap
+	output chan common.EntryMap
+}
+
+// Out is stage output accessor
+func (s *StageProcessing) Out() <-chan common.EntryMap {
+	return s.output
+}
+
+// Close closes the stage output after its workers finish
+func (s *StageProcessing) Close() {
+	s.stage.Close()
+	close(s.output)
+}
+
+// NewStageProcessing is a StageProcessing constructor
+func NewStageProcessing(input <-chan common.EntryMap, processors []Processor, logger logging.Logger) *StageProcessing {
+	stage := &StageProcessing{
+		stage:      stage{wg: &sync.WaitGroup{}, logger: logger},
+		processors: processors,
+		input:      input,
+		output:     make(chan common.EntryMap),
+	}
+	stage.stage.proceed = stage.proceed
+	return stage
+}
+
+func (s *StageProcessing) proceed() {
+	for message := range s.input {
+		if s.process(message) {
+			s.output <- message
+		}
+	}
+}
+
+func (s *StageProcessing) process(message common.EntryMap) bool {
+	for _, processor := range s.processors {
+		if !processor.Process(message) {
+			return false
+		}
+	}
+
+	return true
+}
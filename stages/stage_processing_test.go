@@ -0,0 +1,63 @@
+package stages
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/2gis/loggo/common"
+	"github.com/2gis/loggo/logging"
+)
+
+type processorTest struct {
+	key string
+}
+
+func (p *processorTest) Process(entryMap common.EntryMap) bool {
+	if _, ok := entryMap["drop"]; ok {
+		return false
+	}
+
+	entryMap[p.key] = true
+	return true
+}
+
+func TestStageProcessing(t *testing.T) {
+	inputMessages := []common.EntryMap{
+		{"message": "a"},
+		{"message": "b", "drop": true},
+		{"message": "c"},
+	}
+
+	input := make(chan common.EntryMap, len(inputMessages))
+	stage := NewStageProcessing(
+		input, []Processor{&processorTest{key: "first"}, &processorTest{key: "second"}}, logging.NewLoggerDefault())
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+
+	go func() {
+		StageInit(stage, 2)
+		wg.Done()
+	}()
+
+	for _, message := range inputMessages {
+		input <- message
+	}
+	close(input)
+
+	outputMessages := make([]common.EntryMap, 0, 2)
+
+	for message := range stage.Out() {
+		outputMessages = append(outputMessages, message)
+	}
+
+	assert.Len(t, outputMessages, 2)
+
+	for _, message := range outputMessages {
+		assert.Equal(t, true, message["first"])
+		assert.Equal(t, true, message["second"])
+	}
+
+	wg.Wait()
+}
@@ -33,3 +33,19 @@ sleep 5
   echo "Containerd to redis failed"
   exit 1
 }
+
+### spin loggo
+timeout --preserve-status 5 ./build/loggo/loggo --no-log-journald --no-sla-exporter \
+  --flush-interval-sec=1 --buffer-max-size=25 \
+  --transport="redis" \
+  --logs-path="tests/fixtures/pods_crio" --crio-containers-path="tests/fixtures/crio/lib" \
+  --crio-runroot-path="tests/fixtures/crio/run" \
+  --position-file-path="loggo-logs.pos" --containers-ignore-file-path="loggo-containers-ignore" &&
+  echo "Loggo write launch ok" || echo "Loggo write launch failed"
+
+sleep 5
+### check results
+./build/tests --transport="redis" || {
+  echo "CRI-O to redis failed"
+  exit 1
+}
@@ -1,5 +1,7 @@
 package mocks
 
+import "time"
+
 // CollectorMock for metrics manipulating object
 type CollectorMock struct{}
 
@@ -15,12 +17,27 @@ func (collector *CollectorMock) IncrementLogMessageCount(_, _, _ string) {}
 
 func (collector *CollectorMock) IncrementThrottlingDelay(_, _, _ string, _ float64) {}
 
-func (collector *CollectorMock) ObserveHTTPRequestTime(_, _, _, _, _ string, _ float64) {}
+func (collector *CollectorMock) IncrementTruncationsCount(_, _, _ string) {}
+
+func (collector *CollectorMock) ObserveHTTPRequestTime(
+	_, _, _, _ string, _ float64, _ []float64, _ map[string]string) {
+}
 
-func (collector *CollectorMock) ObserveHTTPUpstreamResponseTimeTotal(_, _, _, _, _ string, _ float64) {
+func (collector *CollectorMock) ObserveHTTPUpstreamResponseTimeTotal(
+	_, _, _, _ string, _ float64, _ []float64, _ map[string]string) {
 }
 
-func (collector *CollectorMock) DeleteThrottlingDelay(_, _, _ string) bool {
+func (collector *CollectorMock) IncrementSampledOutCount(_, _ string) {}
+
+func (collector *CollectorMock) IncrementRedactionHitsCount(_ string, _ int) {}
+
+func (collector *CollectorMock) IncrementTraceContextCount(_ string, _ bool) {}
+
+func (collector *CollectorMock) ObserveSLOEvent(_ string, _ float64, _ []time.Duration, _ bool) {}
+
+func (collector *CollectorMock) IncrementHTTPRequestSeriesOverflowCount(_ string) {}
+
+func (collector *CollectorMock) DeleteContainerSeries(_, _, _ string) bool {
 	return true
 }
 
@@ -0,0 +1,121 @@
+package mocks
+
+import (
+	"context"
+	"net"
+	"sync"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	runtimeapi "k8s.io/cri-api/pkg/apis/runtime/v1"
+)
+
+// RuntimeServiceStub is a grpc CRI runtime service serving containers set by test over unix socket
+type RuntimeServiceStub struct {
+	runtimeapi.UnimplementedRuntimeServiceServer
+
+	mu             sync.Mutex
+	runtimeName    string
+	containers     []*runtimeapi.Container
+	logPaths       map[string]string
+	statusRequests int
+	server         *grpc.Server
+}
+
+// NewRuntimeServiceStub is a RuntimeServiceStub constructor, runtime is named containerd
+func NewRuntimeServiceStub(containers ...*runtimeapi.Container) *RuntimeServiceStub {
+	return &RuntimeServiceStub{
+		runtimeName: "containerd",
+		containers:  containers,
+		logPaths:    make(map[string]string),
+	}
+}
+
+// Serve starts serving on the socket and returns runtime endpoint
+func (s *RuntimeServiceStub) Serve(socket string) (string, error) {
+	listener, err := net.Listen("unix", socket)
+
+	if err != nil {
+		return "", err
+	}
+
+	s.server = grpc.NewServer()
+	runtimeapi.RegisterRuntimeServiceServer(s.server, s)
+
+	go func() {
+		_ = s.server.Serve(listener)
+	}()
+
+	return "unix://" + socket, nil
+}
+
+// Stop stops serving and closes the socket listener
+func (s *RuntimeServiceStub) Stop() {
+	s.server.Stop()
+}
+
+// SetRuntimeName sets runtime name reported by Version
+func (s *RuntimeServiceStub) SetRuntimeName(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.runtimeName = name
+}
+
+// SetContainers replaces containers listed by the runtime
+func (s *RuntimeServiceStub) SetContainers(containers ...*runtimeapi.Container) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.containers = containers
+}
+
+// SetLogPath sets log path of the container status; status of containers without log path isn't found
+func (s *RuntimeServiceStub) SetLogPath(id, logPath string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.logPaths[id] = logPath
+}
+
+// StatusRequests returns count of ContainerStatus requests served
+func (s *RuntimeServiceStub) StatusRequests() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.statusRequests
+}
+
+func (s *RuntimeServiceStub) Version(
+	_ context.Context, _ *runtimeapi.VersionRequest) (*runtimeapi.VersionResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return &runtimeapi.VersionResponse{RuntimeName: s.runtimeName, RuntimeApiVersion: "v1"}, nil
+}
+
+func (s *RuntimeServiceStub) ListContainers(
+	_ context.Context, _ *runtimeapi.ListContainersRequest) (*runtimeapi.ListContainersResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return &runtimeapi.ListContainersResponse{Containers: s.containers}, nil
+}
+
+func (s *RuntimeServiceStub) ContainerStatus(
+	_ context.Context, request *runtimeapi.ContainerStatusRequest) (*runtimeapi.ContainerStatusResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.statusRequests++
+	logPath, ok := s.logPaths[request.ContainerId]
+
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "container %s not found", request.ContainerId)
+	}
+
+	return &runtimeapi.ContainerStatusResponse{
+		Status: &runtimeapi.ContainerStatus{Id: request.ContainerId, LogPath: logPath},
+	}, nil
+}
@@ -5,9 +5,10 @@ const (
 	TypeAMQP     = "amqp"
 	TypeRedis    = "redis"
 	TypeFirehose = "firehose"
+	TypeOTLP     = "otlp"
 )
 
 // RedisMaxIdleConnections default.
 const RedisMaxIdleConnections = 100
 
-var TypesSupported = []string{TypeRedis, TypeAMQP, TypeFirehose}
+var TypesSupported = []string{TypeRedis, TypeAMQP, TypeFirehose, TypeOTLP}
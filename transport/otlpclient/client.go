@@ -0,0 +1,226 @@
+package otlpclient
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/2gis/loggo/configuration"
+)
+
+/* Export protocols */
+const (
+	ProtocolGRPC = "grpc"
+	ProtocolHTTP = "http"
+)
+
+// httpLogsPath is the default path of OTLP/HTTP logs receiver
+const httpLogsPath = "/v1/logs"
+
+type exporter interface {
+	export(ctx context.Context, request *collogspb.ExportLogsServiceRequest) error
+	close() error
+}
+
+// OTLPClient delivers messages as OpenTelemetry log records over grpc or http/protobuf; log records rejected by
+// receiver in partial success responses aren't redelivered, as receivers reject them permanently
+type OTLPClient struct {
+	exporter  exporter
+	converter *converter
+	timeout   time.Duration
+}
+
+// NewOTLPClient is an OTLPClient constructor
+func NewOTLPClient(config configuration.Config) (*OTLPClient, error) {
+	headers, err := parseHeaders(config.OTLPTransportConfig.Headers)
+	if err != nil {
+		return nil, err
+	}
+
+	client := &OTLPClient{
+		converter: newConverter(config),
+		timeout:   config.OTLPTransportConfig.Timeout,
+	}
+
+	switch config.OTLPTransportConfig.Protocol {
+	case ProtocolGRPC:
+		client.exporter, err = newGRPCExporter(
+			config.OTLPTransportConfig.Endpoint, config.OTLPTransportConfig.Insecure, headers)
+	case ProtocolHTTP:
+		client.exporter, err = newHTTPExporter(
+			config.OTLPTransportConfig.Endpoint, config.OTLPTransportConfig.Insecure, headers,
+			config.OTLPTransportConfig.Timeout)
+	default:
+		return nil, fmt.Errorf("unsupported OTLP protocol '%s'", config.OTLPTransportConfig.Protocol)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return client, nil
+}
+
+// DeliverMessages exports messages in a single request
+func (client *OTLPClient) DeliverMessages(messages []string) error {
+	request := &collogspb.ExportLogsServiceRequest{
+		ResourceLogs: client.converter.convert(messages, time.Now()),
+	}
+
+	if len(request.ResourceLogs) == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), client.timeout)
+	defer cancel()
+
+	if err := client.exporter.export(ctx, request); err != nil {
+		return fmt.Errorf("unable to export logs, %w", err)
+	}
+
+	return nil
+}
+
+// Close closes exporter connection
+func (client *OTLPClient) Close() error {
+	return client.exporter.close()
+}
+
+type grpcExporter struct {
+	connection *grpc.ClientConn
+	client     collogspb.LogsServiceClient
+	headers    metadata.MD
+}
+
+func newGRPCExporter(endpoint string, plaintext bool, headers map[string]string) (*grpcExporter, error) {
+	transportCredentials := credentials.NewTLS(&tls.Config{})
+
+	if plaintext {
+		transportCredentials = insecure.NewCredentials()
+	}
+
+	// connection is established lazily, on the first export
+	connection, err := grpc.Dial(endpoint, grpc.WithTransportCredentials(transportCredentials))
+	if err != nil {
+		return nil, err
+	}
+
+	return &grpcExporter{
+		connection: connection,
+		client:     collogspb.NewLogsServiceClient(connection),
+		headers:    metadata.New(headers),
+	}, nil
+}
+
+func (e *grpcExporter) export(ctx context.Context, request *collogspb.ExportLogsServiceRequest) error {
+	_, err := e.client.Export(metadata.NewOutgoingContext(ctx, e.headers), request)
+	return err
+}
+
+func (e *grpcExporter) close() error {
+	return e.connection.Close()
+}
+
+type httpExporter struct {
+	client  *http.Client
+	url     string
+	headers map[string]string
+}
+
+// newHTTPExporter accepts full url or host:port, the scheme is chosen by plaintext then, path defaults to /v1/logs
+func newHTTPExporter(
+	endpoint string, plaintext bool, headers map[string]string, timeout time.Duration) (*httpExporter, error) {
+	if !strings.Contains(endpoint, "://") {
+		scheme := "https://"
+
+		if plaintext {
+			scheme = "http://"
+		}
+
+		endpoint = scheme + endpoint
+	}
+
+	parsed, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	if parsed.Path == "" || parsed.Path == "/" {
+		parsed.Path = httpLogsPath
+	}
+
+	return &httpExporter{
+		client:  &http.Client{Timeout: timeout},
+		url:     parsed.String(),
+		headers: headers,
+	}, nil
+}
+
+func (e *httpExporter) export(ctx context.Context, request *collogspb.ExportLogsServiceRequest) error {
+	body, err := proto.Marshal(request)
+	if err != nil {
+		return err
+	}
+
+	httpRequest, err := http.NewRequestWithContext(ctx, http.MethodPost, e.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	httpRequest.Header.Set("Content-Type", "application/x-protobuf")
+
+	for key, value := range e.headers {
+		httpRequest.Header.Set(key, value)
+	}
+
+	response, err := e.client.Do(httpRequest)
+	if err != nil {
+		return err
+	}
+
+	defer response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		message, _ := ioutil.ReadAll(io.LimitReader(response.Body, 1024))
+		return fmt.Errorf("receiver responded with status %d: %s", response.StatusCode, message)
+	}
+
+	_, err = io.Copy(ioutil.Discard, response.Body)
+	return err
+}
+
+func (e *httpExporter) close() error {
+	e.client.CloseIdleConnections()
+	return nil
+}
+
+// parseHeaders parses comma-separated key=value pairs
+func parseHeaders(headers string) (map[string]string, error) {
+	result := make(map[string]string)
+
+	for _, header := range splitList(headers) {
+		parts := strings.SplitN(header, "=", 2)
+
+		if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" {
+			return nil, fmt.Errorf("OTLP header '%s' must be in key=value form", header)
+		}
+
+		result[strings.ToLower(strings.TrimSpace(parts[0]))] = strings.TrimSpace(parts[1])
+	}
+
+	return result, nil
+}
@@ -0,0 +1,144 @@
+package otlpclient
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/2gis/loggo/configuration"
+)
+
+const testMessage = `{"log":{"msg":"hello","level":"warn"},"kubernetes.pod_name":"pod","time":"2021-01-01T00:00:00Z"}`
+
+// receiverStub is a grpc OTLP logs receiver keeping requests
+type receiverStub struct {
+	collogspb.UnimplementedLogsServiceServer
+
+	mu       sync.Mutex
+	requests []*collogspb.ExportLogsServiceRequest
+	headers  []metadata.MD
+}
+
+func (r *receiverStub) Export(
+	ctx context.Context, request *collogspb.ExportLogsServiceRequest,
+) (*collogspb.ExportLogsServiceResponse, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	headers, _ := metadata.FromIncomingContext(ctx)
+	r.requests = append(r.requests, request)
+	r.headers = append(r.headers, headers)
+	return &collogspb.ExportLogsServiceResponse{}, nil
+}
+
+func testConfig(protocol, endpoint string) configuration.Config {
+	return configuration.Config{
+		ParserConfig: configuration.ParserConfig{UserLogFieldsKey: "log"},
+		SeverityConfig: configuration.SeverityConfig{
+			Fields: "level",
+		},
+		OTLPTransportConfig: configuration.OTLPTransportConfig{
+			Protocol:   protocol,
+			Endpoint:   endpoint,
+			Insecure:   true,
+			Headers:    "Authorization=Bearer token",
+			Timeout:    5 * time.Second,
+			BodyFields: "message,msg",
+		},
+	}
+}
+
+func TestOTLPClientGRPC(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+
+	receiver := &receiverStub{}
+	server := grpc.NewServer()
+	collogspb.RegisterLogsServiceServer(server, receiver)
+
+	go func() {
+		_ = server.Serve(listener)
+	}()
+
+	defer server.Stop()
+
+	client, err := NewOTLPClient(testConfig(ProtocolGRPC, listener.Addr().String()))
+	assert.NoError(t, err)
+	defer client.Close()
+
+	assert.NoError(t, client.DeliverMessages([]string{testMessage, testMessage}))
+	assert.NoError(t, client.DeliverMessages([]string{"not a json"}))
+
+	receiver.mu.Lock()
+	defer receiver.mu.Unlock()
+
+	assert.Len(t, receiver.requests, 1)
+	assert.Equal(t, []string{"Bearer token"}, receiver.headers[0].Get("authorization"))
+
+	records := receiver.requests[0].ResourceLogs[0].ScopeLogs[0].LogRecords
+	assert.Len(t, records, 2)
+	assert.Equal(t, "hello", records[0].Body.GetStringValue())
+	assert.Equal(t, "warn", records[0].SeverityText)
+}
+
+func TestOTLPClientHTTP(t *testing.T) {
+	requests := make(chan *collogspb.ExportLogsServiceRequest, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/logs", r.URL.Path)
+		assert.Equal(t, "application/x-protobuf", r.Header.Get("Content-Type"))
+		assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+
+		body, err := ioutil.ReadAll(r.Body)
+		assert.NoError(t, err)
+
+		request := &collogspb.ExportLogsServiceRequest{}
+		assert.NoError(t, proto.Unmarshal(body, request))
+		requests <- request
+	}))
+	defer server.Close()
+
+	client, err := NewOTLPClient(testConfig(ProtocolHTTP, server.Listener.Addr().String()))
+	assert.NoError(t, err)
+	defer client.Close()
+
+	assert.NoError(t, client.DeliverMessages([]string{testMessage}))
+
+	request := <-requests
+	assert.Len(t, request.ResourceLogs, 1)
+	assert.Equal(t, "k8s.pod.name", request.ResourceLogs[0].Resource.Attributes[0].Key)
+}
+
+func TestOTLPClientHTTPError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	client, err := NewOTLPClient(testConfig(ProtocolHTTP, server.URL+"/custom/logs"))
+	assert.NoError(t, err)
+
+	err = client.DeliverMessages([]string{testMessage})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "503")
+}
+
+func TestNewOTLPClientErrors(t *testing.T) {
+	_, err := NewOTLPClient(testConfig("udp", "localhost:4317"))
+	assert.Error(t, err)
+
+	config := testConfig(ProtocolGRPC, "localhost:4317")
+	config.OTLPTransportConfig.Headers = "invalid"
+	_, err = NewOTLPClient(config)
+	assert.Error(t, err)
+}
@@ -0,0 +1,357 @@
+package otlpclient
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+
+	"github.com/2gis/loggo/common"
+	"github.com/2gis/loggo/configuration"
+	"github.com/2gis/loggo/parsers"
+	"github.com/2gis/loggo/processors"
+)
+
+/* Resource attributes, see OpenTelemetry semantic conventions */
+const (
+	AttributeK8SNamespaceName = "k8s.namespace.name"
+	AttributeK8SPodName       = "k8s.pod.name"
+	AttributeK8SContainerName = "k8s.container.name"
+	AttributeK8SNodeName      = "k8s.node.name"
+)
+
+const scopeName = "loggo"
+
+var (
+	traceIDFields = []string{"trace_id", "traceId"}
+	spanIDFields  = []string{"span_id", "spanId"}
+)
+
+// resourceFields maps entry extends fields to resource attributes
+var resourceFields = []struct {
+	field     string
+	attribute string
+}{
+	{common.KubernetesNamespaceName, AttributeK8SNamespaceName},
+	{common.KubernetesPodName, AttributeK8SPodName},
+	{common.KubernetesContainerName, AttributeK8SContainerName},
+	{common.KubernetesNodeHostname, AttributeK8SNodeName},
+}
+
+// severityNumbers maps canonical severities to the first number of OpenTelemetry severity ranges
+var severityNumbers = map[string]logspb.SeverityNumber{
+	common.SeverityTrace: logspb.SeverityNumber_SEVERITY_NUMBER_TRACE,
+	common.SeverityDebug: logspb.SeverityNumber_SEVERITY_NUMBER_DEBUG,
+	common.SeverityInfo:  logspb.SeverityNumber_SEVERITY_NUMBER_INFO,
+	common.SeverityWarn:  logspb.SeverityNumber_SEVERITY_NUMBER_WARN,
+	common.SeverityError: logspb.SeverityNumber_SEVERITY_NUMBER_ERROR,
+	common.SeverityFatal: logspb.SeverityNumber_SEVERITY_NUMBER_FATAL,
+}
+
+// converter makes OTLP log records of marshalled entries; fields that aren't lifted to resource, body, severity,
+// time or trace context become record attributes with dotted names
+type converter struct {
+	userLogKey string
+	rawLogKey  string
+	criKey     string
+	extendsKey string
+
+	bodyFields     []string
+	severityField  string
+	severityFields []string
+	timestampField string
+}
+
+func newConverter(config configuration.Config) *converter {
+	c := &converter{
+		userLogKey:     config.ParserConfig.UserLogFieldsKey,
+		rawLogKey:      config.ParserConfig.RawLogFieldKey,
+		criKey:         config.ParserConfig.CRIFieldsKey,
+		extendsKey:     config.ParserConfig.ExtendsFieldsKey,
+		bodyFields:     splitList(config.OTLPTransportConfig.BodyFields),
+		severityFields: splitList(config.SeverityConfig.Fields),
+	}
+
+	if config.SeverityConfig.Enabled {
+		c.severityField = config.SeverityConfig.OutputField
+	}
+
+	if config.TimestampConfig.Enabled {
+		c.timestampField = config.TimestampConfig.OutputField
+	}
+
+	return c
+}
+
+// convert makes resource logs of messages, grouped by resource; messages that aren't json objects are skipped
+func (c *converter) convert(messages []string, observed time.Time) []*logspb.ResourceLogs {
+	result := make([]*logspb.ResourceLogs, 0)
+	resources := make(map[string]*logspb.ScopeLogs)
+
+	for _, message := range messages {
+		entryMap := make(common.EntryMap)
+
+		if err := json.Unmarshal([]byte(message), &entryMap); err != nil {
+			continue
+		}
+
+		attributes, key := c.resource(entryMap)
+		scopeLogs, ok := resources[key]
+
+		if !ok {
+			scopeLogs = &logspb.ScopeLogs{Scope: &commonpb.InstrumentationScope{Name: scopeName}}
+			resources[key] = scopeLogs
+			result = append(result, &logspb.ResourceLogs{
+				Resource:  &resourcepb.Resource{Attributes: attributes},
+				ScopeLogs: []*logspb.ScopeLogs{scopeLogs},
+			})
+		}
+
+		scopeLogs.LogRecords = append(scopeLogs.LogRecords, c.record(entryMap, observed))
+	}
+
+	return result
+}
+
+// resource returns resource attributes of the entry and the key to group entries by
+func (c *converter) resource(entryMap common.EntryMap) ([]*commonpb.KeyValue, string) {
+	extends, _ := subMap(entryMap, c.extendsKey)
+	attributes := make([]*commonpb.KeyValue, 0, len(resourceFields))
+	key := strings.Builder{}
+
+	for _, resourceField := range resourceFields {
+		value, _ := extends[resourceField.field].(string)
+		key.WriteString(value)
+		key.WriteByte(0)
+
+		if value != "" {
+			attributes = append(attributes, stringAttribute(resourceField.attribute, value))
+		}
+	}
+
+	return attributes, key.String()
+}
+
+func (c *converter) record(entryMap common.EntryMap, observed time.Time) *logspb.LogRecord {
+	record := &logspb.LogRecord{ObservedTimeUnixNano: uint64(observed.UnixNano())}
+	lifted := make(map[string]bool)
+	userLog, hasUserLog := subMap(entryMap, c.userLogKey)
+
+	for _, resourceField := range resourceFields {
+		lifted[joinPath(c.extendsKey, resourceField.field)] = true
+	}
+
+	if !hasUserLog {
+		// user log hasn't been parsed, the raw string is the body
+		if body, ok := entryMap[c.userLogKey].(string); ok {
+			record.Body = stringValue(body)
+			lifted[c.userLogKey] = true
+		}
+	}
+
+	for _, field := range c.bodyFields {
+		if record.Body != nil || !hasUserLog {
+			break
+		}
+
+		if value, ok := userLog.Lookup(field); ok {
+			record.Body = anyValue(value)
+			lifted[joinPath(c.userLogKey, field)] = true
+		}
+	}
+
+	if record.Body == nil && hasUserLog && c.rawLogKey != "" {
+		// user log content that isn't JSON is kept by parsers under the raw log field
+		if body, ok := userLog[c.rawLogKey].(string); ok {
+			record.Body = stringValue(body)
+			lifted[joinPath(c.userLogKey, c.rawLogKey)] = true
+		}
+	}
+
+	c.setSeverity(record, entryMap, userLog)
+	c.setTime(record, entryMap)
+
+	// canonical fields of trace context normalization take precedence over user log fields
+	if traceID, _ := lookupID(entryMap, []string{processors.FieldTraceID}, 16); traceID != nil {
+		record.TraceId = traceID
+		lifted[processors.FieldTraceID] = true
+	} else if traceID, field := lookupID(userLog, traceIDFields, 16); traceID != nil {
+		record.TraceId = traceID
+		lifted[joinPath(c.userLogKey, field)] = true
+	}
+
+	if spanID, _ := lookupID(entryMap, []string{processors.FieldSpanID}, 8); spanID != nil {
+		record.SpanId = spanID
+		lifted[processors.FieldSpanID] = true
+	} else if spanID, field := lookupID(userLog, spanIDFields, 8); spanID != nil {
+		record.SpanId = spanID
+		lifted[joinPath(c.userLogKey, field)] = true
+	}
+
+	record.Attributes = attributes(entryMap, "", lifted, make([]*commonpb.KeyValue, 0, len(entryMap)))
+	return record
+}
+
+func (c *converter) setSeverity(record *logspb.LogRecord, entryMap, userLog common.EntryMap) {
+	if severity, ok := entryMap[c.severityField].(string); ok && c.severityField != "" {
+		record.SeverityNumber = severityNumbers[severity]
+		record.SeverityText = severity
+		return
+	}
+
+	for _, field := range c.severityFields {
+		value, ok := userLog.Lookup(field)
+
+		if !ok {
+			continue
+		}
+
+		if severity, ok := processors.NormalizeSeverity(value); ok {
+			record.SeverityNumber = severityNumbers[severity]
+			record.SeverityText = fmt.Sprintf("%v", value)
+			return
+		}
+	}
+}
+
+func (c *converter) setTime(record *logspb.LogRecord, entryMap common.EntryMap) {
+	value, _ := entryMap[c.timestampField].(string)
+
+	if value == "" || c.timestampField == "" {
+		cri, _ := subMap(entryMap, c.criKey)
+		value, _ = cri[parsers.LogKeyTime].(string)
+	}
+
+	if timestamp, err := time.Parse(time.RFC3339Nano, value); err == nil {
+		record.TimeUnixNano = uint64(timestamp.UnixNano())
+	}
+}
+
+// lookupID returns hex decoded id of the given bytes length and the field it was found in
+func lookupID(userLog common.EntryMap, fields []string, length int) ([]byte, string) {
+	for _, field := range fields {
+		value, _ := userLog.Lookup(field)
+		id, ok := value.(string)
+
+		if !ok || len(id) != 2*length {
+			continue
+		}
+
+		decoded, err := hex.DecodeString(id)
+
+		if err != nil {
+			continue
+		}
+
+		return decoded, field
+	}
+
+	return nil, ""
+}
+
+// attributes flattens entry map fields to dotted attributes, skipping lifted fields
+func attributes(
+	source map[string]interface{}, prefix string, lifted map[string]bool, result []*commonpb.KeyValue,
+) []*commonpb.KeyValue {
+	for key, value := range source {
+		path := joinPath(prefix, key)
+
+		if lifted[path] {
+			continue
+		}
+
+		switch typed := value.(type) {
+		case map[string]interface{}:
+			result = attributes(typed, path, lifted, result)
+		case common.EntryMap:
+			result = attributes(typed, path, lifted, result)
+		case nil:
+		default:
+			result = append(result, &commonpb.KeyValue{Key: path, Value: anyValue(typed)})
+		}
+	}
+
+	return result
+}
+
+func anyValue(value interface{}) *commonpb.AnyValue {
+	switch typed := value.(type) {
+	case string:
+		return stringValue(typed)
+	case bool:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_BoolValue{BoolValue: typed}}
+	case float64:
+		// json numbers are floats, integers are kept integers
+		if typed == math.Trunc(typed) && math.Abs(typed) < 1<<53 {
+			return &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: int64(typed)}}
+		}
+
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_DoubleValue{DoubleValue: typed}}
+	case []interface{}:
+		values := make([]*commonpb.AnyValue, 0, len(typed))
+
+		for _, item := range typed {
+			values = append(values, anyValue(item))
+		}
+
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_ArrayValue{
+			ArrayValue: &commonpb.ArrayValue{Values: values},
+		}}
+	case map[string]interface{}:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_KvlistValue{
+			KvlistValue: &commonpb.KeyValueList{Values: attributes(typed, "", nil, nil)},
+		}}
+	}
+
+	return stringValue(fmt.Sprintf("%v", value))
+}
+
+func stringValue(value string) *commonpb.AnyValue {
+	return &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: value}}
+}
+
+func stringAttribute(key, value string) *commonpb.KeyValue {
+	return &commonpb.KeyValue{Key: key, Value: stringValue(value)}
+}
+
+// subMap returns entry map under the key, or the entry map itself for empty key
+func subMap(entryMap common.EntryMap, key string) (common.EntryMap, bool) {
+	if key == "" {
+		return entryMap, true
+	}
+
+	switch typed := entryMap[key].(type) {
+	case map[string]interface{}:
+		return typed, true
+	case common.EntryMap:
+		return typed, true
+	}
+
+	return nil, false
+}
+
+func joinPath(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+
+	return prefix + "." + key
+}
+
+func splitList(list string) []string {
+	result := make([]string, 0)
+
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			result = append(result, item)
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,127 @@
+package otlpclient
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+
+	"github.com/2gis/loggo/common"
+	"github.com/2gis/loggo/configuration"
+	"github.com/2gis/loggo/parsers"
+)
+
+func attributesMap(attributes []*commonpb.KeyValue) map[string]*commonpb.AnyValue {
+	result := make(map[string]*commonpb.AnyValue, len(attributes))
+
+	for _, attribute := range attributes {
+		result[attribute.Key] = attribute.Value
+	}
+
+	return result
+}
+
+func TestConvert(t *testing.T) {
+	config := configuration.Config{
+		ParserConfig: configuration.ParserConfig{
+			UserLogFieldsKey: "log",
+			RawLogFieldKey:   "raw",
+			CRIFieldsKey:     "cri",
+			ExtendsFieldsKey: "environment",
+		},
+		SeverityConfig:      configuration.SeverityConfig{Fields: "level"},
+		OTLPTransportConfig: configuration.OTLPTransportConfig{BodyFields: "message,msg"},
+	}
+	converter := newConverter(config)
+	observed := time.Unix(1600000000, 0)
+
+	// user log that isn't JSON is kept under the raw log field by parser
+	parse := parsers.CreateParserDockerFormat(config.ParserConfig)
+	plain, err := parse([]byte(`{"log":"plain line","stream":"stderr"}`), nil)
+	assert.NoError(t, err)
+	plain["environment"] = common.EntryMap{"kubernetes.namespace_name": "ns", "kubernetes.pod_name": "pod-b"}
+	plainMessage, err := json.Marshal(plain)
+	assert.NoError(t, err)
+
+	messages := []string{
+		`{"environment":{"kubernetes.namespace_name":"ns","kubernetes.pod_name":"pod-a",` +
+			`"kubernetes.container_name":"app","kubernetes.node_hostname":"node","dc":"n3"},` +
+			`"cri":{"stream":"stdout","time":"2021-01-01T00:00:00.5Z"},` +
+			`"log":{"message":"first","level":"error","trace_id":"4bf92f3577b34da6a3ce929d0e0e4736",` +
+			`"span_id":"00f067aa0ba902b7","status":200,"ratio":0.5,"request":{"path":"/"}}}`,
+		string(plainMessage),
+		`{"environment":{"kubernetes.namespace_name":"ns","kubernetes.pod_name":"pod-a",` +
+			`"kubernetes.container_name":"app","kubernetes.node_hostname":"node"},"log":{"msg":"second"}}`,
+	}
+
+	resourceLogs := converter.convert(messages, observed)
+	assert.Len(t, resourceLogs, 2)
+
+	resource := attributesMap(resourceLogs[0].Resource.Attributes)
+	assert.Equal(t, "ns", resource[AttributeK8SNamespaceName].GetStringValue())
+	assert.Equal(t, "pod-a", resource[AttributeK8SPodName].GetStringValue())
+	assert.Equal(t, "app", resource[AttributeK8SContainerName].GetStringValue())
+	assert.Equal(t, "node", resource[AttributeK8SNodeName].GetStringValue())
+	assert.Len(t, resourceLogs[0].ScopeLogs[0].LogRecords, 2)
+	assert.Equal(t, scopeName, resourceLogs[0].ScopeLogs[0].Scope.Name)
+
+	record := resourceLogs[0].ScopeLogs[0].LogRecords[0]
+	assert.Equal(t, "first", record.Body.GetStringValue())
+	assert.Equal(t, logspb.SeverityNumber_SEVERITY_NUMBER_ERROR, record.SeverityNumber)
+	assert.Equal(t, "error", record.SeverityText)
+	assert.Equal(t, uint64(time.Date(2021, 1, 1, 0, 0, 0, 5e8, time.UTC).UnixNano()), record.TimeUnixNano)
+	assert.Equal(t, uint64(observed.UnixNano()), record.ObservedTimeUnixNano)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", hex.EncodeToString(record.TraceId))
+	assert.Equal(t, "00f067aa0ba902b7", hex.EncodeToString(record.SpanId))
+
+	attributes := attributesMap(record.Attributes)
+	assert.Equal(t, int64(200), attributes["log.status"].GetIntValue())
+	assert.Equal(t, 0.5, attributes["log.ratio"].GetDoubleValue())
+	assert.Equal(t, "/", attributes["log.request.path"].GetStringValue())
+	assert.Equal(t, "n3", attributes["environment.dc"].GetStringValue())
+	assert.Equal(t, "stdout", attributes["cri.stream"].GetStringValue())
+
+	for _, lifted := range []string{"log.message", "log.trace_id", "log.span_id", "environment.kubernetes.pod_name"} {
+		assert.NotContains(t, attributes, lifted)
+	}
+
+	record = resourceLogs[1].ScopeLogs[0].LogRecords[0]
+	assert.Equal(t, "plain line", record.Body.GetStringValue())
+	assert.Equal(t, logspb.SeverityNumber_SEVERITY_NUMBER_UNSPECIFIED, record.SeverityNumber)
+	assert.Equal(t, uint64(0), record.TimeUnixNano)
+	assert.Len(t, record.Attributes, 1)
+	assert.Equal(t, "stderr", attributesMap(record.Attributes)["cri.stream"].GetStringValue())
+}
+
+func TestConvertNormalizedFields(t *testing.T) {
+	config := configuration.Config{
+		SeverityConfig:  configuration.SeverityConfig{Enabled: true, OutputField: "severity_normalized"},
+		TimestampConfig: configuration.TimestampConfig{Enabled: true, OutputField: "@timestamp"},
+	}
+	converter := newConverter(config)
+
+	resourceLogs := converter.convert([]string{
+		`{"severity_normalized":"info","@timestamp":"2021-01-01T00:00:01Z","time":"2021-01-01T00:00:00Z",` +
+			`"trace_id":"invalid"}`,
+	}, time.Now())
+
+	record := resourceLogs[0].ScopeLogs[0].LogRecords[0]
+	assert.Equal(t, logspb.SeverityNumber_SEVERITY_NUMBER_INFO, record.SeverityNumber)
+	assert.Equal(t, uint64(time.Date(2021, 1, 1, 0, 0, 1, 0, time.UTC).UnixNano()), record.TimeUnixNano)
+	assert.Nil(t, record.TraceId)
+	assert.Nil(t, record.Body)
+	assert.Equal(t, "invalid", attributesMap(record.Attributes)["trace_id"].GetStringValue())
+
+	resourceLogs = converter.convert([]string{
+		`{"trace_id":"4bf92f3577b34da6a3ce929d0e0e4736","span_id":"00f067aa0ba902b7","traceId":"invalid"}`,
+	}, time.Now())
+
+	record = resourceLogs[0].ScopeLogs[0].LogRecords[0]
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", hex.EncodeToString(record.TraceId))
+	assert.Equal(t, "00f067aa0ba902b7", hex.EncodeToString(record.SpanId))
+	assert.Len(t, record.Attributes, 1)
+}